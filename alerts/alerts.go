@@ -0,0 +1,217 @@
+package alerts
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/metrics"
+)
+
+const (
+	ViolationBelowMin = "below_min"
+	ViolationAboveMax = "above_max"
+	ViolationDrop     = "drop"
+)
+
+// A Violation of the expected routes of a protocol
+type Violation struct {
+	Protocol  string    `json:"protocol"`
+	Type      string    `json:"type"`
+	Routes    int64     `json:"routes"`
+	Previous  int64     `json:"previous"`
+	Threshold int64     `json:"threshold"`
+	Since     time.Time `json:"since"`
+	Resolved  bool      `json:"resolved,omitempty"`
+}
+
+var (
+	activeAlerts = metrics.NewGauge(
+		"birdwatcher_alerts_active",
+		"Currently active prefix count alerts",
+		"protocol", "type")
+	raisedAlerts = metrics.NewCounter(
+		"birdwatcher_alerts_raised_total",
+		"Number of raised prefix count alerts",
+		"type")
+)
+
+var state struct {
+	sync.RWMutex
+	previous   map[string]int64
+	violations map[string]*Violation
+}
+
+func importedRoutes(protocol bird.Parsed) int64 {
+	routes, ok := protocol["routes"].(bird.Parsed)
+	if !ok {
+		return 0
+	}
+	imported, _ := routes["imported"].(int64)
+	return imported
+}
+
+func dropPercent(previous, current int64) int64 {
+	if previous <= 0 || current >= previous {
+		return 0
+	}
+	return (previous - current) * 100 / previous
+}
+
+// evaluate checks a protocol against the configured rules
+func evaluate(
+	config Config,
+	peers map[string]PeerConfig,
+	name string,
+	protocol bird.Parsed,
+	previous int64,
+	hasPrevious bool,
+) []*Violation {
+	violations := []*Violation{}
+	routes := importedRoutes(protocol)
+
+	peer, hasPeer := peers[name]
+	drop := config.DropPercent
+	if hasPeer {
+		if peer.DropPercent > 0 {
+			drop = peer.DropPercent
+		}
+		if peer.MinRoutes > 0 && routes < peer.MinRoutes {
+			violations = append(violations, &Violation{
+				Protocol:  name,
+				Type:      ViolationBelowMin,
+				Routes:    routes,
+				Previous:  previous,
+				Threshold: peer.MinRoutes,
+			})
+		}
+		if peer.MaxRoutes > 0 && routes > peer.MaxRoutes {
+			violations = append(violations, &Violation{
+				Protocol:  name,
+				Type:      ViolationAboveMax,
+				Routes:    routes,
+				Previous:  previous,
+				Threshold: peer.MaxRoutes,
+			})
+		}
+	} else if protocol["bird_protocol"] != "BGP" {
+		return violations // Default rules only apply to BGP
+	}
+
+	if hasPrevious && drop > 0 && dropPercent(previous, routes) >= drop {
+		violations = append(violations, &Violation{
+			Protocol:  name,
+			Type:      ViolationDrop,
+			Routes:    routes,
+			Previous:  previous,
+			Threshold: drop,
+		})
+	}
+
+	return violations
+}
+
+// update evaluates all protocols and returns
+// the newly raised and the resolved violations.
+func update(config Config, protocols bird.Parsed, now time.Time) ([]*Violation, []*Violation) {
+	peers := make(map[string]PeerConfig)
+	for _, p := range config.Peers {
+		peers[p.Protocol] = p
+	}
+
+	state.Lock()
+	defer state.Unlock()
+
+	if state.violations == nil {
+		state.violations = make(map[string]*Violation)
+	}
+
+	current := make(map[string]*Violation)
+	counts := make(map[string]int64)
+	for name, p := range protocols {
+		protocol, ok := p.(bird.Parsed)
+		if !ok {
+			continue
+		}
+		previous, hasPrevious := state.previous[name]
+		for _, v := range evaluate(config, peers, name, protocol, previous, hasPrevious) {
+			current[name+"/"+v.Type] = v
+		}
+		counts[name] = importedRoutes(protocol)
+	}
+
+	raised := []*Violation{}
+	resolved := []*Violation{}
+	for key, v := range current {
+		if active, ok := state.violations[key]; ok {
+			v.Since = active.Since
+			continue
+		}
+		v.Since = now
+		raised = append(raised, v)
+	}
+	for key, v := range state.violations {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		// Drops are only visible for a single refresh,
+		// keep them active until the peer recovers.
+		if v.Type == ViolationDrop && counts[v.Protocol] < v.Previous {
+			current[key] = v
+			continue
+		}
+		v.Resolved = true
+		resolved = append(resolved, v)
+	}
+
+	state.previous = counts
+	state.violations = current
+
+	activeAlerts.Reset()
+	for _, v := range current {
+		activeAlerts.Set(1, v.Protocol, v.Type)
+	}
+	for _, v := range raised {
+		raisedAlerts.Inc(v.Type)
+	}
+
+	return raised, resolved
+}
+
+// Active returns all currently active violations
+func Active() []*Violation {
+	state.RLock()
+	violations := make([]*Violation, 0, len(state.violations))
+	for _, v := range state.violations {
+		violations = append(violations, v)
+	}
+	state.RUnlock()
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Protocol == violations[j].Protocol {
+			return violations[i].Type < violations[j].Type
+		}
+		return violations[i].Protocol < violations[j].Protocol
+	})
+
+	return violations
+}
+
+// Start evaluating the rules on every protocols refresh
+func Start(config Config) {
+	bird.RegisterProtocolsHook(func(p bird.Parsed) {
+		protocols, ok := p["protocols"].(bird.Parsed)
+		if !ok {
+			return
+		}
+
+		raised, resolved := update(config, protocols, time.Now().UTC())
+		if config.Webhook == "" {
+			return
+		}
+		for _, v := range append(raised, resolved...) {
+			go notify(config.Webhook, v)
+		}
+	})
+}
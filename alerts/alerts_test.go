@@ -0,0 +1,60 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+func protocolWithRoutes(imported int64) bird.Parsed {
+	return bird.Parsed{
+		"bird_protocol": "BGP",
+		"routes":        bird.Parsed{"imported": imported},
+	}
+}
+
+func TestUpdateAlerts(t *testing.T) {
+	config := Config{
+		DropPercent: 90,
+		Peers: []PeerConfig{
+			{Protocol: "R2", MinRoutes: 10, MaxRoutes: 100},
+		},
+	}
+
+	raised, _ := update(config, bird.Parsed{
+		"R1": protocolWithRoutes(1000),
+		"R2": protocolWithRoutes(5),
+	}, time.Now())
+	if len(raised) != 1 || raised[0].Type != ViolationBelowMin {
+		t.Fatal("Expected R2 to be below min, got:", raised)
+	}
+
+	raised, resolved := update(config, bird.Parsed{
+		"R1": protocolWithRoutes(20),
+		"R2": protocolWithRoutes(50),
+	}, time.Now())
+	if len(raised) != 1 || raised[0].Type != ViolationDrop {
+		t.Fatal("Expected R1 drop, got:", raised)
+	}
+	if len(resolved) != 1 || resolved[0].Protocol != "R2" {
+		t.Fatal("Expected R2 to be resolved, got:", resolved)
+	}
+
+	// The drop stays active until the routes recovered
+	update(config, bird.Parsed{
+		"R1": protocolWithRoutes(30),
+		"R2": protocolWithRoutes(50),
+	}, time.Now())
+	if len(Active()) != 1 {
+		t.Error("Expected drop to be still active")
+	}
+
+	_, resolved = update(config, bird.Parsed{
+		"R1": protocolWithRoutes(1000),
+		"R2": protocolWithRoutes(50),
+	}, time.Now())
+	if len(resolved) != 1 || len(Active()) != 0 {
+		t.Error("Expected drop to be resolved")
+	}
+}
@@ -0,0 +1,23 @@
+package alerts
+
+// Prefix count alerting configuration
+
+type Config struct {
+	Enabled bool   `toml:"enabled"`
+	Webhook string `toml:"webhook"`
+
+	// Alert if the number of imported routes of
+	// any BGP session drops by this percentage
+	// between two refreshes. Zero disables the check.
+	DropPercent int64 `toml:"drop_percent"`
+
+	Peers []PeerConfig `toml:"peers"`
+}
+
+// PeerConfig sets the expected routes for a protocol
+type PeerConfig struct {
+	Protocol    string `toml:"protocol"`
+	MinRoutes   int64  `toml:"min_routes"`
+	MaxRoutes   int64  `toml:"max_routes"`
+	DropPercent int64  `toml:"drop_percent"`
+}
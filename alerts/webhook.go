@@ -0,0 +1,33 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// Post the violation as JSON to the webhook
+func notify(url string, violation *Violation) {
+	payload, err := json.Marshal(violation)
+	if err != nil {
+		log.Println("Encoding alert failed:", err)
+		return
+	}
+
+	res, err := webhookClient.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		log.Println("Alert webhook failed:", err)
+		return
+	}
+	res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		log.Println("Alert webhook responded with:", res.Status)
+	}
+}
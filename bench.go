@@ -0,0 +1,111 @@
+package main
+
+// The bench subcommand measures parser throughput
+// and allocations on large birdc outputs.
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+type benchResult struct {
+	name     string
+	size     int
+	duration time.Duration
+	allocs   uint64
+	bytes    uint64
+}
+
+// benchParse parses the input runs times and reports
+// the mean duration and allocations per run.
+func benchParse(name, outputType string, input []byte, runs int) (*benchResult, error) {
+	res := &benchResult{name: name, size: len(input)}
+
+	var before, after runtime.MemStats
+	for i := 0; i < runs; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+
+		if _, err := bird.Parse(outputType, bytes.NewReader(input)); err != nil {
+			return nil, err
+		}
+
+		res.duration += time.Since(start)
+		runtime.ReadMemStats(&after)
+		res.allocs += after.Mallocs - before.Mallocs
+		res.bytes += after.TotalAlloc - before.TotalAlloc
+	}
+
+	n := uint64(runs)
+	res.duration /= time.Duration(runs)
+	res.allocs /= n
+	res.bytes /= n
+	return res, nil
+}
+
+func (r *benchResult) String() string {
+	mbs := float64(r.size) / (1 << 20) / r.duration.Seconds()
+	return fmt.Sprintf("%-20s %10d B %12s %8.2f MB/s %12d allocs %12d B alloc",
+		r.name, r.size, r.duration.Round(time.Millisecond), mbs, r.allocs, r.bytes)
+}
+
+func benchCommand(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	routes := flags.String("routes", "100000,1000000",
+		"Comma separated sizes of the generated route outputs")
+	outputType := flags.String("type", "routes",
+		"Type of the birdc output in files: "+strings.Join(bird.ParserTypes(), ", "))
+	runs := flags.Int("runs", 3, "Number of runs per input")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: birdwatcher bench [options] [file...]")
+		fmt.Fprintln(os.Stderr, "Without files, generated route outputs are parsed.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *runs < 1 {
+		return fmt.Errorf("runs must be at least 1")
+	}
+
+	for _, filename := range flags.Args() {
+		input, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		res, err := benchParse(filename, *outputType, input, *runs)
+		if err != nil {
+			return err
+		}
+		fmt.Println(res)
+	}
+	if flags.NArg() > 0 {
+		return nil
+	}
+
+	for _, size := range strings.Split(*routes, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil {
+			return fmt.Errorf("invalid number of routes: %s", size)
+		}
+		buf := &bytes.Buffer{}
+		if err := bird.GenerateRoutesOutput(buf, n); err != nil {
+			return err
+		}
+		res, err := benchParse(fmt.Sprintf("%d routes", n), "routes", buf.Bytes(), *runs)
+		if err != nil {
+			return err
+		}
+		fmt.Println(res)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package bird
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// announcementKey identifies identical announcements
+// of a prefix in different tables. Routes of different
+// peers are different announcements.
+func announcementKey(route Parsed) string {
+	key, _ := json.Marshal(Parsed{
+		"network":       route["network"],
+		"gateway":       route["gateway"],
+		"from_protocol": route["from_protocol"],
+		"bgp":           route["bgp"],
+	})
+	return string(key)
+}
+
+// dedupeKey identifies routes of a network with identical
+// BGP attributes. Unlike announcementKey, it ignores the peer
+// (from_protocol and gateway), as ?dedupe=true collapses the
+// announcements of all peers and lists them in peers.
+func dedupeKey(route Parsed) string {
+	key, _ := json.Marshal(Parsed{
+		"network": route["network"],
+		"bgp":     route["bgp"],
+	})
+	return string(key)
+}
+
+// A dedupedRoute collects the peers announcing
+// a collapsed route and the tables containing it.
+type dedupedRoute struct {
+	route  Parsed
+	peers  []string
+	tables []string
+	seen   map[string]bool
+}
+
+func (d *dedupedRoute) add(route Parsed) {
+	if peer, _ := route["from_protocol"].(string); peer != "" && !d.seen["peer "+peer] {
+		d.seen["peer "+peer] = true
+		d.peers = append(d.peers, peer)
+	}
+	tables, _ := route["tables"].([]string)
+	if table, ok := route["table"].(string); ok {
+		tables = append([]string{table}, tables...)
+	}
+	for _, table := range tables {
+		if table != "" && !d.seen["table "+table] {
+			d.seen["table "+table] = true
+			d.tables = append(d.tables, table)
+		}
+	}
+}
+
+// DedupeRoutes collapses the routes of a network with
+// identical BGP attributes into the first of them. It lists
+// the peers announcing the route and the tables containing it.
+// The table and peer of the first route are replaced by these
+// lists, e.g. for the merged routes of /routes/all.
+func DedupeRoutes(res Parsed) Parsed {
+	routes, ok := res["routes"].([]Parsed)
+	if !ok {
+		return res
+	}
+
+	deduped := []*dedupedRoute{}
+	seen := map[string]*dedupedRoute{}
+	for _, route := range routes {
+		key := dedupeKey(route)
+		known, ok := seen[key]
+		if !ok {
+			known = &dedupedRoute{route: route, seen: map[string]bool{}}
+			seen[key] = known
+			deduped = append(deduped, known)
+		}
+		known.add(route)
+	}
+
+	collapsed := make([]Parsed, 0, len(deduped))
+	for _, d := range deduped {
+		route := make(Parsed, len(d.route)+2)
+		for k, v := range d.route {
+			route[k] = v
+		}
+		delete(route, "table")
+		delete(route, "peer")
+		route["peers"] = append([]string{}, d.peers...)
+		route["tables"] = append([]string{}, d.tables...)
+		collapsed = append(collapsed, route)
+	}
+
+	result := make(Parsed, len(res))
+	for k, v := range res {
+		result[k] = v
+	}
+	result["routes"] = collapsed
+	return result
+}
+
+// mergePeerTableRoutes merges the routes of the peer
+// tables. Each route is annotated with the table and peer
+// it was first seen in and all tables containing it.
+func mergePeerTableRoutes(peers []PeerTable, routes map[string][]Parsed) []Parsed {
+	merged := []Parsed{}
+	seen := map[string]Parsed{}
+	for _, peer := range peers {
+		for _, route := range routes[peer.Table] {
+			key := announcementKey(route)
+			if known, ok := seen[key]; ok {
+				known["tables"] = append(known["tables"].([]string), peer.Table)
+				continue
+			}
+
+			annotated := make(Parsed, len(route)+3)
+			for k, v := range route {
+				annotated[k] = v
+			}
+			annotated["table"] = peer.Table
+			annotated["peer"] = peer.Protocol
+			annotated["tables"] = []string{peer.Table}
+
+			seen[key] = annotated
+			merged = append(merged, annotated)
+		}
+	}
+	return merged
+}
+
+// RoutesAllPeerTables returns the deduplicated routes
+// of all discovered peer tables.
+func RoutesAllPeerTables(ctx context.Context, useCache bool) (Parsed, bool, error) {
+	discovered, fromCache, err := DiscoverPeerTables(ctx, useCache)
+	if err != nil {
+		return nil, false, err
+	}
+	discoveredPeers, _ := discovered["peers"].([]PeerTable)
+
+	// Query each table once
+	peers := []PeerTable{}
+	queried := map[string]bool{}
+	for _, peer := range discoveredPeers {
+		if !peer.TableExists || queried[peer.Table] {
+			continue
+		}
+		queried[peer.Table] = true
+		peers = append(peers, peer)
+	}
+
+	routes := map[string][]Parsed{}
+	tableErrors := Parsed{}
+	var expires time.Time
+	var firstError error
+	for _, peer := range peers {
+		res, cached, err := RoutesTable(ctx, useCache, peer.Table)
+		fromCache = fromCache && cached
+		if err != nil {
+			tableErrors[peer.Table] = err.Error()
+			if firstError == nil {
+				firstError = err
+			}
+			continue
+		}
+		routes[peer.Table], _ = res["routes"].([]Parsed)
+		if ttl, ok := CacheExpiry(res); ok && (expires.IsZero() || ttl.Before(expires)) {
+			expires = ttl
+		}
+	}
+
+	// Fail only if no table could be queried
+	if firstError != nil && len(routes) == 0 {
+		return nil, false, firstError
+	}
+
+	res := Parsed{
+		"routes":    mergePeerTableRoutes(peers, routes),
+		"cached_at": discovered["cached_at"],
+	}
+	if len(tableErrors) > 0 {
+		res["table_errors"] = tableErrors
+	}
+	if !expires.IsZero() {
+		res["ttl"] = expires
+	}
+	return res, fromCache, nil
+}
@@ -0,0 +1,137 @@
+package bird
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseASN parses an AS number in plain ("196618"),
+// asdot ("3.10") or prefixed ("AS196618") notation.
+func ParseASN(value string) (uint32, error) {
+	asn := strings.TrimSpace(value)
+	if len(asn) > 2 && strings.EqualFold(asn[:2], "AS") {
+		asn = asn[2:]
+	}
+
+	if dot := strings.Index(asn, "."); dot >= 0 {
+		high, errHigh := strconv.ParseUint(asn[:dot], 10, 16)
+		low, errLow := strconv.ParseUint(asn[dot+1:], 10, 16)
+		if errHigh != nil || errLow != nil {
+			return 0, fmt.Errorf("Invalid AS number: %s", value)
+		}
+		return uint32(high<<16 | low), nil
+	}
+
+	n, err := strconv.ParseUint(asn, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid AS number: %s", value)
+	}
+	return uint32(n), nil
+}
+
+// neighborASN reads the parsed neighbor AS of a protocol.
+// Values with trailing information are parsed as strings.
+func neighborASN(protocol Parsed) (uint32, bool) {
+	switch v := protocol["neighbor_as"].(type) {
+	case int64:
+		if v < 0 || v > 1<<32-1 {
+			return 0, false
+		}
+		return uint32(v), true
+	case string:
+		fields := strings.Fields(v)
+		if len(fields) == 0 {
+			return 0, false
+		}
+		asn, err := ParseASN(fields[0])
+		return asn, err == nil
+	}
+	return 0, false
+}
+
+// ProtocolsASN returns all BGP sessions with the neighbor AS
+// using the index built with the protocols.
+func ProtocolsASN(ctx context.Context, useCache bool, asn uint32) (Parsed, bool, error) {
+	protocols, from_cache, err := Protocols(ctx, useCache)
+	if err != nil {
+		return nil, false, err
+	}
+
+	sessions := Parsed{}
+	protocolsMeta, ok := fromCache(GetCacheKey("metaProtocol"))
+	if ok {
+		index, _ := protocolsMeta["protocols"].(Parsed)["neighbor_as"].(Parsed)
+		indexed, _ := index[strconv.FormatUint(uint64(asn), 10)].(Parsed)
+		for key, protocol := range indexed {
+			sessions[key] = *(protocol.(*Parsed))
+		}
+	}
+
+	return Parsed{
+		"asn":       asn,
+		"protocols": sessions,
+		"ttl":       protocols["ttl"],
+		"cached_at": protocols["cached_at"]}, from_cache, nil
+}
+
+// RoutesExportASN returns the routes exported to all BGP
+// sessions with the neighbor AS. Each route is annotated
+// with the session it is exported to.
+func RoutesExportASN(ctx context.Context, useCache bool, asn uint32) (Parsed, bool, error) {
+	res, fromCache, err := ProtocolsASN(ctx, useCache, asn)
+	if err != nil {
+		return nil, false, err
+	}
+	sessions, _ := res["protocols"].(Parsed)
+
+	names := make([]string, 0, len(sessions))
+	for name := range sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	routes := []Parsed{}
+	counts := Parsed{}
+	sessionErrors := Parsed{}
+	var expires time.Time
+	for _, name := range names {
+		exported, cached, err := RoutesExport(ctx, useCache, name)
+		fromCache = fromCache && cached
+		if err != nil {
+			sessionErrors[name] = err.Error()
+			continue
+		}
+		if ttl, ok := CacheExpiry(exported); ok && (expires.IsZero() || ttl.Before(expires)) {
+			expires = ttl
+		}
+
+		sessionRoutes, _ := exported["routes"].([]Parsed)
+		for _, route := range sessionRoutes {
+			annotated := make(Parsed, len(route)+1)
+			for k, v := range route {
+				annotated[k] = v
+			}
+			annotated["session"] = name
+			routes = append(routes, annotated)
+		}
+		counts[name] = len(sessionRoutes)
+	}
+
+	result := Parsed{
+		"asn":       asn,
+		"sessions":  counts,
+		"routes":    routes,
+		"cached_at": res["cached_at"],
+	}
+	if len(sessionErrors) > 0 {
+		result["session_errors"] = sessionErrors
+	}
+	if !expires.IsZero() {
+		result["ttl"] = expires
+	}
+	return result, fromCache, nil
+}
@@ -0,0 +1,59 @@
+package bird
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseASN(t *testing.T) {
+	for value, expected := range map[string]uint32{
+		"65001":      65001,
+		"AS65001":    65001,
+		"as196618":   196618,
+		"3.10":       196618,
+		"4294967295": 4294967295,
+	} {
+		asn, err := ParseASN(value)
+		if err != nil || asn != expected {
+			t.Error("Expected", expected, "for", value, "got:", asn, err)
+		}
+	}
+
+	for _, value := range []string{"", "AS", "4294967296", "1.65536", "-1", "65001x"} {
+		if _, err := ParseASN(value); err == nil {
+			t.Error("Expected an error for", value)
+		}
+	}
+}
+
+func TestNeighborASN(t *testing.T) {
+	for _, value := range []interface{}{int64(65001), "65001", "65001 (internal)"} {
+		if asn, ok := neighborASN(Parsed{"neighbor_as": value}); !ok || asn != 65001 {
+			t.Error("Expected 65001 for", value, "got:", asn)
+		}
+	}
+	if _, ok := neighborASN(Parsed{}); ok {
+		t.Error("Expected no neighbor AS")
+	}
+}
+
+func TestRoutesExportASN(t *testing.T) {
+	defer withFixtures(t, map[string]string{
+		"protocols_all": readSample(t, "protocols_bgp_pipe.sample"),
+		"route_all":     readSample(t, "routes_bird1_ipv4.sample"),
+	})()
+
+	res, _, _ := RoutesExportASN(context.Background(), false, 1764)
+	routes, _ := res["routes"].([]Parsed)
+	if len(routes) == 0 || routes[0]["session"] != "R194_42" {
+		t.Fatal("Expected the exported routes of R194_42, got:", res)
+	}
+	if sessions := res["sessions"].(Parsed); sessions["R194_42"] != len(routes) {
+		t.Error("Unexpected session counts:", sessions)
+	}
+
+	res, _, _ = RoutesExportASN(context.Background(), false, 64500)
+	if routes := res["routes"].([]Parsed); len(routes) != 0 {
+		t.Error("Expected no routes for an unknown AS, got:", routes)
+	}
+}
@@ -2,21 +2,25 @@ package bird
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"log"
-	"reflect"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"os/exec"
+
+	"github.com/alice-lg/birdwatcher/tracing"
 )
 
 type Cache interface {
 	Set(key string, val Parsed, ttl int) error
 	Get(key string) (Parsed, error)
 	Expire() int
+	Flush() int
 }
 
 var ClientConf BirdConfig
@@ -27,15 +31,23 @@ var cache Cache // stores parsed birdc output
 var CacheConf CacheConfig
 var RateLimitConf struct {
 	sync.RWMutex
-	Conf RateLimitConfig
+	Conf     RateLimitConfig
+	Rejected int // Requests rejected since startup
 }
-var RunQueue sync.Map // queue birdc commands before execution
 
-var NilParse Parsed = (Parsed)(nil) // special Parsed values
-var BirdError Parsed = Parsed{"error": "bird unreachable"}
+// Requests waiting for the rate limit reset
+var rateLimitQueue struct {
+	sync.Mutex
+	waiting int
+	reset   chan struct{} // Closed on the next reset
+}
+var RunQueue sync.Map // queue birdc commands before execution
 
-func IsSpecial(ret Parsed) bool { // test for special Parsed values
-	return reflect.DeepEqual(ret, NilParse) || reflect.DeepEqual(ret, BirdError)
+// A queuedRun is a running birdc command. Queries for
+// the same command wait for it and share the error.
+type queuedRun struct {
+	sync.WaitGroup
+	err *QueryError
 }
 
 // intitialize the Cache once during setup with either a MemoryCache or
@@ -56,7 +68,9 @@ func InitializeCache() {
 			maxKeys = maxKeysDefault
 		}
 
-		cache = NewMemoryCache(maxKeys)
+		memoryCache := NewMemoryCache(maxKeys)
+		memoryCache.evicted = cacheEvicted
+		cache = memoryCache
 		log.Println("Initialized MemoryCache with maxKeys:", maxKeys)
 	}
 }
@@ -66,6 +80,11 @@ func ExpireCache() int {
 	return cache.Expire()
 }
 
+// FlushCache is a convenience method to remove all cached results.
+func FlushCache() int {
+	return cache.Flush()
+}
+
 /* Convenience method to make new entries in the cache.
  * Abstracts over the specific caching implementation and the ability to set
  * individual TTL values for entries. Always use the default TTL value from the
@@ -121,9 +140,23 @@ func GetCacheKey(fname string, fargs ...interface{}) string {
 }
 
 func Run(args string) (io.Reader, error) {
+	if ClientConf.Fixtures != "" {
+		return runFixture(args)
+	}
+
 	args = "-r " + "show " + args // enforce birdc in restricted mode with "-r" argument
-	argsList := strings.Split(args, " ")
+	out, err := runBirdc(strings.Split(args, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(out), nil
+}
 
+// runBirdc executes birdc with the arguments,
+// aborting after the configured timeout. The output
+// of show commands is captured before it is normalized.
+func runBirdc(argsList []string) ([]byte, error) {
 	// Allow for arguments in the config
 	cmdArgs := strings.Split(ClientConf.BirdCmd, " ")
 	birdc := cmdArgs[0]
@@ -133,12 +166,22 @@ func Run(args string) (io.Reader, error) {
 	cmd = append(cmd, cmdArgs...)
 	cmd = append(cmd, argsList...)
 
-	out, err := exec.Command(birdc, cmd...).Output()
-	if err != nil {
-		return nil, err
+	ctx := context.Background()
+	if ClientConf.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(ClientConf.Timeout)*time.Second)
+		defer cancel()
 	}
 
-	return bytes.NewReader(out), nil
+	out, err := exec.CommandContext(ctx, birdc, cmd...).Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, ctx.Err()
+	}
+	if CaptureDir != "" && err == nil &&
+		len(argsList) > 2 && argsList[0] == "-r" && argsList[1] == "show" {
+		capture(strings.Join(argsList[2:], " "), out)
+	}
+	return NormalizeOutput(out), err
 }
 
 func InstallRateLimitReset() {
@@ -146,85 +189,282 @@ func InstallRateLimitReset() {
 		c := time.Tick(time.Second)
 
 		for _ = range c {
-			RateLimitConf.Lock()
-			RateLimitConf.Conf.Reqs = RateLimitConf.Conf.Max
-			RateLimitConf.Unlock()
+			resetRateLimit()
 		}
 	}()
 }
 
-func checkRateLimit() bool {
-	RateLimitConf.RLock()
-	check := !RateLimitConf.Conf.Enabled
-	RateLimitConf.RUnlock()
-	if check {
+func resetRateLimit() {
+	RateLimitConf.Lock()
+	RateLimitConf.Conf.Reqs = RateLimitConf.Conf.Max
+	RateLimitConf.Unlock()
+
+	rateLimitQueue.Lock()
+	if rateLimitQueue.reset != nil {
+		close(rateLimitQueue.reset)
+		rateLimitQueue.reset = nil
+	}
+	rateLimitQueue.Unlock()
+}
+
+// nextRateLimitReset returns a channel closed on the next reset
+func nextRateLimitReset() chan struct{} {
+	rateLimitQueue.Lock()
+	defer rateLimitQueue.Unlock()
+	if rateLimitQueue.reset == nil {
+		rateLimitQueue.reset = make(chan struct{})
+	}
+	return rateLimitQueue.reset
+}
+
+func takeRateLimitToken() bool {
+	RateLimitConf.Lock()
+	defer RateLimitConf.Unlock()
+	if !RateLimitConf.Conf.Enabled {
 		return true
 	}
+	if RateLimitConf.Conf.Reqs < 1 {
+		return false
+	}
+	RateLimitConf.Conf.Reqs -= 1
+	return true
+}
 
+// waitRateLimitToken queues the request until the rate limit
+// is reset, for at most the configured queue delay.
+func waitRateLimitToken() bool {
 	RateLimitConf.RLock()
-	check = RateLimitConf.Conf.Reqs < 1
+	size := RateLimitConf.Conf.QueueSize
+	delay := time.Duration(RateLimitConf.Conf.QueueDelay) * time.Millisecond
 	RateLimitConf.RUnlock()
-	if check {
+	if size <= 0 || delay <= 0 {
 		return false
 	}
 
+	rateLimitQueue.Lock()
+	if rateLimitQueue.waiting >= size {
+		rateLimitQueue.Unlock()
+		return false
+	}
+	rateLimitQueue.waiting++
+	rateLimitQueue.Unlock()
+	defer func() {
+		rateLimitQueue.Lock()
+		rateLimitQueue.waiting--
+		rateLimitQueue.Unlock()
+	}()
+
+	timeout := time.NewTimer(delay)
+	defer timeout.Stop()
+	for {
+		reset := nextRateLimitReset()
+		if takeRateLimitToken() {
+			return true
+		}
+		select {
+		case <-reset:
+		case <-timeout.C:
+			return false
+		}
+	}
+}
+
+func checkRateLimit() bool {
+	if takeRateLimitToken() || waitRateLimitToken() {
+		return true
+	}
+
 	RateLimitConf.Lock()
-	RateLimitConf.Conf.Reqs -= 1
+	RateLimitConf.Rejected += 1
 	RateLimitConf.Unlock()
+	return false
+}
 
-	return true
+type rateLimitKey struct{}
+
+// withSharedRateLimit returns a context, in which the birdc
+// commands take a single rate limit token together, e.g. the
+// lookups of one request.
+func withSharedRateLimit(ctx context.Context) context.Context {
+	var (
+		once    sync.Once
+		allowed bool
+	)
+	return context.WithValue(ctx, rateLimitKey{}, func() bool {
+		once.Do(func() { allowed = checkRateLimit() })
+		return allowed
+	})
+}
+
+// withoutRateLimit returns a context, in which the
+// birdc commands are not rate limited.
+func withoutRateLimit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rateLimitKey{}, func() bool { return true })
+}
+
+// allowedByRateLimit checks the rate limit of the context,
+// or takes a token of the rate limit for each command.
+func allowedByRateLimit(ctx context.Context) bool {
+	if check, ok := ctx.Value(rateLimitKey{}).(func() bool); ok {
+		return check()
+	}
+	return checkRateLimit()
+}
+
+// RateLimitStatus reports the configuration, the remaining
+// requests and the number of rejected and queued requests.
+func RateLimitStatus() Parsed {
+	rateLimitQueue.Lock()
+	waiting := rateLimitQueue.waiting
+	rateLimitQueue.Unlock()
+
+	RateLimitConf.RLock()
+	defer RateLimitConf.RUnlock()
+
+	return Parsed{
+		"enabled":             RateLimitConf.Conf.Enabled,
+		"requests_per_minute": RateLimitConf.Conf.Max,
+		"tokens":              RateLimitConf.Conf.Reqs,
+		"rejected":            RateLimitConf.Rejected,
+		"queue_size":          RateLimitConf.Conf.QueueSize,
+		"queued":              waiting,
+	}
+}
+
+// parse runs the parser and converts a panic on
+// unexpected output into a parse error.
+func parse(cmd string, parser func(io.Reader) Parsed, out io.Reader) (parsed Parsed, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Parsing output of", cmd, "failed:", r)
+			parsed, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	return parser(out), nil
+}
+
+// CommandClass returns the type of a birdc command,
+// e.g. "route", "route_count" or "protocols".
+func CommandClass(cmd string) string {
+	class := strings.SplitN(cmd, " ", 2)[0]
+	if class == "route" && strings.Contains(cmd, " count") {
+		return "route_count"
+	}
+	return class
 }
 
-func RunAndParse(useCache bool, key string, cmd string, parser func(io.Reader) Parsed, updateCache func(*Parsed)) (Parsed, bool) {
-	var wg sync.WaitGroup
+func RunAndParse(ctx context.Context, useCache bool, key string, cmd string, parser func(io.Reader) Parsed, updateCache func(*Parsed)) (res Parsed, fromCacheHit bool, queryErr error) {
+	class := CommandClass(cmd)
+	span := tracing.StartSpanFromContext(ctx, "birdc "+class)
+	span.SetAttribute("birdc.command", cmd)
+	defer span.End()
+
+	start := time.Now()
+	defer func() {
+		observeQuery(class, fromCacheHit, time.Since(start))
+	}()
 
 	if useCache {
-		if val, ok := fromCache(cmd); ok {
-			return val, true
+		child := span.Child("cache")
+		val, ok := fromCache(cmd)
+		child.SetAttribute("cache.hit", ok)
+		child.End()
+		observeCacheLookup(cmd, class, ok)
+		if ok {
+			span.SetAttribute("cache.hit", true)
+			return val, true, nil
 		}
+		logQuery(ctx, "cache miss of", cmd)
 	}
 
-	wg.Add(1)
-	if queueGroup, queueLoaded := RunQueue.LoadOrStore(cmd, &wg); queueLoaded {
-		(*queueGroup.(*sync.WaitGroup)).Wait()
+	run := &queuedRun{}
+	run.Add(1)
+	if queued, queueLoaded := RunQueue.LoadOrStore(cmd, run); queueLoaded {
+		child := span.Child("queue")
+		queued := queued.(*queuedRun)
+		queued.Wait()
+		child.End()
 
 		if val, ok := fromCache(cmd); ok {
-			return val, true
-		} else {
-			// TODO BirdError should also be signaled somehow
-			return NilParse, false
+			return val, true, nil
+		}
+		if queued.err != nil {
+			span.SetError(queued.err)
+			return nil, false, queued.err
 		}
+		span.SetError(ErrRateLimited)
+		return nil, false, ErrRateLimited
 	}
 
-	if !checkRateLimit() {
-		wg.Done()
+	fail := func(err *QueryError) (Parsed, bool, error) {
+		span.SetError(err)
+		run.err = err
+		run.Done()
 		RunQueue.Delete(cmd)
-		return NilParse, false
+		return nil, false, err
+	}
+
+	if shedLoad(cmd) {
+		return fail(ErrOverloaded)
+	}
+
+	child := span.Child("rate_limit")
+	allowed := allowedByRateLimit(ctx)
+	child.End()
+	if !allowed {
+		return fail(ErrRateLimited)
 	}
 
+	logQuery(ctx, "running birdc", cmd)
+	child = span.Child("exec")
+	execStart := time.Now()
 	out, err := Run(cmd)
+	outputSize := 0
+	if r, ok := out.(*bytes.Reader); ok {
+		outputSize = r.Len()
+		child.SetAttribute("birdc.output_bytes", outputSize)
+	}
+	observeExec(cmd, class, outputSize, time.Since(execStart))
+	child.SetError(err)
+	child.End()
 	if err != nil {
-		// ignore errors for now
-		wg.Done()
-		RunQueue.Delete(cmd)
-		return BirdError, false
+		recordError(ctx, cmd, err)
+		if err == context.DeadlineExceeded {
+			return fail(ErrTimeout)
+		}
+		return fail(ErrUnreachable)
 	}
 
-	parsed := parser(out)
+	release := trackInFlight(outputSize)
+	defer release()
+
+	child = span.Child("parse")
+	parseStart := time.Now()
+	parsed, err := parse(cmd, parser, out)
+	observeParse(cmd, class, outputSize, time.Since(parseStart))
+	child.SetError(err)
+	child.End()
+	if err != nil {
+		recordError(ctx, cmd, err)
+		return fail(ErrParse)
+	}
+	observeWarnings(cmd, class, parsed)
 
 	if updateCache != nil {
 		updateCache(&parsed)
 	}
 
-	toCache(cmd, parsed)
+	if toCache(cmd, parsed) {
+		cacheStored(cmd, outputSize)
+	}
 
-	wg.Done()
+	run.Done()
 	RunQueue.Delete(cmd)
 
-	return parsed, false
+	return parsed, false, nil
 }
 
-func Status(useCache bool) (Parsed, bool) {
+func Status(ctx context.Context, useCache bool) (Parsed, bool, error) {
 	updateParsedCache := func(p *Parsed) {
 		status := (*p)["status"].(Parsed)
 
@@ -246,6 +486,7 @@ func Status(useCache bool) (Parsed, bool) {
 		}
 
 		status["last_reconfig"] = lastReconfig
+		setBirdVersion(status)
 
 		// Filter fields
 		for _, field := range StatusConf.FilterFields {
@@ -253,18 +494,16 @@ func Status(useCache bool) (Parsed, bool) {
 		}
 	}
 
-	birdStatus, from_cache := RunAndParse(useCache, GetCacheKey("Status"), "status", parseStatus, updateParsedCache)
-	return birdStatus, from_cache
+	return RunAndParse(ctx, useCache, GetCacheKey("Status"), "status", parseStatus, updateParsedCache)
 }
 
-func ProtocolsShort(useCache bool) (Parsed, bool) {
-	res, from_cache := RunAndParse(useCache, GetCacheKey("ProtocolsShort"), "protocols", parseProtocolsShort, nil)
-	return res, from_cache
+func ProtocolsShort(ctx context.Context, useCache bool) (Parsed, bool, error) {
+	return RunAndParse(ctx, useCache, GetCacheKey("ProtocolsShort"), "protocols", parseProtocolsShort, nil)
 }
 
-func Protocols(useCache bool) (Parsed, bool) {
+func Protocols(ctx context.Context, useCache bool) (Parsed, bool, error) {
 	createMetaCache := func(p *Parsed) {
-		metaProtocol := Parsed{"protocols": Parsed{"bird_protocol": Parsed{}}}
+		metaProtocol := Parsed{"protocols": Parsed{"bird_protocol": Parsed{}, "neighbor_as": Parsed{}}}
 
 		for key, _ := range (*p)["protocols"].(Parsed) {
 			parsed := (*p)["protocols"].(Parsed)[key].(Parsed)
@@ -276,19 +515,30 @@ func Protocols(useCache bool) (Parsed, bool) {
 				metaProtocol["protocols"].(Parsed)["bird_protocol"].(Parsed)[birdProtocol] = Parsed{}
 			}
 			metaProtocol["protocols"].(Parsed)["bird_protocol"].(Parsed)[birdProtocol].(Parsed)[protocol] = &parsed
+
+			// Index the BGP sessions by neighbor AS
+			if asn, ok := neighborASN(parsed); ok && birdProtocol == "BGP" {
+				index := metaProtocol["protocols"].(Parsed)["neighbor_as"].(Parsed)
+				key := strconv.FormatUint(uint64(asn), 10)
+				if _, ok := index[key]; !ok {
+					index[key] = Parsed{}
+				}
+				index[key].(Parsed)[protocol] = &parsed
+			}
 		}
 
 		toCache(GetCacheKey("metaProtocol"), metaProtocol)
+
+		runProtocolsHooks(*p)
 	}
 
-	res, from_cache := RunAndParse(useCache, GetCacheKey("Protocols"), "protocols all", parseProtocols, createMetaCache)
-	return res, from_cache
+	return RunAndParse(ctx, useCache, GetCacheKey("Protocols"), "protocols all", parseProtocols, createMetaCache)
 }
 
-func ProtocolsBgp(useCache bool) (Parsed, bool) {
-	protocols, from_cache := Protocols(useCache)
-	if IsSpecial(protocols) {
-		return protocols, from_cache
+func ProtocolsBgp(ctx context.Context, useCache bool) (Parsed, bool, error) {
+	protocols, from_cache, err := Protocols(ctx, useCache)
+	if err != nil {
+		return nil, false, err
 	}
 
 	protocolsMeta, _ := fromCache(GetCacheKey("metaProtocol"))
@@ -302,31 +552,44 @@ func ProtocolsBgp(useCache bool) (Parsed, bool) {
 
 	return Parsed{"protocols": bgpProtocols,
 		"ttl":       protocols["ttl"],
-		"cached_at": protocols["cached_at"]}, from_cache
+		"cached_at": protocols["cached_at"]}, from_cache, nil
 }
 
-func Symbols(useCache bool) (Parsed, bool) {
-	return RunAndParse(useCache, GetCacheKey("Symbols"), "symbols", parseSymbols, nil)
+func Symbols(ctx context.Context, useCache bool) (Parsed, bool, error) {
+	return RunAndParse(ctx, useCache, GetCacheKey("Symbols"), "symbols", parseSymbols, nil)
 }
 
 func routesQuery(filter string) string {
 	cmd := "route " + filter
 
-	if getBirdVersion() < 2 || ClientConf.Dualstack {
+	channel := channelFilter()
+	if channel == "" {
 		return cmd
 	}
 
-	return cmd + " where net.type = NET_IP" + IPVersion
+	return cmd + " where " + channel
 }
 
-func remapTable(table string) string {
-	if v := getBirdVersion(); v < 2 {
-		return table // Nothing to do for bird1
+// defaultTable restricts a query to the configured default
+// table. Without a default table, BIRD uses its master table.
+func defaultTable(filter string) string {
+	if ClientConf.DefaultTable == "" {
+		return filter
 	}
+	return filter + " table '" + ClientConf.DefaultTable + "'"
+}
 
+func remapTable(table string) string {
 	if table != "master" {
 		return table // Nothing to do here
 	}
+	if ClientConf.DefaultTable != "" {
+		return ClientConf.DefaultTable
+	}
+
+	if v := getBirdVersion(); v < 2 {
+		return table // Nothing to do for bird1
+	}
 
 	// Rewrite master table
 	if IPVersion == "4" {
@@ -336,9 +599,11 @@ func remapTable(table string) string {
 	return "master6"
 }
 
-func RoutesPrefixed(useCache bool, prefix string) (Parsed, bool) {
-	cmd := routesQuery(prefix + " all")
+func RoutesPrefixed(ctx context.Context, useCache bool, prefix string) (Parsed, bool, error) {
+	cmd := routesQuery(defaultTable(prefix + " all"))
+	cmd = queryCommand("RoutesPrefixed", QueryVars{Prefix: prefix}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesPrefixed", prefix),
 		cmd,
@@ -346,9 +611,11 @@ func RoutesPrefixed(useCache bool, prefix string) (Parsed, bool) {
 		nil)
 }
 
-func RoutesProto(useCache bool, protocol string) (Parsed, bool) {
-	cmd := routesQuery("all protocol '" + protocol + "'")
+func RoutesProto(ctx context.Context, useCache bool, protocol string) (Parsed, bool, error) {
+	cmd := routesQuery(defaultTable("all protocol '" + protocol + "'"))
+	cmd = queryCommand("RoutesProto", QueryVars{Protocol: protocol}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesProto", protocol),
 		cmd,
@@ -356,9 +623,11 @@ func RoutesProto(useCache bool, protocol string) (Parsed, bool) {
 		nil)
 }
 
-func RoutesPeer(useCache bool, peer string) (Parsed, bool) {
-	cmd := "route all where from=" + peer
+func RoutesPeer(ctx context.Context, useCache bool, peer string) (Parsed, bool, error) {
+	cmd := "route " + defaultTable("all") + " where from=" + peer
+	cmd = queryCommand("RoutesPeer", QueryVars{Peer: peer}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesPeer", peer),
 		cmd,
@@ -366,10 +635,12 @@ func RoutesPeer(useCache bool, peer string) (Parsed, bool) {
 		nil)
 }
 
-func RoutesTableAndPeer(useCache bool, table string, peer string) (Parsed, bool) {
+func RoutesTableAndPeer(ctx context.Context, useCache bool, table string, peer string) (Parsed, bool, error) {
 	table = remapTable(table)
 	cmd := "route table '" + table + "' all where from=" + peer
+	cmd = queryCommand("RoutesTableAndPeer", QueryVars{Table: table, Peer: peer}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesTableAndPeer", table, peer),
 		cmd,
@@ -377,9 +648,11 @@ func RoutesTableAndPeer(useCache bool, table string, peer string) (Parsed, bool)
 		nil)
 }
 
-func RoutesProtoCount(useCache bool, protocol string) (Parsed, bool) {
-	cmd := routesQuery("protocol '" + protocol + "' count")
+func RoutesProtoCount(ctx context.Context, useCache bool, protocol string) (Parsed, bool, error) {
+	cmd := routesQuery(defaultTable("protocol '" + protocol + "' count"))
+	cmd = queryCommand("RoutesProtoCount", QueryVars{Protocol: protocol}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesProtoCount", protocol),
 		cmd,
@@ -387,9 +660,11 @@ func RoutesProtoCount(useCache bool, protocol string) (Parsed, bool) {
 		nil)
 }
 
-func RoutesProtoPrimaryCount(useCache bool, protocol string) (Parsed, bool) {
-	cmd := routesQuery("primary protocol '" + protocol + "' count")
+func RoutesProtoPrimaryCount(ctx context.Context, useCache bool, protocol string) (Parsed, bool, error) {
+	cmd := routesQuery(defaultTable("primary protocol '" + protocol + "' count"))
+	cmd = queryCommand("RoutesProtoPrimaryCount", QueryVars{Protocol: protocol}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesProtoPrimaryCount", protocol),
 		cmd,
@@ -397,12 +672,14 @@ func RoutesProtoPrimaryCount(useCache bool, protocol string) (Parsed, bool) {
 		nil)
 }
 
-func PipeRoutesFilteredCount(useCache bool, pipe string, table string, neighborAddress string) (Parsed, bool) {
+func PipeRoutesFilteredCount(ctx context.Context, useCache bool, pipe string, table string, neighborAddress string) (Parsed, bool, error) {
 	table = remapTable(table)
 	cmd := "route table '" + table +
 		"' noexport '" + pipe +
 		"' where from=" + neighborAddress + " count"
+	cmd = queryCommand("PipeRoutesFilteredCount", QueryVars{Table: table, Pipe: pipe, Peer: neighborAddress}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("PipeRoutesFilteredCount", table, pipe, neighborAddress),
 		cmd,
@@ -410,10 +687,12 @@ func PipeRoutesFilteredCount(useCache bool, pipe string, table string, neighborA
 		nil)
 }
 
-func PipeRoutesFiltered(useCache bool, pipe string, table string) (Parsed, bool) {
+func PipeRoutesFiltered(ctx context.Context, useCache bool, pipe string, table string) (Parsed, bool, error) {
 	table = remapTable(table)
 	cmd := routesQuery("table '" + table + "' noexport '" + pipe + "' all")
+	cmd = queryCommand("PipeRoutesFiltered", QueryVars{Table: table, Pipe: pipe}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("PipeRoutesFiltered", table, pipe),
 		cmd,
@@ -421,9 +700,11 @@ func PipeRoutesFiltered(useCache bool, pipe string, table string) (Parsed, bool)
 		nil)
 }
 
-func RoutesFiltered(useCache bool, protocol string) (Parsed, bool) {
-	cmd := routesQuery("all filtered protocol '" + protocol + "'")
+func RoutesFiltered(ctx context.Context, useCache bool, protocol string) (Parsed, bool, error) {
+	cmd := routesQuery(defaultTable("all filtered protocol '" + protocol + "'"))
+	cmd = queryCommand("RoutesFiltered", QueryVars{Protocol: protocol}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesFiltered", protocol),
 		cmd,
@@ -431,9 +712,11 @@ func RoutesFiltered(useCache bool, protocol string) (Parsed, bool) {
 		nil)
 }
 
-func RoutesExport(useCache bool, protocol string) (Parsed, bool) {
+func RoutesExport(ctx context.Context, useCache bool, protocol string) (Parsed, bool, error) {
 	cmd := routesQuery("all export '" + protocol + "'")
+	cmd = queryCommand("RoutesExport", QueryVars{Protocol: protocol}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesExport", protocol),
 		cmd,
@@ -441,9 +724,20 @@ func RoutesExport(useCache bool, protocol string) (Parsed, bool) {
 		nil)
 }
 
-func RoutesNoExport(useCache bool, protocol string) (Parsed, bool) {
+func RoutesNoExport(ctx context.Context, useCache bool, protocol string) (Parsed, bool, error) {
+	// In a multi table setup, the routes are not
+	// exported by the pipe of the peer
+	vars := QueryVars{Protocol: protocol}
+	if pipe, table, ok := PeerPipeAndTable(protocol); ok {
+		protocol = pipe
+		vars.Pipe = pipe
+		vars.Table = table
+	}
+
 	cmd := routesQuery("all noexport '" + protocol + "'")
+	cmd = queryCommand("RoutesNoExport", vars, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesNoExport", protocol),
 		cmd,
@@ -451,9 +745,11 @@ func RoutesNoExport(useCache bool, protocol string) (Parsed, bool) {
 		nil)
 }
 
-func RoutesExportCount(useCache bool, protocol string) (Parsed, bool) {
+func RoutesExportCount(ctx context.Context, useCache bool, protocol string) (Parsed, bool, error) {
 	cmd := routesQuery("export '" + protocol + "' count")
+	cmd = queryCommand("RoutesExportCount", QueryVars{Protocol: protocol}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesExportCount", protocol),
 		cmd,
@@ -461,10 +757,12 @@ func RoutesExportCount(useCache bool, protocol string) (Parsed, bool) {
 		nil)
 }
 
-func RoutesTable(useCache bool, table string) (Parsed, bool) {
+func RoutesTable(ctx context.Context, useCache bool, table string) (Parsed, bool, error) {
 	table = remapTable(table)
 	cmd := routesQuery("table '" + table + "' all")
+	cmd = queryCommand("RoutesTable", QueryVars{Table: table}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesTable", table),
 		cmd,
@@ -472,10 +770,12 @@ func RoutesTable(useCache bool, table string) (Parsed, bool) {
 		nil)
 }
 
-func RoutesTableFiltered(useCache bool, table string) (Parsed, bool) {
+func RoutesTableFiltered(ctx context.Context, useCache bool, table string) (Parsed, bool, error) {
 	table = remapTable(table)
 	cmd := routesQuery("table '" + table + "' all filtered")
+	cmd = queryCommand("RoutesTableFiltered", QueryVars{Table: table}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesTableFiltered", table),
 		cmd,
@@ -483,10 +783,12 @@ func RoutesTableFiltered(useCache bool, table string) (Parsed, bool) {
 		nil)
 }
 
-func RoutesTableCount(useCache bool, table string) (Parsed, bool) {
+func RoutesTableCount(ctx context.Context, useCache bool, table string) (Parsed, bool, error) {
 	table = remapTable(table)
 	cmd := routesQuery("table '" + table + "' count")
+	cmd = queryCommand("RoutesTableCount", QueryVars{Table: table}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesTableCount", table),
 		cmd,
@@ -495,10 +797,12 @@ func RoutesTableCount(useCache bool, table string) (Parsed, bool) {
 	)
 }
 
-func RoutesLookupTable(useCache bool, net string, table string) (Parsed, bool) {
+func RoutesLookupTable(ctx context.Context, useCache bool, net string, table string) (Parsed, bool, error) {
 	table = remapTable(table)
 	cmd := routesQuery("for " + net + " table '" + table + "' all")
+	cmd = queryCommand("RoutesLookupTable", QueryVars{Prefix: net, Table: table}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesLookupTable", net, table),
 		cmd,
@@ -506,9 +810,11 @@ func RoutesLookupTable(useCache bool, net string, table string) (Parsed, bool) {
 		nil)
 }
 
-func RoutesLookupProtocol(useCache bool, net string, protocol string) (Parsed, bool) {
-	cmd := routesQuery("for " + net + " protocol '" + protocol + "' all")
+func RoutesLookupProtocol(ctx context.Context, useCache bool, net string, protocol string) (Parsed, bool, error) {
+	cmd := routesQuery(defaultTable("for " + net + " protocol '" + protocol + "' all"))
+	cmd = queryCommand("RoutesLookupProtocol", QueryVars{Prefix: net, Protocol: protocol}, cmd)
 	return RunAndParse(
+		ctx,
 		useCache,
 		GetCacheKey("RoutesLookupProtocol", net, protocol),
 		cmd,
@@ -516,37 +822,22 @@ func RoutesLookupProtocol(useCache bool, net string, protocol string) (Parsed, b
 		nil)
 }
 
-func getBirdVersion() int {
-	// We assume the bird major version does not change during
-	// the time the birdwatcher is running.
-	//
-	// However, this requires now a restart when going
-	// from bird1 to bird2.
-	if BirdVersion != 0 {
-		return BirdVersion
-	}
-
-	// This method is a bit hacky.
-	status, _ := Status(false) // Get status without cache
-	if IsSpecial(status) {
-		return 0
-	}
-
-	birdStatus, ok := status["status"].(Parsed)
-	if !ok {
-		return 0
-	}
-
-	version, ok := birdStatus["version"].(string)
-	if !ok {
-		return 0
+// CacheExpiry returns the time when the
+// cached result will be refreshed.
+func CacheExpiry(res Parsed) (time.Time, bool) {
+	ttl, err := parseCacheTTL(res["ttl"])
+	if err != nil || ttl.IsZero() {
+		return time.Time{}, false
 	}
+	return ttl, true
+}
 
-	v, err := strconv.Atoi(string(version[0]))
-	if err != nil {
-		return 0
+// CachedAt returns the time when the
+// result was read from BIRD.
+func CachedAt(res Parsed) (time.Time, bool) {
+	cachedAt, err := parseCacheTTL(res["cached_at"])
+	if err != nil || cachedAt.IsZero() {
+		return time.Time{}, false
 	}
-
-	BirdVersion = v
-	return v
+	return cachedAt, true
 }
@@ -0,0 +1,53 @@
+package bird
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alice-lg/birdwatcher/tracing"
+)
+
+func TestRunAndParseTrace(t *testing.T) {
+	defer withFixtures(t, map[string]string{
+		"protocols_short": readSample(t, "protocols_short.sample"),
+	})()
+
+	recorded := tracing.Record()
+	handler := tracing.StartSpan("handler")
+	ctx := tracing.ContextWithSpan(context.Background(), handler)
+	if _, _, err := RunAndParse(ctx, true, "protocols_short", "protocols_short", parseProtocolsShort, nil); err != nil {
+		t.Fatal(err)
+	}
+	handler.End()
+	spans := recorded()
+
+	ids := map[string]string{} // name -> span id
+	for _, span := range spans {
+		ids[span.Name()] = span.SpanID()
+	}
+	for _, span := range spans {
+		if span.TraceID() != handler.TraceID() {
+			t.Error("Expected", span.Name(), "in the trace of the handler")
+		}
+	}
+	for name, parent := range map[string]string{
+		"birdc protocols_short": "handler",
+		"cache":                 "birdc protocols_short",
+		"rate_limit":            "birdc protocols_short",
+		"exec":                  "birdc protocols_short",
+		"parse":                 "birdc protocols_short",
+	} {
+		found := false
+		for _, span := range spans {
+			if span.Name() == name {
+				found = true
+				if span.ParentID() != ids[parent] {
+					t.Error("Expected", name, "to be a child of", parent)
+				}
+			}
+		}
+		if !found {
+			t.Error("Expected a span:", name)
+		}
+	}
+}
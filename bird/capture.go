@@ -0,0 +1,101 @@
+package bird
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// CaptureDir is the spool directory for raw birdc outputs.
+// Capturing is disabled if empty.
+var CaptureDir string
+
+var captureSeq uint64
+
+// A CaptureHeader is the first line of a capture file,
+// the raw birdc output follows.
+type CaptureHeader struct {
+	Command   string    `json:"command"`
+	Timestamp time.Time `json:"timestamp"`
+	Size      int       `json:"size"`
+}
+
+// Write the raw output of a command to the spool directory
+func capture(cmd string, out []byte) {
+	now := time.Now().UTC()
+	header, err := json.Marshal(CaptureHeader{
+		Command:   cmd,
+		Timestamp: now,
+		Size:      len(out),
+	})
+	if err != nil {
+		log.Println("Capturing birdc output failed:", err)
+		return
+	}
+
+	name := fmt.Sprintf("%s-%06d-%s.capture",
+		now.Format("20060102T150405.000"),
+		atomic.AddUint64(&captureSeq, 1)%1000000,
+		fixtureKeys(cmd)[0])
+	if len(name) > 200 {
+		name = name[:192] + ".capture"
+	}
+
+	data := append(append(header, '\n'), out...)
+	if err := ioutil.WriteFile(filepath.Join(CaptureDir, name), data, 0644); err != nil {
+		log.Println("Capturing birdc output failed:", err)
+	}
+}
+
+// ReadCapture reads the header and output of a capture
+func ReadCapture(filename string) (*CaptureHeader, []byte, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, nil, err
+	}
+	header := &CaptureHeader{}
+	if err := json.Unmarshal(line, header); err != nil {
+		return nil, nil, err
+	}
+
+	out, err := ioutil.ReadAll(io.LimitReader(reader, int64(header.Size)))
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, out, nil
+}
+
+// ParserTypeForCommand determines the parser
+// used for the output of a birdc command.
+func ParserTypeForCommand(cmd string) string {
+	switch {
+	case cmd == "status":
+		return "status"
+	case cmd == "protocols all":
+		return "protocols"
+	case cmd == "protocols":
+		return "protocols_short"
+	case cmd == "symbols":
+		return "symbols"
+	case strings.HasPrefix(cmd, "route") && strings.Contains(cmd, " count"):
+		return "routes_count"
+	case strings.HasPrefix(cmd, "route"):
+		return "routes"
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package bird
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCaptureAndRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "birdwatcher-capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	CaptureDir = dir
+	defer func() { CaptureDir = "" }()
+
+	capture("route all protocol 'R1'", []byte("output\n"))
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.capture"))
+	if len(files) != 1 {
+		t.Fatal("Expected one capture, got:", files)
+	}
+
+	header, out, err := ReadCapture(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Command != "route all protocol 'R1'" || string(out) != "output\n" {
+		t.Error("Unexpected capture:", header, string(out))
+	}
+	if ParserTypeForCommand(header.Command) != "routes" {
+		t.Error("Expected routes parser")
+	}
+}
+
+func TestCaptureRawOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "birdwatcher-capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A birdc printing a latin1 description
+	birdc := filepath.Join(dir, "birdc")
+	script := "#!/bin/sh\nprintf 'Description: caf\\351\\n'\n"
+	if err := ioutil.WriteFile(birdc, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(conf BirdConfig) { ClientConf = conf }(ClientConf)
+	ClientConf.BirdCmd = birdc
+	ClientConf.OutputCharset = CharsetLatin1
+	CaptureDir = dir
+	defer func() { CaptureDir = "" }()
+
+	reader, err := Run("protocols all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := ioutil.ReadAll(reader)
+	if string(out) != "Description: café\n" {
+		t.Error("Expected the normalized output, got:", string(out))
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.capture"))
+	if len(files) != 1 {
+		t.Fatal("Expected one capture, got:", files)
+	}
+	header, raw, err := ReadCapture(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Command != "protocols all" || string(raw) != "Description: caf\xe9\n" {
+		t.Errorf("Expected the raw output to be captured, got: %v %q", header, raw)
+	}
+	if string(NormalizeOutput(raw)) != string(out) {
+		t.Error("Expected the replayed output to match the normalized output")
+	}
+}
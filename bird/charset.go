@@ -0,0 +1,44 @@
+package bird
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// Handling of bytes in the birdc output which are not
+// valid UTF-8, e.g. descriptions in a legacy encoding.
+const (
+	CharsetLatin1  = "latin1"  // Transcode from ISO-8859-1
+	CharsetReplace = "replace" // Replace with U+FFFD
+)
+
+// NormalizeOutput makes the birdc output valid UTF-8
+// with the configured output charset.
+func NormalizeOutput(out []byte) []byte {
+	return normalizeOutput(out, ClientConf.OutputCharset)
+}
+
+// normalizeOutput makes the birdc output valid UTF-8.
+// Valid UTF-8 sequences are kept as they are.
+func normalizeOutput(out []byte, charset string) []byte {
+	if utf8.Valid(out) {
+		return out
+	}
+
+	normalized := bytes.NewBuffer(make([]byte, 0, len(out)+len(out)/8))
+	for len(out) > 0 {
+		r, size := utf8.DecodeRune(out)
+		if r == utf8.RuneError && size <= 1 {
+			if charset == CharsetReplace {
+				normalized.WriteRune(utf8.RuneError)
+			} else {
+				normalized.WriteRune(rune(out[0]))
+			}
+			out = out[1:]
+			continue
+		}
+		normalized.Write(out[:size])
+		out = out[size:]
+	}
+	return normalized.Bytes()
+}
@@ -0,0 +1,20 @@
+package bird
+
+import (
+	"testing"
+)
+
+func TestNormalizeOutput(t *testing.T) {
+	utf8 := []byte("description: Müller GmbH\n")
+	if out := normalizeOutput(utf8, ""); string(out) != string(utf8) {
+		t.Error("Expected valid UTF-8 to be kept, got:", string(out))
+	}
+
+	latin1 := []byte("description: M\xfcller GmbH\n")
+	if out := normalizeOutput(latin1, CharsetLatin1); string(out) != "description: Müller GmbH\n" {
+		t.Error("Expected Latin-1 to be transcoded, got:", string(out))
+	}
+	if out := normalizeOutput(latin1, CharsetReplace); string(out) != "description: M�ller GmbH\n" {
+		t.Error("Expected the invalid byte to be replaced, got:", string(out))
+	}
+}
@@ -15,6 +15,20 @@ type BirdConfig struct {
 	BirdCmd        string `toml:"birdc"`
 	CacheTtl       int    `toml:"ttl"`
 	Dualstack      bool   `toml:"dualstack"`
+	Timeout        int    `toml:"timeout"`    // in seconds
+	SlowQuery      int    `toml:"slow_query"` // in milliseconds
+
+	// Read canned birdc outputs from this directory
+	// instead of running birdc.
+	Fixtures string `toml:"fixtures"`
+
+	// Handling of birdc output which is not valid UTF-8:
+	// "latin1" (default) or "replace"
+	OutputCharset string `toml:"output_charset"`
+
+	// Table of the route queries, which do not select a
+	// table, and replacing "master" (e.g. "master4")
+	DefaultTable string `toml:"default_table"`
 }
 
 type ParserConfig struct {
@@ -25,6 +39,19 @@ type RateLimitConfig struct {
 	Reqs    int
 	Max     int `toml:"requests_per_minute"`
 	Enabled bool
+
+	// Requests over the limit wait for up to queue_delay
+	// milliseconds, if less than queue_size are waiting.
+	QueueSize  int `toml:"queue_size"`
+	QueueDelay int `toml:"queue_delay"`
+}
+
+// Load shedding near the memory budget
+type MemoryConfig struct {
+	// Approximate memory in use in MB, then dumps are rejected
+	SoftLimit int `toml:"soft_limit"`
+	// Seconds in the Retry-After header of rejected requests
+	RetryAfter int `toml:"retry_after"`
 }
 
 type CacheConfig struct {
@@ -34,4 +61,12 @@ type CacheConfig struct {
 	RedisDb       int    `toml:"redis_db"`
 
 	MaxKeys int `toml:"max_keys"`
+
+	// Flush the cache when BIRD was reconfigured
+	InvalidateOnReconfig bool `toml:"invalidate_on_reconfig"`
+	ReconfigInterval     int  `toml:"reconfig_interval"` // in seconds
+
+	// Queries run again after the cache was flushed
+	Rewarm            []string `toml:"rewarm"`
+	RewarmConcurrency int      `toml:"rewarm_concurrency"`
 }
@@ -0,0 +1,89 @@
+package bird
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+)
+
+// Messages of BIRD for a successful (dry run) configure
+var configureSuccess = []string{
+	"Reconfigured",
+	"Reconfiguration in progress",
+	"Reconfiguration already in progress, queueing new config",
+	"Configuration OK",
+}
+
+// parseConfigure collects the output of birdc configure
+// without the greeting and checks for success.
+func parseConfigure(reader io.Reader) Parsed {
+	output := []string{}
+	success := false
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || (strings.HasPrefix(line, "BIRD ") && strings.HasSuffix(line, " ready.")) {
+			continue
+		}
+		output = append(output, line)
+		for _, message := range configureSuccess {
+			if line == message {
+				success = true
+			}
+		}
+	}
+
+	return Parsed{
+		"success": success,
+		"output":  output,
+	}
+}
+
+// Configure reloads the BIRD configuration. With check,
+// the configuration is only validated ("configure check").
+// This requires birdc without the restricted mode.
+func Configure(ctx context.Context, check bool) (Parsed, error) {
+	args := "configure"
+	if check {
+		args = "configure check"
+	}
+
+	logQuery(ctx, "running birdc", args)
+
+	var (
+		out io.Reader
+		err error
+	)
+	if ClientConf.Fixtures != "" {
+		out, err = runFixture(args)
+	} else {
+		var buf []byte
+		buf, err = runBirdc(strings.Split(args, " "))
+		out = bytes.NewReader(buf)
+	}
+	if err != nil {
+		recordError(ctx, args, err)
+		if err == context.DeadlineExceeded {
+			return nil, ErrTimeout
+		}
+		return nil, ErrUnreachable
+	}
+
+	res, err := parse(args, parseConfigure, out)
+	if err != nil {
+		return nil, ErrParse
+	}
+	res["command"] = args
+
+	if !check && res["success"] == true {
+		InvalidateBirdVersion()
+		count := FlushCache()
+		logQuery(ctx, "reconfigured BIRD, flushed", count, "cached results")
+		go rewarmAfterFlush()
+	}
+
+	return res, nil
+}
@@ -0,0 +1,28 @@
+package bird
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConfigure(t *testing.T) {
+	tests := []struct {
+		out     string
+		success bool
+		lines   int
+	}{
+		{"BIRD 2.0.7 ready.\nReading configuration from /etc/bird.conf\nReconfigured\n", true, 2},
+		{"BIRD 2.0.7 ready.\nReading configuration from /etc/bird.conf\nConfiguration OK\n", true, 2},
+		{"BIRD 2.0.7 ready.\nReading configuration from /etc/bird.conf\n/etc/bird.conf:12:3 syntax error, unexpected '}'\n", false, 2},
+	}
+
+	for _, test := range tests {
+		res := parseConfigure(strings.NewReader(test.out))
+		if res["success"] != test.success {
+			t.Error("Expected success", test.success, "for:", test.out)
+		}
+		if output := res["output"].([]string); len(output) != test.lines {
+			t.Error("Unexpected output:", output)
+		}
+	}
+}
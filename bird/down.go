@@ -0,0 +1,141 @@
+package bird
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time formats of the "since" column, depending on the
+// timeformat configured in BIRD.
+var sinceLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"15:04:05",
+	"02-01-2006",
+}
+
+// parseSince parses the state change timestamp of a
+// protocol in the local time of BIRD. Times without a
+// date are from within the last day.
+func parseSince(since string, now time.Time) (time.Time, bool) {
+	since = strings.TrimSpace(since)
+	for _, layout := range sinceLayouts {
+		if len(since) < len(layout) {
+			continue
+		}
+		t, err := time.ParseInLocation(layout, since[:len(layout)], now.Location())
+		if err != nil {
+			continue
+		}
+		if layout == "15:04:05" {
+			t = time.Date(now.Year(), now.Month(), now.Day(),
+				t.Hour(), t.Minute(), t.Second(), 0, now.Location())
+			if t.After(now) {
+				t = t.AddDate(0, 0, -1)
+			}
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// ParseTimestamp parses a timestamp printed by BIRD in any
+// of its timeformats, e.g. "08:17:33" of iso short.
+func ParseTimestamp(value string) (time.Time, bool) {
+	return parseSince(value, time.Now())
+}
+
+// ParseMinDuration parses a duration like "24h" or "7d"
+func ParseMinDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("Invalid duration: %s", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Invalid duration: %s", value)
+	}
+	return d, nil
+}
+
+// downSessions lists the BGP sessions which are not up for
+// at least the duration, the longest down first. Sessions
+// with an unknown state change are only listed without
+// a minimum duration.
+func downSessions(protocols Parsed, minDuration time.Duration, now time.Time) []Parsed {
+	sessions := []Parsed{}
+	for name, p := range protocols {
+		protocol, ok := p.(Parsed)
+		if !ok || protocol["bird_protocol"] != "BGP" || protocol["state"] == "up" {
+			continue
+		}
+
+		session := Parsed{
+			"protocol":         name,
+			"state":            protocol["state"],
+			"neighbor_address": protocol["neighbor_address"],
+			"neighbor_as":      protocol["neighbor_as"],
+			"description":      protocol["description"],
+			"last_error":       protocol["last_error"],
+			"state_changed":    protocol["state_changed"],
+			"down_for":         nil,
+		}
+
+		changed, _ := protocol["state_changed"].(string)
+		since, ok := parseSince(changed, now)
+		if !ok && minDuration > 0 {
+			continue
+		}
+		if ok {
+			downFor := now.Sub(since)
+			if downFor < minDuration {
+				continue
+			}
+			session["down_for"] = int64(downFor.Seconds())
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		di, _ := sessions[i]["down_for"].(int64)
+		dj, _ := sessions[j]["down_for"].(int64)
+		if di != dj {
+			return di > dj
+		}
+		return sessions[i]["protocol"].(string) < sessions[j]["protocol"].(string)
+	})
+	return sessions
+}
+
+// ProtocolsDown lists the BGP sessions down for at
+// least the minimum duration.
+func ProtocolsDown(ctx context.Context, useCache bool, minDuration time.Duration) (Parsed, bool, error) {
+	res, fromCache, err := Protocols(ctx, useCache)
+	if err != nil {
+		return nil, false, err
+	}
+	protocols, _ := res["protocols"].(Parsed)
+
+	down := Parsed{
+		"protocols":    downSessions(protocols, minDuration, time.Now()),
+		"min_duration": int64(minDuration.Seconds()),
+	}
+	// Keep the cache status of the protocols
+	for _, key := range []string{"ttl", "cached_at"} {
+		if v, ok := res[key]; ok {
+			down[key] = v
+		}
+	}
+	return down, fromCache, nil
+}
@@ -0,0 +1,53 @@
+package bird
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2021, 3, 29, 10, 0, 0, 0, time.UTC)
+	for since, expected := range map[string]time.Time{
+		"2021-03-28 08:00:00":     time.Date(2021, 3, 28, 8, 0, 0, 0, time.UTC),
+		"2021-03-28 08:00:00.123": time.Date(2021, 3, 28, 8, 0, 0, 0, time.UTC),
+		"2021-03-20":              time.Date(2021, 3, 20, 0, 0, 0, 0, time.UTC),
+		"09:30:00":                time.Date(2021, 3, 29, 9, 30, 0, 0, time.UTC),
+		"11:30:00.500":            time.Date(2021, 3, 28, 11, 30, 0, 0, time.UTC),
+	} {
+		parsed, ok := parseSince(since, now)
+		if !ok || !parsed.Equal(expected) {
+			t.Error("Expected", expected, "for", since, "got:", parsed)
+		}
+	}
+	if _, ok := parseSince("never", now); ok {
+		t.Error("Expected an invalid timestamp")
+	}
+}
+
+func TestDownSessions(t *testing.T) {
+	now := time.Date(2021, 3, 29, 10, 0, 0, 0, time.UTC)
+	protocols := Parsed{
+		"R1": Parsed{"bird_protocol": "BGP", "state": "up", "state_changed": "2021-03-01"},
+		"R2": Parsed{"bird_protocol": "BGP", "state": "start", "state_changed": "2021-03-01"},
+		"R3": Parsed{"bird_protocol": "BGP", "state": "down", "state_changed": "2021-03-29 08:00:00"},
+		"R4": Parsed{"bird_protocol": "BGP", "state": "down", "state_changed": "?"},
+		"K1": Parsed{"bird_protocol": "Kernel", "state": "down", "state_changed": "2021-03-01"},
+	}
+
+	sessions := downSessions(protocols, 24*time.Hour, now)
+	if len(sessions) != 1 || sessions[0]["protocol"] != "R2" || sessions[0]["down_for"] != int64(28*24*3600+10*3600) {
+		t.Error("Expected R2 down for 28 days, got:", sessions)
+	}
+
+	sessions = downSessions(protocols, 0, now)
+	if len(sessions) != 3 || sessions[1]["protocol"] != "R3" || sessions[2]["down_for"] != nil {
+		t.Error("Expected R2, R3 and R4, got:", sessions)
+	}
+
+	if d, err := ParseMinDuration("7d"); err != nil || d != 7*24*time.Hour {
+		t.Error("Expected 7 days, got:", d, err)
+	}
+	if _, err := ParseMinDuration("-1h"); err == nil {
+		t.Error("Expected an error for a negative duration")
+	}
+}
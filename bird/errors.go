@@ -0,0 +1,106 @@
+package bird
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A QueryError is the reason a query returned no result.
+// It is returned alongside the (empty) result of the query.
+type QueryError struct {
+	Code      string        `json:"code"`
+	Message   string        `json:"message"`
+	Retryable bool          `json:"retryable"`
+	Fields    []*FieldError `json:"fields,omitempty"`
+}
+
+func (e *QueryError) Error() string {
+	return e.Message
+}
+
+var (
+	ErrUnreachable = &QueryError{Code: "bird_unreachable", Message: "bird unreachable", Retryable: true}
+	ErrTimeout     = &QueryError{Code: "bird_timeout", Message: "bird query timed out", Retryable: true}
+	ErrRateLimited = &QueryError{Code: "rate_limited", Message: "rate limit exceeded", Retryable: true}
+	ErrParse       = &QueryError{Code: "parse_error", Message: "could not parse bird output"}
+	ErrInvalid     = &QueryError{Code: "invalid_request", Message: "invalid request parameters"}
+	ErrOverloaded  = &QueryError{Code: "overloaded", Message: "memory limit reached", Retryable: true}
+	ErrUnsupported = &QueryError{Code: "unsupported", Message: "query not supported by this bird"}
+)
+
+// Unsupported creates the error of a query, which the
+// running BIRD does not support.
+func Unsupported(message string) *QueryError {
+	err := *ErrUnsupported
+	err.Message = message
+	return &err
+}
+
+// IsQueryError checks if err is a query error of the same kind
+// as target, e.g. an invalid request with any fields.
+func IsQueryError(err error, target *QueryError) bool {
+	queryErr, ok := err.(*QueryError)
+	return ok && queryErr.Code == target.Code
+}
+
+// A FieldError is an invalid request parameter
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// InvalidRequest creates the error of a request with
+// invalid parameters, which is rejected before querying bird.
+func InvalidRequest(fields ...*FieldError) *QueryError {
+	err := *ErrInvalid
+	if len(fields) > 0 {
+		err.Message = fields[0].Error()
+	}
+	err.Fields = fields
+	return &err
+}
+
+// A CommandError is a failed birdc invocation
+type CommandError struct {
+	Timestamp time.Time `json:"timestamp"`
+	Command   string    `json:"command"`
+	Error     string    `json:"error"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+const maxRecentErrors = 100
+
+var recentErrors struct {
+	sync.Mutex
+	errors []CommandError
+}
+
+func recordError(ctx context.Context, cmd string, err error) {
+	logQuery(ctx, "birdc", cmd, "failed:", err)
+
+	recentErrors.Lock()
+	defer recentErrors.Unlock()
+
+	recentErrors.errors = append(recentErrors.errors, CommandError{
+		Timestamp: time.Now().UTC(),
+		Command:   cmd,
+		Error:     err.Error(),
+		RequestID: RequestID(ctx),
+	})
+	if len(recentErrors.errors) > maxRecentErrors {
+		recentErrors.errors = recentErrors.errors[1:]
+	}
+}
+
+// RecentErrors returns the last failed birdc invocations
+func RecentErrors() []CommandError {
+	recentErrors.Lock()
+	defer recentErrors.Unlock()
+
+	return append([]CommandError{}, recentErrors.errors...)
+}
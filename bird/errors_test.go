@@ -0,0 +1,102 @@
+package bird
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunAndParseErrors(t *testing.T) {
+	defer withFixtures(t, map[string]string{
+		"protocols_short": readSample(t, "protocols_short.sample"),
+	})()
+	defer func() { RateLimitConf.Conf = RateLimitConfig{} }()
+
+	// Missing fixtures behave like an unreachable bird
+	logged := &bytes.Buffer{}
+	log.SetOutput(logged)
+	ctx := WithRequestID(context.Background(), "frontend-1234")
+	res, _, err := RunAndParse(ctx, false, "interfaces", "interfaces", parseStatus, nil)
+	log.SetOutput(os.Stderr)
+	if err != ErrUnreachable {
+		t.Error("Expected ErrUnreachable, got:", err)
+	}
+	if res != nil {
+		t.Error("Expected no result, got:", res)
+	}
+	for _, line := range []string{
+		"Request frontend-1234 running birdc interfaces",
+		"Request frontend-1234 birdc interfaces failed:",
+	} {
+		if !strings.Contains(logged.String(), line) {
+			t.Error("Expected the log line:", line)
+		}
+	}
+	errors := RecentErrors()
+	if last := errors[len(errors)-1]; last.Command != "interfaces" || last.RequestID != "frontend-1234" {
+		t.Error("Expected the failed command with the request ID, got:", last)
+	}
+
+	panics := func(io.Reader) Parsed {
+		var p Parsed
+		return p["status"].(Parsed)
+	}
+	_, _, err = RunAndParse(context.Background(), false, "protocols_short", "protocols_short", panics, nil)
+	if err != ErrParse {
+		t.Error("Expected ErrParse, got:", err)
+	}
+
+	RateLimitConf.Conf = RateLimitConfig{Enabled: true, Reqs: 0}
+	_, _, err = RunAndParse(context.Background(), false, "protocols_short", "protocols_short", parseProtocolsShort, nil)
+	if err != ErrRateLimited {
+		t.Error("Expected ErrRateLimited, got:", err)
+	}
+
+	RateLimitConf.Conf = RateLimitConfig{}
+	_, _, err = RunAndParse(context.Background(), false, "protocols_short", "protocols_short", parseProtocolsShort, nil)
+	if err != nil {
+		t.Error("Expected no error, got:", err)
+	}
+}
+
+func TestInvalidRequest(t *testing.T) {
+	err := InvalidRequest(&FieldError{Field: "prefix", Message: "Invalid address or prefix"})
+	if !IsQueryError(err, ErrInvalid) || IsQueryError(err, ErrParse) {
+		t.Error("Expected an invalid request, got:", err)
+	}
+	if err.Message != "prefix: Invalid address or prefix" || len(err.Fields) != 1 {
+		t.Error("Unexpected error:", err.Message, err.Fields)
+	}
+	if ErrInvalid.Fields != nil {
+		t.Error("Expected ErrInvalid to be unchanged")
+	}
+}
+
+func TestRateLimitQueue(t *testing.T) {
+	defer func() { RateLimitConf.Conf = RateLimitConfig{} }()
+
+	RateLimitConf.Conf = RateLimitConfig{Enabled: true, Max: 1, QueueSize: 1, QueueDelay: 1000}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		resetRateLimit()
+	}()
+	if !checkRateLimit() {
+		t.Error("Expected the queued request to be allowed after the reset")
+	}
+
+	RateLimitConf.Conf = RateLimitConfig{Enabled: true, QueueSize: 1, QueueDelay: 20}
+	if checkRateLimit() {
+		t.Error("Expected the request to be rejected after the queue delay")
+	}
+
+	RateLimitConf.Conf = RateLimitConfig{Enabled: true, QueueSize: 0, QueueDelay: 1000}
+	start := time.Now()
+	if checkRateLimit() || time.Since(start) > 100*time.Millisecond {
+		t.Error("Expected the request to be rejected without a queue")
+	}
+}
@@ -0,0 +1,51 @@
+package bird
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var fixtureKeyRx = regexp.MustCompile(`[^A-Za-z0-9\.\-]+`)
+
+// fixtureKeys derives the fixture filenames for a command,
+// from the most to the least specific one:
+//
+//	route all protocol 'R1' -> route_all_protocol_R1,
+//	                           route_all_protocol,
+//	                           route_all, route
+func fixtureKeys(args string) []string {
+	words := strings.Fields(args)
+	keys := []string{}
+	for i := len(words); i > 0; i-- {
+		key := fixtureKeyRx.ReplaceAllString(strings.Join(words[:i], "_"), "_")
+		key = strings.Trim(key, "_")
+		if key == "" || (len(keys) > 0 && keys[len(keys)-1] == key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// runFixture reads the canned output for a command
+// from the fixtures directory instead of running birdc.
+func runFixture(args string) (io.Reader, error) {
+	for _, key := range fixtureKeys(args) {
+		filename := filepath.Join(ClientConf.Fixtures, key+".sample")
+		out, err := ioutil.ReadFile(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(NormalizeOutput(out)), nil
+	}
+	return nil, fmt.Errorf("no fixture for command: %s", args)
+}
@@ -0,0 +1,38 @@
+package bird
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// GenerateRoutesOutput writes a representative "show route all"
+// output of BIRD 2 with n routes, for benchmarking the parser.
+// Every fourth prefix has an additional non primary route.
+func GenerateRoutesOutput(w io.Writer, n int) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "BIRD 2.0.7 ready.")
+	fmt.Fprintln(out, "Table master4:")
+
+	for i := 0; i < n; i++ {
+		asn := 64512 + i%1000
+		gw := fmt.Sprintf("172.31.%d.%d", (i/250)%250, i%250+1)
+		secondary := i%4 == 1
+		if secondary {
+			fmt.Fprintf(out, "                     unicast [R%d 2021-03-30 02:28:19] (100) [AS%di]\n", asn, asn)
+		} else {
+			fmt.Fprintf(out, "%d.%d.%d.0/24          unicast [R%d 2021-03-30 02:28:19] * (100) [AS%di]\n",
+				1+(i>>16)%223, (i>>8)%256, i%256, asn, asn)
+		}
+		fmt.Fprintf(out, "\tvia %s on eth0\n", gw)
+		fmt.Fprintln(out, "\tType: BGP univ")
+		fmt.Fprintln(out, "\tBGP.origin: IGP")
+		fmt.Fprintf(out, "\tBGP.as_path: %d 3356 %d\n", asn, 1000+i%5000)
+		fmt.Fprintf(out, "\tBGP.next_hop: %s\n", gw)
+		fmt.Fprintln(out, "\tBGP.local_pref: 100")
+		fmt.Fprintf(out, "\tBGP.community: (%d,100) (%d,200) (65000,%d)\n", asn, asn, i%1000)
+		fmt.Fprintf(out, "\tBGP.large_community: (9033, 65666, %d) (9033, 1, %d)\n", i%20, asn)
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,83 @@
+package bird
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// withFixtures answers the queries of a test with the fixtures,
+// which map the fixture names to the birdc output, and caches
+// the results in a new memory cache. The BIRD version is set to 1.
+// The returned function restores the config, the cache and the
+// BIRD version.
+func withFixtures(t *testing.T, fixtures map[string]string) func() {
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, output := range fixtures {
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".sample"), []byte(output), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf, previousCache, version := ClientConf, cache, BirdVersion
+	memoryCache := NewMemoryCache(100)
+	memoryCache.evicted = cacheEvicted
+	cache = memoryCache
+	ClientConf.Fixtures = dir
+	ClientConf.CacheTtl = 5
+	BirdVersion = 1
+
+	return func() {
+		memoryCache.Flush()
+		ClientConf, cache, BirdVersion = conf, previousCache, version
+		os.RemoveAll(dir)
+	}
+}
+
+// readSample returns the content of a sample in the test directory
+func readSample(t *testing.T, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join("../test", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFixtureKeys(t *testing.T) {
+	keys := fixtureKeys("route all protocol 'R1' where net.type = NET_IP4")
+	expected := []string{
+		"route_all_protocol_R1_where_net.type_NET_IP4",
+		"route_all_protocol_R1_where_net.type",
+		"route_all_protocol_R1_where",
+		"route_all_protocol_R1",
+		"route_all_protocol",
+		"route_all",
+		"route",
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Error("Unexpected keys:", keys)
+	}
+}
+
+func TestRunFixture(t *testing.T) {
+	ClientConf.Fixtures = "../test"
+	defer func() { ClientConf.Fixtures = "" }()
+
+	out, err := Run("protocols_short")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(out)
+	if len(data) == 0 {
+		t.Error("Expected fixture content")
+	}
+
+	if _, err := Run("interfaces"); err == nil {
+		t.Error("Expected an error for a missing fixture")
+	}
+}
@@ -0,0 +1,73 @@
+package bird
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A ProtocolsHook is called with the result of every
+// fresh (not cached) "show protocols all" query.
+type ProtocolsHook func(protocols Parsed)
+
+var protocolsHooks struct {
+	sync.RWMutex
+	hooks []ProtocolsHook
+}
+
+// RegisterProtocolsHook adds a hook which is invoked
+// each time the protocols are refreshed from BIRD.
+func RegisterProtocolsHook(hook ProtocolsHook) {
+	protocolsHooks.Lock()
+	protocolsHooks.hooks = append(protocolsHooks.hooks, hook)
+	protocolsHooks.Unlock()
+}
+
+func runProtocolsHooks(protocols Parsed) {
+	protocolsHooks.RLock()
+	defer protocolsHooks.RUnlock()
+
+	for _, hook := range protocolsHooks.hooks {
+		hook(protocols)
+	}
+}
+
+var protocolsPoller struct {
+	sync.Mutex
+	interval time.Duration
+	started  bool
+}
+
+// PollProtocols refreshes the protocols from BIRD at least
+// every interval, which runs the protocols hooks. All modules
+// share one poller running at the shortest interval.
+func PollProtocols(interval time.Duration) {
+	protocolsPoller.Lock()
+	defer protocolsPoller.Unlock()
+
+	if protocolsPoller.interval == 0 || interval < protocolsPoller.interval {
+		protocolsPoller.interval = interval
+	}
+	if protocolsPoller.started {
+		return
+	}
+	protocolsPoller.started = true
+
+	go func() {
+		for {
+			protocolsPoller.Lock()
+			interval := protocolsPoller.interval
+			protocolsPoller.Unlock()
+
+			time.Sleep(interval)
+			refreshProtocols()
+		}
+	}()
+}
+
+// refreshProtocols queries the protocols bypassing the cache.
+// The polls are not rate limited, so they do not take the
+// tokens of API requests.
+func refreshProtocols() {
+	Protocols(withoutRateLimit(context.Background()), false)
+}
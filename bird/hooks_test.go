@@ -0,0 +1,26 @@
+package bird
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRefreshProtocols(t *testing.T) {
+	defer withFixtures(t, map[string]string{
+		"protocols_all": readSample(t, "protocols_bgp_pipe.sample"),
+	})()
+
+	runs := 0
+	RegisterProtocolsHook(func(Parsed) { runs++ })
+
+	// The polls are not rate limited
+	RateLimitConf.Conf = RateLimitConfig{Enabled: true}
+	defer func() { RateLimitConf.Conf = RateLimitConfig{} }()
+
+	Protocols(context.Background(), true)
+	refreshProtocols()
+	refreshProtocols()
+	if runs != 2 {
+		t.Error("Expected the hooks to run for each poll, not cached results, got:", runs)
+	}
+}
@@ -0,0 +1,126 @@
+package bird
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The classes BIRD prefixes the last error with
+var lastErrorClasses = []struct {
+	prefix string
+	class  string
+}{
+	{"Error: ", "error"},
+	{"Socket: ", "socket"},
+	{"Received: ", "received"},
+	{"BGP Error: ", "sent"},
+	{"Automatic shutdown: ", "automatic_shutdown"},
+}
+
+// A bgpNotification is a BGP notification code and
+// subcode with the description printed by BIRD.
+type bgpNotification struct {
+	code    int64
+	subcode int64
+	message string
+}
+
+var bgpNotifications = []bgpNotification{
+	{1, 0, "Invalid message header"},
+	{1, 1, "Connection not synchronized"},
+	{1, 2, "Bad message length"},
+	{1, 3, "Bad message type"},
+	{2, 0, "Invalid OPEN message"},
+	{2, 1, "Unsupported version number"},
+	{2, 2, "Bad peer AS"},
+	{2, 3, "Bad BGP identifier"},
+	{2, 4, "Unsupported optional parameter"},
+	{2, 5, "Authentication failure"},
+	{2, 6, "Unacceptable hold time"},
+	{2, 7, "Required capability missing"},
+	{2, 8, "No supported AFI/SAFI"},
+	{3, 0, "Invalid UPDATE message"},
+	{3, 1, "Malformed attribute list"},
+	{3, 2, "Unrecognized well-known attribute"},
+	{3, 3, "Missing mandatory attribute"},
+	{3, 4, "Invalid attribute flags"},
+	{3, 5, "Invalid attribute length"},
+	{3, 6, "Invalid ORIGIN attribute"},
+	{3, 7, "AS routing loop"},
+	{3, 8, "Invalid NEXT_HOP attribute"},
+	{3, 9, "Optional attribute error"},
+	{3, 10, "Invalid network field"},
+	{3, 11, "Malformed AS_PATH"},
+	{4, 0, "Hold timer expired"},
+	{5, 0, "Finite state machine error"},
+	{5, 1, "Unexpected message in OpenSent state"},
+	{5, 2, "Unexpected message in OpenConfirm state"},
+	{5, 3, "Unexpected message in Established state"},
+	{6, 0, "Cease"},
+	{6, 1, "Maximum number of prefixes reached"},
+	{6, 2, "Administrative shutdown"},
+	{6, 3, "Peer de-configured"},
+	{6, 4, "Administrative reset"},
+	{6, 5, "Connection rejected"},
+	{6, 6, "Other configuration change"},
+	{6, 7, "Connection collision resolution"},
+	{6, 8, "Out of Resources"},
+	{7, 0, "Invalid ROUTE-REFRESH message"},
+	{7, 1, "Invalid ROUTE-REFRESH message length"},
+}
+
+var unknownNotification = regexp.MustCompile(`^Unknown error (\d+)\.(\d+)`)
+
+// lookupNotification finds the notification by the
+// description, the longest matching description wins.
+func lookupNotification(message string) (int64, int64, bool) {
+	if groups := unknownNotification.FindStringSubmatch(message); groups != nil {
+		code, _ := strconv.ParseInt(groups[1], 10, 64)
+		subcode, _ := strconv.ParseInt(groups[2], 10, 64)
+		return code, subcode, true
+	}
+
+	var match *bgpNotification
+	for i, n := range bgpNotifications {
+		if strings.HasPrefix(message, n.message) &&
+			(match == nil || len(n.message) > len(match.message)) {
+			match = &bgpNotifications[i]
+		}
+	}
+	if match == nil {
+		return 0, 0, false
+	}
+	return match.code, match.subcode, true
+}
+
+// parseLastError splits the last error of a protocol into
+// its class and message. Notifications received from or
+// sent to the neighbor include the code and subcode.
+func parseLastError(res Parsed) {
+	lastError, ok := res["last_error"].(string)
+	if !ok {
+		return
+	}
+	lastError = strings.TrimSpace(lastError)
+
+	class := ""
+	message := lastError
+	for _, c := range lastErrorClasses {
+		if strings.HasPrefix(lastError, c.prefix) {
+			class = c.class
+			message = strings.TrimPrefix(lastError, c.prefix)
+			break
+		}
+	}
+	res["last_error_class"] = class
+	res["last_error_message"] = message
+
+	if class != "received" && class != "sent" {
+		return
+	}
+	if code, subcode, ok := lookupNotification(message); ok {
+		res["notification_code"] = code
+		res["notification_subcode"] = subcode
+	}
+}
@@ -0,0 +1,127 @@
+package bird
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Match modes for route lookups
+const (
+	MatchExact    = "exact"    // show route <prefix>
+	MatchLongest  = "longest"  // show route for <prefix>
+	MatchCovering = "covering" // all routes covering <prefix>
+)
+
+// ParseMatchMode validates a match mode. An empty
+// mode is the default longest prefix match.
+func ParseMatchMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return MatchLongest, nil
+	case MatchExact, MatchLongest, MatchCovering:
+		return mode, nil
+	}
+	return "", fmt.Errorf("Invalid match mode, use one of: %s, %s, %s",
+		MatchExact, MatchLongest, MatchCovering)
+}
+
+// RoutesLookupTableMatch looks up a prefix in a table
+// using the given match mode.
+func RoutesLookupTableMatch(ctx context.Context, useCache bool, prefix string, table string, mode string) (Parsed, bool, error) {
+	switch mode {
+	case MatchExact:
+		return RoutesExactTable(ctx, useCache, prefix, table)
+	case MatchCovering:
+		return RoutesCoveringTable(ctx, useCache, prefix, table)
+	}
+	return RoutesLookupTable(ctx, useCache, prefix, table)
+}
+
+// RoutesExactTable returns only the routes for exactly the prefix
+func RoutesExactTable(ctx context.Context, useCache bool, prefix string, table string) (Parsed, bool, error) {
+	table = remapTable(table)
+	cmd := routesQuery(prefix + " table '" + table + "' all")
+	cmd = queryCommand("RoutesExactTable", QueryVars{Prefix: prefix, Table: table}, cmd)
+	return RunAndParse(
+		ctx,
+		useCache,
+		GetCacheKey("RoutesExactTable", prefix, table),
+		cmd,
+		parseRoutes,
+		nil)
+}
+
+// RoutesCoveringTable returns all routes of a table covering
+// the prefix. BIRD has no query for this, so the full table
+// is filtered.
+func RoutesCoveringTable(ctx context.Context, useCache bool, prefix string, table string) (Parsed, bool, error) {
+	lookup, err := parseLookupPrefix(prefix)
+	if err != nil {
+		return nil, false, InvalidRequest(&FieldError{Field: "prefix", Message: err.Error()})
+	}
+
+	res, fromCache, err := RoutesTable(ctx, useCache, table)
+	if err != nil {
+		return nil, false, err
+	}
+	return filterCoveringRoutes(res, lookup), fromCache, nil
+}
+
+// parseLookupPrefix accepts a prefix or a single address
+func parseLookupPrefix(prefix string) (*net.IPNet, error) {
+	if !strings.Contains(prefix, "/") {
+		ip := net.ParseIP(prefix)
+		if ip == nil {
+			return nil, fmt.Errorf("Invalid address: %s", prefix)
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid prefix: %s", prefix)
+	}
+	return network, nil
+}
+
+// covers checks if network contains the entire lookup prefix
+func covers(network, lookup *net.IPNet) bool {
+	ones, bits := network.Mask.Size()
+	lookupOnes, lookupBits := lookup.Mask.Size()
+	if bits != lookupBits || ones > lookupOnes {
+		return false
+	}
+	return network.Contains(lookup.IP)
+}
+
+// filterCoveringRoutes returns a copy of the result with
+// only the routes covering the lookup prefix.
+func filterCoveringRoutes(res Parsed, lookup *net.IPNet) Parsed {
+	routes, _ := res["routes"].([]Parsed)
+
+	filtered := []Parsed{}
+	for _, route := range routes {
+		prefix, _ := route["network"].(string)
+		_, network, err := net.ParseCIDR(prefix)
+		if err != nil {
+			continue
+		}
+		if covers(network, lookup) {
+			filtered = append(filtered, route)
+		}
+	}
+
+	result := Parsed{}
+	for k, v := range res {
+		result[k] = v
+	}
+	result["routes"] = filtered
+	return result
+}
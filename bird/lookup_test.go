@@ -0,0 +1,76 @@
+package bird
+
+import (
+	"testing"
+)
+
+func TestParseMatchMode(t *testing.T) {
+	mode, err := ParseMatchMode("")
+	if err != nil || mode != MatchLongest {
+		t.Error("Expected default longest match, got:", mode, err)
+	}
+	for _, m := range []string{MatchExact, MatchLongest, MatchCovering} {
+		if mode, err := ParseMatchMode(m); err != nil || mode != m {
+			t.Error("Expected", m, "got:", mode, err)
+		}
+	}
+	if _, err := ParseMatchMode("fuzzy"); err == nil {
+		t.Error("Expected an error for an invalid match mode")
+	}
+}
+
+func TestFilterCoveringRoutes(t *testing.T) {
+	res := Parsed{
+		"ttl": 5,
+		"routes": []Parsed{
+			{"network": "10.0.0.0/8"},
+			{"network": "10.1.0.0/16"},
+			{"network": "10.1.2.0/24"},
+			{"network": "10.1.2.128/25"},
+			{"network": "10.2.0.0/16"},
+			{"network": "2001:db8::/32"},
+		},
+	}
+
+	tests := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"10.1.2.3", []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24"}},
+		{"10.1.2.0/24", []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24"}},
+		{"10.1.0.0/15", []string{"10.0.0.0/8"}},
+		{"10.1.2.200", []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "10.1.2.128/25"}},
+		{"2001:db8::1", []string{"2001:db8::/32"}},
+		{"192.168.0.0/24", []string{}},
+	}
+
+	for _, test := range tests {
+		lookup, err := parseLookupPrefix(test.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		routes := filterCoveringRoutes(res, lookup)["routes"].([]Parsed)
+		if len(routes) != len(test.expected) {
+			t.Error(test.prefix, "expected", test.expected, "got:", routes)
+			continue
+		}
+		for i, route := range routes {
+			if route["network"] != test.expected[i] {
+				t.Error(test.prefix, "expected", test.expected[i], "got:", route["network"])
+			}
+		}
+	}
+
+	// The original result must not be modified
+	if len(res["routes"].([]Parsed)) != 6 {
+		t.Error("Expected the original routes to be unchanged")
+	}
+}
+
+func TestParseLookupPrefix(t *testing.T) {
+	for _, prefix := range []string{"10.0.0.1/33", "foo", "10.0.0/8"} {
+		if _, err := parseLookupPrefix(prefix); err == nil {
+			t.Error("Expected an error for:", prefix)
+		}
+	}
+}
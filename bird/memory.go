@@ -0,0 +1,90 @@
+package bird
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/alice-lg/birdwatcher/metrics"
+)
+
+var MemoryConf MemoryConfig
+
+// Parsed results take about this many times the
+// size of the birdc output, measured with the routes parser.
+const parsedSizeFactor = 5
+
+// Size of the birdc output of running parses
+var inFlightBytes int64
+
+var (
+	memoryUsed = metrics.NewGauge(
+		"birdwatcher_memory_used_bytes",
+		"Approximate memory of cached results and running parses",
+		"ip_version")
+	shedQueries = metrics.NewCounter(
+		"birdwatcher_shed_queries_total",
+		"Number of queries rejected near the memory limit",
+		"command", "ip_version")
+)
+
+// MemoryUsed approximates the memory in use by the cached
+// results and the results being parsed. With redis, the cached
+// results are kept by redis and only count while they are
+// decoded for a request.
+func MemoryUsed() int64 {
+	used := atomic.LoadInt64(&inFlightBytes)
+	if !CacheConf.UseRedis {
+		cachedSizes.Lock()
+		for _, size := range cachedSizes.m {
+			used += int64(size)
+		}
+		cachedSizes.Unlock()
+	}
+	return used * parsedSizeFactor
+}
+
+// trackInFlight adds the output of a running parse
+// and returns a function removing it again.
+func trackInFlight(size int) func() {
+	atomic.AddInt64(&inFlightBytes, int64(size))
+	memoryUsed.Set(float64(MemoryUsed()), IPVersion)
+	return func() {
+		atomic.AddInt64(&inFlightBytes, -int64(size))
+		memoryUsed.Set(float64(MemoryUsed()), IPVersion)
+	}
+}
+
+// isDump checks if a command lists the routes of a table or a
+// protocol, unlike counts and lookups of a single prefix.
+func isDump(cmd string) bool {
+	if CommandClass(cmd) != "route" {
+		return false
+	}
+	words := strings.Fields(cmd)
+	if len(words) < 2 {
+		return true
+	}
+	return words[1] != "for" && !strings.ContainsAny(words[1], ".:")
+}
+
+// shedLoad checks if a command has to be rejected,
+// because the memory in use reached the soft limit.
+func shedLoad(cmd string) bool {
+	if MemoryConf.SoftLimit <= 0 || !isDump(cmd) {
+		return false
+	}
+	if MemoryUsed() < int64(MemoryConf.SoftLimit)<<20 {
+		return false
+	}
+	shedQueries.Inc(CommandClass(cmd), IPVersion)
+	return true
+}
+
+// MemoryRetryAfter returns the seconds after which
+// rejected requests should be retried.
+func MemoryRetryAfter() int {
+	if MemoryConf.RetryAfter <= 0 {
+		return 10
+	}
+	return MemoryConf.RetryAfter
+}
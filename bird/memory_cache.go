@@ -14,6 +14,8 @@ type MemoryCache struct {
 	a map[string]time.Time // Access times
 
 	maxKeys int // Maximum number of keys to cache
+
+	evicted func(key, reason string) // Called for removed keys
 }
 
 // NewMemoryCache creates a new MemoryCache with a maximum number of keys.
@@ -36,13 +38,13 @@ func (c *MemoryCache) Get(key string) (Parsed, error) {
 	c.Unlock()
 
 	if !ok { // cache miss
-		return NilParse, errors.New("Failed to retrive key '" + key + "' from MemoryCache.")
+		return nil, errors.New("Failed to retrive key '" + key + "' from MemoryCache.")
 	}
 
 	// Check if the TTL is still valid
 	ttl, ok := val["ttl"].(time.Time)
 	if !ok {
-		return NilParse, errors.New("Invalid TTL value for key '" + key + "'")
+		return nil, errors.New("Invalid TTL value for key '" + key + "'")
 	}
 
 	if ttl.Before(time.Now()) {
@@ -102,6 +104,14 @@ func (c *MemoryCache) expireLRU() {
 	}
 	delete(c.m, oldestKey)
 	delete(c.a, oldestKey)
+	c.evict(oldestKey, "lru")
+}
+
+// evict reports a removed key
+func (c *MemoryCache) evict(key, reason string) {
+	if c.evicted != nil {
+		c.evicted(key, reason)
+	}
 }
 
 // Expire all keys in cache that are older than the
@@ -123,7 +133,23 @@ func (c *MemoryCache) Expire() int {
 	for _, key := range expiredKeys {
 		delete(c.m, key)
 		delete(c.a, key)
+		c.evict(key, "expired")
 	}
 
 	return len(expiredKeys)
 }
+
+// Flush removes all keys from the cache.
+func (c *MemoryCache) Flush() int {
+	c.Lock()
+	defer c.Unlock()
+
+	count := len(c.m)
+	for key := range c.m {
+		c.evict(key, "flushed")
+	}
+	c.m = make(map[string]Parsed)
+	c.a = make(map[string]time.Time)
+
+	return count
+}
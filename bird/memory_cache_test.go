@@ -32,7 +32,7 @@ func TestMemoryCacheAccess(t *testing.T) {
 func TestMemoryCacheAccessKeyMissing(t *testing.T) {
 	cache := NewMemoryCache(100)
 	parsed, err := cache.Get("test_missing_key")
-	if !IsSpecial(parsed) {
+	if parsed != nil {
 		t.Error(err)
 	}
 	t.Log("Cache error:", err)
@@ -136,3 +136,19 @@ func TestMemoryCacheNoCache(t *testing.T) {
 		t.Error("Expected error, got nil")
 	}
 }
+
+func TestMemoryCacheFlush(t *testing.T) {
+	cache := NewMemoryCache(100)
+	for _, key := range []string{"status", "protocols all"} {
+		if err := cache.Set(key, Parsed{}, 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count := cache.Flush(); count != 2 {
+		t.Error("Expected 2 flushed keys, got:", count)
+	}
+	if _, err := cache.Get("status"); err == nil {
+		t.Error("Expected the flushed key to be gone")
+	}
+}
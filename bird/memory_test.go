@@ -0,0 +1,43 @@
+package bird
+
+import (
+	"testing"
+)
+
+func TestIsDump(t *testing.T) {
+	for cmd, dump := range map[string]bool{
+		"route all protocol 'R1'":         true,
+		"route table 'T1' all":            true,
+		"route 10.0.0.0/8 all":            false,
+		"route for 2001:db8::1 all":       false,
+		"route protocol 'R1' count":       false,
+		"protocols all":                   false,
+		"route all filtered protocol 'R'": true,
+	} {
+		if isDump(cmd) != dump {
+			t.Error("Expected dump", dump, "for", cmd)
+		}
+	}
+}
+
+func TestShedLoad(t *testing.T) {
+	defer func() { MemoryConf = MemoryConfig{} }()
+	MemoryConf = MemoryConfig{SoftLimit: 1}
+
+	if shedLoad("route all protocol 'R1'") {
+		t.Error("Expected no load shedding below the limit")
+	}
+
+	release := trackInFlight(1 << 20)
+	if !shedLoad("route all protocol 'R1'") {
+		t.Error("Expected a dump to be rejected over the limit")
+	}
+	if shedLoad("route 10.0.0.0/8 all") {
+		t.Error("Expected a prefix lookup to be allowed over the limit")
+	}
+	release()
+
+	if shedLoad("route all protocol 'R1'") {
+		t.Error("Expected no load shedding after the parse finished")
+	}
+}
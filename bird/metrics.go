@@ -0,0 +1,156 @@
+package bird
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/metrics"
+)
+
+var (
+	queryDuration = metrics.NewHistogram(
+		"birdwatcher_query_duration_seconds",
+		"Duration of queries including cache lookups",
+		nil, "command", "cache", "ip_version")
+	execDuration = metrics.NewHistogram(
+		"birdwatcher_birdc_exec_duration_seconds",
+		"Duration of birdc executions",
+		nil, "command", "ip_version")
+	parseDuration = metrics.NewHistogram(
+		"birdwatcher_birdc_parse_duration_seconds",
+		"Duration of parsing birdc output",
+		nil, "command", "ip_version")
+	parserWarnings = metrics.NewCounter(
+		"birdwatcher_parser_warnings_total",
+		"Number of distinct non-fatal oddities found while parsing birdc output",
+		"command", "ip_version")
+	slowQueries = metrics.NewCounter(
+		"birdwatcher_birdc_slow_queries_total",
+		"Number of birdc executions and parses exceeding the slow query threshold",
+		"command", "stage", "ip_version")
+
+	cacheHits = metrics.NewCounter(
+		"birdwatcher_cache_hits_total",
+		"Number of queries answered from the cache",
+		"command", "ip_version")
+	cacheMisses = metrics.NewCounter(
+		"birdwatcher_cache_misses_total",
+		"Number of queries not found in the cache",
+		"command", "ip_version")
+	cacheEvictions = metrics.NewCounter(
+		"birdwatcher_cache_evictions_total",
+		"Number of removed cache entries",
+		"command", "reason", "ip_version")
+	cacheEntries = metrics.NewGauge(
+		"birdwatcher_cache_entries",
+		"Number of cached results",
+		"command", "ip_version")
+	cacheBytes = metrics.NewGauge(
+		"birdwatcher_cache_bytes",
+		"Size of the birdc output of cached results",
+		"command", "ip_version")
+)
+
+// Sizes of the birdc output of the cached commands
+var cachedSizes = struct {
+	sync.Mutex
+	m map[string]int
+}{
+	m: map[string]int{},
+}
+
+func observeQuery(class string, cacheHit bool, d time.Duration) {
+	cache := "miss"
+	if cacheHit {
+		cache = "hit"
+	}
+	queryDuration.Observe(d.Seconds(), class, cache, IPVersion)
+}
+
+func observeExec(cmd, class string, size int, d time.Duration) {
+	execDuration.Observe(d.Seconds(), class, IPVersion)
+	checkSlowQuery(cmd, class, "exec", size, d)
+}
+
+// observeWarnings counts and logs the parser warnings of a result
+func observeWarnings(cmd, class string, parsed Parsed) {
+	warnings := Warnings(parsed)
+	if len(warnings) == 0 {
+		return
+	}
+	parserWarnings.Add(float64(len(warnings)), class, IPVersion)
+	log.Println("Parsing", cmd, "returned", len(warnings), "warnings, the first:", warnings[0])
+}
+
+func observeParse(cmd, class string, size int, d time.Duration) {
+	parseDuration.Observe(d.Seconds(), class, IPVersion)
+	checkSlowQuery(cmd, class, "parse", size, d)
+}
+
+// checkSlowQuery logs and counts a stage of a query
+// taking longer than the configured slow_query threshold.
+func checkSlowQuery(cmd, class, stage string, size int, d time.Duration) bool {
+	if ClientConf.SlowQuery <= 0 ||
+		d < time.Duration(ClientConf.SlowQuery)*time.Millisecond {
+		return false
+	}
+
+	log.Printf("Slow query: %s of '%s' took %s (%d bytes)", stage, cmd, d, size)
+	slowQueries.Inc(class, stage, IPVersion)
+	return true
+}
+
+// observeCacheLookup counts a hit or miss. A miss of a
+// tracked entry means it has expired in the cache.
+func observeCacheLookup(cmd, class string, hit bool) {
+	if hit {
+		cacheHits.Inc(class, IPVersion)
+		return
+	}
+	cacheMisses.Inc(class, IPVersion)
+	cacheEvicted(cmd, "expired")
+}
+
+// cacheStored tracks a new cache entry
+func cacheStored(cmd string, size int) {
+	if ClientConf.CacheTtl == 0 {
+		return // Nothing is cached
+	}
+
+	cachedSizes.Lock()
+	defer cachedSizes.Unlock()
+	cachedSizes.m[cmd] = size
+	updateCacheGauges()
+}
+
+// cacheEvicted counts and untracks a removed cache entry
+func cacheEvicted(cmd, reason string) {
+	cachedSizes.Lock()
+	defer cachedSizes.Unlock()
+	if _, ok := cachedSizes.m[cmd]; !ok {
+		return
+	}
+	delete(cachedSizes.m, cmd)
+	cacheEvictions.Inc(CommandClass(cmd), reason, IPVersion)
+	updateCacheGauges()
+}
+
+// updateCacheGauges sets the entries and bytes per
+// command class. The cachedSizes lock must be held.
+func updateCacheGauges() {
+	entries := map[string]int{}
+	bytes := map[string]int{}
+	for cmd, size := range cachedSizes.m {
+		class := CommandClass(cmd)
+		entries[class]++
+		bytes[class] += size
+	}
+
+	cacheEntries.Reset()
+	cacheBytes.Reset()
+	for class, n := range entries {
+		cacheEntries.Set(float64(n), class, IPVersion)
+		cacheBytes.Set(float64(bytes[class]), class, IPVersion)
+	}
+}
@@ -0,0 +1,47 @@
+package bird
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckSlowQuery(t *testing.T) {
+	defer func(conf BirdConfig) { ClientConf = conf }(ClientConf)
+
+	ClientConf.SlowQuery = 0
+	if checkSlowQuery("show route all", "route", "exec", 42, time.Minute) {
+		t.Error("The slow query log should be disabled")
+	}
+
+	ClientConf.SlowQuery = 100
+	if checkSlowQuery("show route all", "route", "exec", 42, 99*time.Millisecond) {
+		t.Error("Query below the threshold should not be logged")
+	}
+	if !checkSlowQuery("show route all", "route", "parse", 42, 100*time.Millisecond) {
+		t.Error("Query at the threshold should be logged")
+	}
+}
+
+func TestCacheTracking(t *testing.T) {
+	defer func(conf BirdConfig) { ClientConf = conf }(ClientConf)
+	ClientConf.CacheTtl = 5
+
+	memoryCache := NewMemoryCache(1)
+	memoryCache.evicted = cacheEvicted
+
+	for _, cmd := range []string{"show status", "show protocols all"} {
+		if err := memoryCache.Set(cmd, Parsed{}, ClientConf.CacheTtl); err != nil {
+			t.Fatal(err)
+		}
+		cacheStored(cmd, 100)
+	}
+
+	cachedSizes.Lock()
+	defer cachedSizes.Unlock()
+	if len(cachedSizes.m) != 1 {
+		t.Error("Expected the LRU entry to be untracked, got:", cachedSizes.m)
+	}
+	if cachedSizes.m["show protocols all"] != 100 {
+		t.Error("Expected the new entry to be tracked, got:", cachedSizes.m)
+	}
+}
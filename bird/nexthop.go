@@ -0,0 +1,113 @@
+package bird
+
+import (
+	"context"
+	"strings"
+)
+
+// Upper bound of distinct next hops resolved for one result
+const maxNexthopLookups = 64
+
+// routeNexthop returns the BGP next hop of a route, or the
+// gateway. Link-local next hops are not resolved.
+func routeNexthop(route Parsed) string {
+	nextHop, _ := route["gateway"].(string)
+	if bgp, ok := route["bgp"].(Parsed); ok {
+		bgpNextHop, _ := bgp["next_hop"].(string)
+		if addrs := strings.Fields(bgpNextHop); len(addrs) > 0 {
+			nextHop = addrs[0]
+		}
+	}
+	if nextHop == "" || isLinkLocal(nextHop) {
+		return ""
+	}
+	return nextHop
+}
+
+// resolveNexthop looks up the route of a next hop in a table
+// and returns its immediate gateway and interface.
+func resolveNexthop(ctx context.Context, useCache bool, nextHop, table string) (Parsed, bool) {
+	res, fromCache, err := RoutesLookupTable(ctx, useCache, nextHop, table)
+	routes, _ := res["routes"].([]Parsed)
+	if err != nil || len(routes) == 0 {
+		return Parsed{"address": nextHop, "resolved": false}, fromCache
+	}
+
+	route := routes[0]
+	for _, r := range routes {
+		if primary, _ := r["primary"].(bool); primary {
+			route = r
+			break
+		}
+	}
+
+	// Directly connected next hops are their own gateway
+	gateway, _ := route["gateway"].(string)
+	if gateway == "" {
+		gateway = nextHop
+	}
+	return Parsed{
+		"address":   nextHop,
+		"resolved":  true,
+		"network":   route["network"],
+		"protocol":  route["from_protocol"],
+		"gateway":   gateway,
+		"interface": route["interface"],
+	}, fromCache
+}
+
+// ResolveNexthops looks up the next hop of each route in the
+// table of the route, e.g. a BGP next hop resolved through the
+// IGP, and adds the gateway and interface of the route it resolves
+// to as resolved_nexthop. Each next hop is looked up once, beyond
+// maxNexthopLookups next hops the routes are left unresolved.
+// The lookups take a single rate limit token together.
+func ResolveNexthops(ctx context.Context, useCache bool, res Parsed) (Parsed, bool) {
+	routes, ok := res["routes"].([]Parsed)
+	if !ok {
+		return res, true
+	}
+	ctx = withSharedRateLimit(ctx)
+
+	fromCache := true
+	resolved := map[string]Parsed{}
+	withNexthops := make([]Parsed, 0, len(routes))
+	for _, route := range routes {
+		nextHop := routeNexthop(route)
+		if nextHop == "" {
+			withNexthops = append(withNexthops, route)
+			continue
+		}
+		table, _ := route["table"].(string)
+		if table == "" {
+			table = "master"
+		}
+
+		key := table + " " + nextHop
+		nexthop, ok := resolved[key]
+		if !ok && len(resolved) < maxNexthopLookups {
+			var cached bool
+			nexthop, cached = resolveNexthop(ctx, useCache, nextHop, table)
+			fromCache = fromCache && cached
+			resolved[key] = nexthop
+		}
+		if nexthop == nil {
+			withNexthops = append(withNexthops, route)
+			continue
+		}
+
+		copied := make(Parsed, len(route)+1)
+		for k, v := range route {
+			copied[k] = v
+		}
+		copied["resolved_nexthop"] = nexthop
+		withNexthops = append(withNexthops, copied)
+	}
+
+	result := make(Parsed, len(res))
+	for k, v := range res {
+		result[k] = v
+	}
+	result["routes"] = withNexthops
+	return result, fromCache
+}
@@ -0,0 +1,53 @@
+package bird
+
+import (
+	"context"
+	"testing"
+)
+
+func TestResolveNexthops(t *testing.T) {
+	defer withFixtures(t, map[string]string{
+		"route_for_1.2.3.16": "BIRD 1.6.3 ready.\n" +
+			"1.2.3.0/24         via 10.0.0.2 on eno8 [ospf1 2017-06-21 08:17:33] * (150/20) [10.0.0.2]\n" +
+			"\tType: OSPF unicast univ\n",
+	})()
+
+	route := Parsed{
+		"network": "16.0.0.0/24",
+		"gateway": "1.2.3.16",
+		"bgp":     Parsed{"next_hop": "1.2.3.16"},
+	}
+	res := Parsed{"routes": []Parsed{
+		route,
+		{"network": "16.0.1.0/24", "gateway": "1.2.3.16"},
+		{"network": "16.0.2.0/24", "gateway": "1.2.3.99"},
+		{"network": "fe80::/64", "gateway": "fe80::1"},
+	}}
+
+	// The lookups take one rate limit token together
+	RateLimitConf.Conf = RateLimitConfig{Enabled: true, Max: 2, Reqs: 2}
+	defer func() { RateLimitConf.Conf = RateLimitConfig{} }()
+
+	resolved, _ := ResolveNexthops(context.Background(), false, res)
+	if RateLimitConf.Conf.Reqs != 1 {
+		t.Error("Expected one rate limit token to be taken, left:", RateLimitConf.Conf.Reqs)
+	}
+	routes := resolved["routes"].([]Parsed)
+	nexthop, ok := routes[0]["resolved_nexthop"].(Parsed)
+	if !ok || nexthop["gateway"] != "10.0.0.2" || nexthop["interface"] != "eno8" ||
+		nexthop["network"] != "1.2.3.0/24" {
+		t.Error("Unexpected resolved next hop:", routes[0]["resolved_nexthop"])
+	}
+	if routes[1]["resolved_nexthop"].(Parsed)["gateway"] != "10.0.0.2" {
+		t.Error("Expected the gateway as next hop without BGP, got:", routes[1])
+	}
+	if routes[2]["resolved_nexthop"].(Parsed)["resolved"] != false {
+		t.Error("Expected an unresolved next hop, got:", routes[2])
+	}
+	if _, ok := routes[3]["resolved_nexthop"]; ok {
+		t.Error("Link-local next hops must not be resolved")
+	}
+	if _, ok := route["resolved_nexthop"]; ok {
+		t.Error("The routes of the result must not be modified")
+	}
+}
@@ -0,0 +1,189 @@
+package bird
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Reply codes of birdc preceding the lines, e.g. 1016-
+var ospfCodeRx = regexp.MustCompile(`^\d{4}[ -]`)
+
+// ospfTopology collects the areas of "show ospf topology"
+// and "show ospf state" and the external routes of the ASBRs.
+type ospfTopology struct {
+	areas     []Parsed
+	externals []Parsed
+
+	area    Parsed // Current area, nil for "other ASBRs"
+	router  Parsed // Current router
+	network Parsed // Current network
+}
+
+func (t *ospfTopology) startArea(id string) {
+	t.area = Parsed{
+		"area":     id,
+		"routers":  []Parsed{},
+		"networks": []Parsed{},
+		"links":    []Parsed{},
+	}
+	t.areas = append(t.areas, t.area)
+	t.router, t.network = nil, nil
+}
+
+// startNode begins a router or network of the current section
+func (t *ospfTopology) startNode(kind, id string) {
+	t.router, t.network = nil, nil
+	node := Parsed{kind: id, "reachable": true}
+	if kind == "router" {
+		t.router = node
+	} else {
+		node["routers"] = []string{}
+		t.network = node
+	}
+
+	if t.area == nil {
+		return // Only externals are listed for other ASBRs
+	}
+	key := kind + "s"
+	t.area[key] = append(t.area[key].([]Parsed), node)
+}
+
+// metricOf returns the number following a keyword like "metric"
+func metricOf(words []string, keyword string) (int64, bool) {
+	for i := 0; i+1 < len(words); i++ {
+		if words[i] == keyword {
+			return parseInt(words[i+1]), true
+		}
+	}
+	return 0, false
+}
+
+// parseRouterEntry reads an entry of a router, e.g.
+// "router 10.0.0.2 metric 10" or "external 0.0.0.0/0 metric2 100"
+func (t *ospfTopology) parseRouterEntry(words []string) {
+	if words[0] == "unreachable" {
+		t.router["reachable"] = false
+		return
+	}
+	if len(words) < 2 {
+		return
+	}
+
+	from := t.router["router"]
+	switch words[0] {
+	case "distance":
+		t.router["distance"] = parseInt(words[1])
+	case "external":
+		external := Parsed{"router": from, "network": words[1]}
+		if metric, ok := metricOf(words, "metric2"); ok {
+			external["metric"] = metric
+			external["metric_type"] = int64(2)
+		} else {
+			external["metric"], _ = metricOf(words, "metric")
+			external["metric_type"] = int64(1)
+		}
+		for i := 2; i+1 < len(words); i++ {
+			if words[i] == "via" || words[i] == "tag" {
+				external[words[i]] = words[i+1]
+			}
+		}
+		t.externals = append(t.externals, external)
+	case "router", "virtual-link", "network", "stubnet", "xnetwork", "xrouter":
+		if t.area == nil {
+			return
+		}
+		metric, _ := metricOf(words, "metric")
+		t.area["links"] = append(t.area["links"].([]Parsed), Parsed{
+			"from":   from,
+			"to":     words[1],
+			"type":   words[0],
+			"metric": metric,
+		})
+	}
+}
+
+// parseNetworkEntry reads an entry of a network: the designated
+// router, the distance and the attached routers.
+func (t *ospfTopology) parseNetworkEntry(words []string) {
+	if words[0] == "unreachable" {
+		t.network["reachable"] = false
+		return
+	}
+	if len(words) < 2 {
+		return
+	}
+
+	switch words[0] {
+	case "dr":
+		t.network["dr"] = words[1]
+	case "distance":
+		t.network["distance"] = parseInt(words[1])
+	case "router":
+		t.network["routers"] = append(t.network["routers"].([]string), words[1])
+	}
+}
+
+// parseOspfTopology parses the output of "show ospf topology"
+// or "show ospf state" into the routers, networks and links with
+// their costs per area. Entries are indented with tabs:
+//
+//	area 0.0.0.0
+//		router 10.0.0.1
+//			distance 0
+//			router 10.0.0.2 metric 10
+//			network 10.0.12.0/24 metric 10
+//		network 10.0.12.0/24
+//			dr 10.0.0.2
+//			router 10.0.0.1
+func parseOspfTopology(reader io.Reader) Parsed {
+	t := &ospfTopology{areas: []Parsed{}, externals: []Parsed{}}
+
+	lines := newLineIterator(reader, true)
+	for lines.next() {
+		line := ospfCodeRx.ReplaceAllString(lines.string(), "")
+		if specialLine(line) {
+			continue
+		}
+		depth := len(line) - len(strings.TrimLeft(line, "\t"))
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			continue
+		}
+
+		switch {
+		case depth == 0 && words[0] == "area" && len(words) > 1:
+			t.startArea(words[1])
+		case depth == 0 && line == "other ASBRs":
+			t.area, t.router, t.network = nil, nil, nil
+		case depth == 1 && len(words) > 1 && (words[0] == "router" || words[0] == "network"):
+			t.startNode(words[0], words[1])
+		case depth == 2 && t.router != nil:
+			t.parseRouterEntry(words)
+		case depth == 2 && t.network != nil:
+			t.parseNetworkEntry(words)
+		}
+	}
+
+	return Parsed{"areas": t.areas, "externals": t.externals}
+}
+
+// OspfTopology returns the topology of an OSPF protocol, with
+// state it includes the stub networks and external routes.
+func OspfTopology(ctx context.Context, useCache bool, protocol string, state bool) (Parsed, bool, error) {
+	cmd := "ospf topology"
+	if state {
+		cmd = "ospf state"
+	}
+	if protocol != "" {
+		cmd += " '" + protocol + "'"
+	}
+	return RunAndParse(
+		ctx,
+		useCache,
+		GetCacheKey("OspfTopology", protocol, state),
+		cmd,
+		parseOspfTopology,
+		nil)
+}
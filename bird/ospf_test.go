@@ -0,0 +1,53 @@
+package bird
+
+import (
+	"testing"
+)
+
+func TestParseOspfTopology(t *testing.T) {
+	f, err := openFile("ospf_state.sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	res := parseOspfTopology(f)
+	areas := res["areas"].([]Parsed)
+	if len(areas) != 2 || areas[0]["area"] != "0.0.0.0" {
+		t.Fatal("Expected the areas 0.0.0.0 and 0.0.0.1, got:", areas)
+	}
+
+	routers := areas[0]["routers"].([]Parsed)
+	if len(routers) != 3 || routers[1]["distance"] != int64(10) || routers[2]["reachable"] != false {
+		t.Error("Unexpected routers:", routers)
+	}
+
+	networks := areas[0]["networks"].([]Parsed)
+	if len(networks) != 1 || networks[0]["dr"] != "10.0.0.2" ||
+		len(networks[0]["routers"].([]string)) != 2 {
+		t.Error("Unexpected networks:", networks)
+	}
+
+	links := areas[0]["links"].([]Parsed)
+	if len(links) != 6 {
+		t.Fatal("Expected 6 links, got:", links)
+	}
+	if l := links[0]; l["from"] != "10.0.0.1" || l["to"] != "10.0.0.2" ||
+		l["type"] != "router" || l["metric"] != int64(10) {
+		t.Error("Unexpected link:", l)
+	}
+	if l := links[3]; l["from"] != "10.0.0.2" || l["type"] != "router" {
+		t.Error("Unexpected link:", l)
+	}
+
+	externals := res["externals"].([]Parsed)
+	if len(externals) != 2 {
+		t.Fatal("Expected 2 externals, got:", externals)
+	}
+	if e := externals[0]; e["metric"] != int64(10000) || e["metric_type"] != int64(2) || e["via"] != "10.0.12.254" {
+		t.Error("Unexpected external:", e)
+	}
+	if e := externals[1]; e["router"] != "10.0.0.7" || e["metric_type"] != int64(1) || e["tag"] != "00000001" {
+		t.Error("Unexpected external of another ASBR:", e)
+	}
+}
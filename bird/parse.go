@@ -0,0 +1,36 @@
+package bird
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+var parsers = map[string]func(io.Reader) Parsed{
+	"status":          parseStatus,
+	"protocols":       parseProtocols,
+	"protocols_short": parseProtocolsShort,
+	"symbols":         parseSymbols,
+	"routes":          parseRoutes,
+	"routes_count":    parseRoutesCount,
+}
+
+// ParserTypes lists the available output types
+func ParserTypes() []string {
+	types := make([]string, 0, len(parsers))
+	for t := range parsers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Parse birdc output of the given type, e.g. from a
+// file captured with birdc.
+func Parse(outputType string, reader io.Reader) (Parsed, error) {
+	parser, ok := parsers[outputType]
+	if !ok {
+		return nil, fmt.Errorf("unknown output type: %s", outputType)
+	}
+	return parser(reader), nil
+}
@@ -0,0 +1,25 @@
+package bird
+
+import (
+	"testing"
+)
+
+func TestParseByType(t *testing.T) {
+	f, err := openFile("routes_bird2_ipv4.sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	res, err := Parse("routes", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if routes := res["routes"].([]Parsed); len(routes) != 4 {
+		t.Error("Expected 4 routes, got:", len(routes))
+	}
+
+	if _, err := Parse("unknown", f); err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+}
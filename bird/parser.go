@@ -3,10 +3,12 @@ package bird
 import (
 	"bufio"
 	"io"
+	"net"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // WorkerPoolSize is the number of go routines used to parse routing tables concurrently
@@ -49,6 +51,7 @@ var (
 			prefix            *regexp.Regexp
 			gateway           *regexp.Regexp
 			iface             *regexp.Regexp
+			table             *regexp.Regexp
 		}
 	}
 )
@@ -78,18 +81,19 @@ func init() {
 	regex.protocol.stringValue = regexp.MustCompile(`^\s+([^:]+):\s+(.+)\s*$`)
 	regex.protocol.routeChanges = regexp.MustCompile(`(Import|Export) (updates|withdraws):\s+(\d+|---)\s+(\d+|---)\s+(\d+|---)\s+(\d+|---)\s+(\d+|---)\s*$`)
 
-	regex.routes.startDefinition = regexp.MustCompile(`^(` + re_prefix + `)\s+via\s+(` + re_ip + `)\s+on\s+(` + re_ifname + `)\s+\[([\w\.:]+)\s+([0-9\-\:\s]+)(?:\s+from\s+(` + re_prefix + `)){0,1}\]\s+(?:(\*)\s+){0,1}\((\d+)(?:\/\d+){0,1}|\?\).*`)
+	regex.routes.startDefinition = regexp.MustCompile(`^(` + re_prefix + `)\s+via\s+(` + re_ip + `)\s+on\s+(` + re_ifname + `)\s+\[([\w\.:]+)\s+([0-9\-\:\s]+)(?:\s+from\s+(` + re_prefix + `)){0,1}\]\s+(?:(\*)\s+){0,1}\((\d+)(?:\/(?:\d+|\?)){0,1}\).*`)
 	regex.protocol.short = regexp.MustCompile(`^(?:1002\-)?(\S+)\s+(\S+)\s+(\S+)\s+(\S+)\s+([0-9\-]+\s+[0-9\:\.]+?|[0-9\-]+|[0-9\:\.]+)(?:\s*|\s+(.*)\s*?)$`)
-	regex.routes.second = regexp.MustCompile(`^\s+via\s+(` + re_ip + `)\s+on\s+(` + re_ifname + `)\s+\[([\w\.:]+)\s+([0-9\-\:\s]+)(?:\s+from\s+(` + re_prefix + `)){0,1}\]\s+(?:(\*)\s+){0,1}\((\d+)(?:\/\d+){0,1}\).*$`)
+	regex.routes.second = regexp.MustCompile(`^\s+via\s+(` + re_ip + `)\s+on\s+(` + re_ifname + `)\s+\[([\w\.:]+)\s+([0-9\-\:\s]+)(?:\s+from\s+(` + re_prefix + `)){0,1}\]\s+(?:(\*)\s+){0,1}\((\d+)(?:\/(?:\d+|\?)){0,1}\).*$`)
 	regex.routes.routeType = regexp.MustCompile(`^\s+(?:Type|source):\s+(.*)\s*$`)
 	regex.routes.bgp = regexp.MustCompile(`^\s+(?:(?i)bgp).(\w+):\s+(.+)\s*$`)
 	regex.routes.community = regexp.MustCompile(`^\((\d+),\s*(\d+)\)`)
 	regex.routes.largeCommunity = regexp.MustCompile(`^\((\d+),\s*(\d+),\s*(\d+)\)`)
 	regex.routes.extendedCommunity = regexp.MustCompile(`^\(([^,]+),\s*([^,]+),\s*([^,]+)\)`)
 	regex.routes.origin = regexp.MustCompile(`\([^\(]*\)\s*`)
-	regex.routes.prefix = regexp.MustCompile(`^(` + re_prefix + `)?\s+(?:unicast|blackhole)\s+\[([\w\.:]+)\s+([0-9\-\:\.\s]+)(?:\s+from\s+(` + re_prefix + `))?\]\s+(?:(\*)\s+)?\((\d+)(?:\/\d+)?(?:\/[^\)]*)?\).*$`)
-	regex.routes.gateway = regexp.MustCompile(`^\s+via\s+(` + re_ip + `)\s+on\s+(` + re_ifname + `)\s*$`)
+	regex.routes.prefix = regexp.MustCompile(`^(` + re_prefix + `)?\s+(?:unicast|blackhole|unreachable|prohibit)\s+\[([\w\.:]+)\s+([0-9\-\:\.\s]+)(?:\s+from\s+(` + re_prefix + `))?\]\s+(?:(\*)\s+)?\((\d+)(?:\/\d+)?(?:\/[^\)]*)?\).*$`)
+	regex.routes.gateway = regexp.MustCompile(`^\s+via\s+(` + re_ip + `)(?:%\S+)?\s+on\s+(` + re_ifname + `)\s*$`)
 	regex.routes.iface = regexp.MustCompile(`^\s+dev\s+(` + re_ifname + `)\s*$`)
+	regex.routes.table = regexp.MustCompile(`^Table\s+(\S+):\s*$`)
 }
 
 func dirtyContains(l []string, e string) bool {
@@ -172,6 +176,8 @@ func parseProtocolsShort(reader io.Reader) Parsed {
 
 func parseProtocols(reader io.Reader) Parsed {
 	res := Parsed{}
+	warnings := parseWarnings{}
+	now := time.Now()
 
 	proto := ""
 
@@ -183,7 +189,19 @@ func parseProtocols(reader io.Reader) Parsed {
 			if !emptyString(proto) {
 				parsed := parseProtocol(proto)
 
-				res[parsed["protocol"].(string)] = parsed
+				name, ok := parsed["protocol"].(string)
+				if !ok {
+					warnings.add("Protocol block without a header: %s",
+						strings.TrimSpace(strings.SplitN(proto, "\n", 2)[0]))
+					proto = ""
+					continue
+				}
+				if changed, _ := parsed["state_changed"].(string); changed != "" {
+					if _, ok := parseSince(changed, now); !ok {
+						warnings.add("Unparsable state change timestamp of %s: %s", name, changed)
+					}
+				}
+				res[name] = parsed
 			}
 			proto = ""
 		} else {
@@ -191,7 +209,7 @@ func parseProtocols(reader io.Reader) Parsed {
 		}
 	}
 
-	return Parsed{"protocols": res}
+	return warnings.attach(Parsed{"protocols": res})
 }
 
 func parseSymbols(reader io.Reader) Parsed {
@@ -222,11 +240,13 @@ func parseSymbols(reader io.Reader) Parsed {
 type blockJob struct {
 	lines    []string
 	position int
+	table    string // From "Table ...:" of "show route table all"
 }
 
 type blockParsed struct {
 	items    []Parsed
 	position int
+	warnings parseWarnings
 }
 
 func parseRoutes(reader io.Reader) Parsed {
@@ -238,22 +258,28 @@ func parseRoutes(reader io.Reader) Parsed {
 
 	pos := 0
 	block := []string{}
+	table := ""
 	lines := newLineIterator(reader, true)
 
 	for lines.next() {
 		line := lines.string()
 
 		if line[0] != 32 && line[0] != 9 && len(block) > 0 {
-			jobs <- blockJob{block, pos}
+			jobs <- blockJob{block, pos, table}
 			pos++
 			block = []string{}
 		}
 
+		if m := regex.routes.table.FindStringSubmatch(line); m != nil {
+			table = m[1]
+			continue
+		}
+
 		block = append(block, line)
 	}
 
 	if len(block) > 0 {
-		jobs <- blockJob{block, pos}
+		jobs <- blockJob{block, pos, table}
 	}
 
 	close(jobs)
@@ -282,12 +308,20 @@ func startRouteConsumer(out <-chan blockParsed) chan Parsed {
 
 	go func() {
 		byBlock := map[int][]Parsed{}
+		warningsByBlock := map[int]parseWarnings{}
 		count := 0
 		for r := range out {
 			count++
 			byBlock[r.position] = r.items
+			warningsByBlock[r.position] = r.warnings
 		}
-		res <- Parsed{"routes": sortedSliceForRouteBlocks(byBlock, count)}
+
+		// Keep the order of the warnings in the output
+		warnings := parseWarnings{}
+		for i := 0; i < count; i++ {
+			warnings.merge(warningsByBlock[i])
+		}
+		res <- warnings.attach(Parsed{"routes": sortedSliceForRouteBlocks(byBlock, count)})
 	}()
 
 	return res
@@ -310,14 +344,15 @@ func sortedSliceForRouteBlocks(byBlock map[int][]Parsed, numBlocks int) []Parsed
 
 func workerForRouteBlockParsing(jobs <-chan blockJob, out chan<- blockParsed, wg *sync.WaitGroup) {
 	for j := range jobs {
-		parseRouteLines(j.lines, j.position, out)
+		parseRouteLines(j.lines, j.position, j.table, out)
 	}
 	wg.Done()
 }
 
-func parseRouteLines(lines []string, position int, ch chan<- blockParsed) {
+func parseRouteLines(lines []string, position int, table string, ch chan<- blockParsed) {
 	route := Parsed{}
 	routes := []Parsed{}
+	warnings := parseWarnings{}
 
 	for i := 0; i < len(lines); {
 		line := lines[i]
@@ -344,8 +379,12 @@ func parseRouteLines(lines []string, position int, ch chan<- blockParsed) {
 			}
 
 			parseMainRouteDetail(regex.routes.startDefinition.FindStringSubmatch(line), route)
+		} else if len(route) == 0 {
+			warnings.add("Route attributes without a route: %s", strings.TrimSpace(line))
 		} else if regex.routes.gateway.MatchString(line) {
 			parseRoutesGatewayBird2(regex.routes.gateway.FindStringSubmatch(line), route)
+		} else if regex.routes.iface.MatchString(line) {
+			route["interface"] = regex.routes.iface.FindStringSubmatch(line)[1]
 		} else if regex.routes.second.MatchString(line) {
 			routes = append(routes, route)
 
@@ -388,6 +427,12 @@ func parseRouteLines(lines []string, position int, ch chan<- blockParsed) {
 
 			parseRoutesBgp(line, bgp)
 			route["bgp"] = bgp
+
+			if nextHop, ok := bgp["next_hop"].(string); ok {
+				setBgpNextHop(route, nextHop)
+			}
+		} else if name := routeAttributeName(line); !ignoredRouteAttributes[name] {
+			warnings.add("Unknown route attribute: %s", name)
 		}
 
 		i++
@@ -397,12 +442,18 @@ func parseRouteLines(lines []string, position int, ch chan<- blockParsed) {
 		routes = append(routes, route)
 	}
 
-	ch <- blockParsed{routes, position}
+	if table != "" {
+		for _, route := range routes {
+			route["table"] = table
+		}
+	}
+
+	ch <- blockParsed{routes, position, warnings}
 }
 
 func parseMainRouteDetail(groups []string, route Parsed) {
 	route["network"] = groups[1]
-	route["gateway"] = groups[2]
+	setGateway(route, groups[2])
 	route["interface"] = groups[3]
 	route["from_protocol"] = groups[4]
 	route["age"] = groups[5]
@@ -438,10 +489,70 @@ func parseMainRouteDetailBird2(groups []string, route Parsed, formerPrefix strin
 }
 
 func parseRoutesGatewayBird2(groups []string, route Parsed) {
-	route["gateway"] = groups[1]
+	setGateway(route, groups[1])
 	route["interface"] = groups[2]
 }
 
+func isLinkLocal(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.IsLinkLocalUnicast()
+}
+
+// setGateway sets the next hop of a route. A link-local next
+// hop is also kept as gateway_ll and does not replace a global
+// gateway. Without a global next hop, the gateway is link-local.
+func setGateway(route Parsed, gateway string) {
+	if !isLinkLocal(gateway) {
+		route["gateway"] = gateway
+		return
+	}
+
+	route["gateway_ll"] = gateway
+	if current, ok := route["gateway"].(string); !ok || isLinkLocal(current) {
+		route["gateway"] = gateway
+	}
+}
+
+// setBgpNextHop applies a BGP.next_hop with a global and a
+// link-local address, e.g. "2001:db8::1 fe80::1". If BIRD
+// showed the link-local address as gateway, the global address
+// of the same next hop becomes the gateway.
+func setBgpNextHop(route Parsed, nextHop string) {
+	addrs := strings.Fields(nextHop)
+	if len(addrs) != 2 || isLinkLocal(addrs[0]) || !isLinkLocal(addrs[1]) {
+		return
+	}
+	global, linkLocal := addrs[0], addrs[1]
+
+	if _, ok := route["gateway_ll"]; !ok {
+		route["gateway_ll"] = linkLocal
+	}
+	if gateway, ok := route["gateway"].(string); ok && gateway == linkLocal {
+		route["gateway"] = global
+	}
+}
+
+// Attributes of BIRD 3 which are not parsed on purpose
+var ignoredRouteAttributes = map[string]bool{
+	"preference":                     true,
+	"igp_metric":                     true,
+	"from":                           true,
+	"Internal route handling values": true,
+}
+
+// routeAttributeName returns the name of an attribute
+// line like "\tospf_metric1: 20" for warnings.
+func routeAttributeName(line string) string {
+	name := strings.TrimSpace(line)
+	if colon := strings.Index(name, ":"); colon > 0 {
+		name = name[:colon]
+	}
+	if len(name) > 40 {
+		name = name[:40] + "..."
+	}
+	return name
+}
+
 func parseRoutesSecond(line string, route Parsed) Parsed {
 	tmp, ok := route["network"]
 	if !ok {
@@ -577,6 +688,8 @@ func parseProtocol(lines string) Parsed {
 	}
 
 	res["route_changes"] = routeChanges
+	parseLastError(res)
+	parseFilters(res)
 
 	if _, ok := res["routes"]; !ok {
 		routes := Parsed{}
@@ -592,6 +705,18 @@ func parseProtocol(lines string) Parsed {
 	return res
 }
 
+// parseFilters names the filters of a protocol like the BIRD
+// configuration: the "Input filter" is the import_filter and the
+// "Output filter" the export_filter, e.g. ACCEPT or (unnamed).
+func parseFilters(res Parsed) {
+	if filter, ok := res["input_filter"].(string); ok {
+		res["import_filter"] = strings.TrimSpace(filter)
+	}
+	if filter, ok := res["output_filter"].(string); ok {
+		res["export_filter"] = strings.TrimSpace(filter)
+	}
+}
+
 func parseLine(line string, handlers []func(string) bool) {
 	for _, h := range handlers {
 		if h(line) {
@@ -0,0 +1,57 @@
+package bird
+
+import (
+	"bytes"
+	"testing"
+)
+
+func benchmarkParseRoutes(b *testing.B, n int) {
+	buf := &bytes.Buffer{}
+	if err := GenerateRoutesOutput(buf, n); err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		res := parseRoutes(bytes.NewReader(data))
+		if routes := res["routes"].([]Parsed); len(routes) != n {
+			b.Fatal("Expected", n, "routes, got:", len(routes))
+		}
+	}
+}
+
+func BenchmarkParseRoutes100k(b *testing.B) {
+	benchmarkParseRoutes(b, 100000)
+}
+
+func BenchmarkParseRoutes1M(b *testing.B) {
+	if testing.Short() {
+		b.Skip("skipping 1M routes in short mode")
+	}
+	benchmarkParseRoutes(b, 1000000)
+}
+
+func BenchmarkParseProtocols(b *testing.B) {
+	f, err := openFile("protocols_bgp_pipe.sample")
+	if err != nil {
+		b.Fatal(err)
+	}
+	sample := &bytes.Buffer{}
+	sample.ReadFrom(f)
+	f.Close()
+
+	// Repeat the sample to get a few thousand protocol blocks
+	data := bytes.Repeat(append(sample.Bytes(), '\n'), 1000)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		parseProtocols(bytes.NewReader(data))
+	}
+}
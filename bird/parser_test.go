@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/kr/pretty"
@@ -133,6 +134,26 @@ func TestParseProtocolBgp(t *testing.T) {
 	fmt.Println(protocols)
 }
 
+func TestParseProtocolFilters(t *testing.T) {
+	f, err := openFile("protocols_bgp_pipe.sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	protocols := parseProtocols(f)["protocols"].(Parsed)
+	for name, filters := range map[string][2]string{
+		"M65001_nada_co_ripe":  {"in_nada_co_ripe", "(unnamed)"},
+		"C65003_nada2_co_ripe": {"in_nada2_co_ripe", "REJECT"},
+	} {
+		protocol := protocols[name].(Parsed)
+		if protocol["import_filter"] != filters[0] || protocol["export_filter"] != filters[1] {
+			t.Error("Unexpected filters of", name, "got:",
+				protocol["import_filter"], protocol["export_filter"])
+		}
+	}
+}
+
 func TestParseProtocolShort(t *testing.T) {
 	f, err := openFile("protocols_short.sample")
 	if err != nil {
@@ -555,3 +576,155 @@ type expectedRoute struct {
 	localPref           string
 	iface               string
 }
+
+func TestParseRoutesLinkLocalGateway(t *testing.T) {
+	out := "BIRD 2.0.7 ready.\n" +
+		"2001:db8:1::/48      unicast [R1 2024-01-15 10:00:00] * (100) [AS64500i]\n" +
+		"\tvia fe80::1%eth0 on eth0\n" +
+		"\tType: BGP univ\n" +
+		"\tBGP.next_hop: 2001:db8:ff::1 fe80::1\n" +
+		"2001:db8:2::/48      unicast [R2 2024-01-15 10:00:00] * (100) [AS64501i]\n" +
+		"\tvia fe80::2 on eth1\n" +
+		"\tType: BGP univ\n" +
+		"\tBGP.next_hop: fe80::2\n"
+
+	routes := parseRoutes(strings.NewReader(out))["routes"].([]Parsed)
+	if len(routes) != 2 {
+		t.Fatal("Expected 2 routes, got:", len(routes))
+	}
+
+	expected := []struct{ gateway, gatewayLL, iface string }{
+		{"2001:db8:ff::1", "fe80::1", "eth0"},
+		{"fe80::2", "fe80::2", "eth1"},
+	}
+	for i, e := range expected {
+		route := routes[i]
+		if route["gateway"] != e.gateway || route["gateway_ll"] != e.gatewayLL || route["interface"] != e.iface {
+			t.Error("Unexpected next hop of", route["network"], ":",
+				route["gateway"], route["gateway_ll"], route["interface"])
+		}
+	}
+}
+
+func TestParseRoutesPrimary(t *testing.T) {
+	out := "BIRD 1.6.8 ready.\n" +
+		"10.0.0.0/8         via 192.0.2.1 on eth0 [R1 2024-01-15 10:00:00] * (100/?) [AS64500i]\n" +
+		"\tType: BGP unicast univ\n" +
+		"                   via 192.0.2.2 on eth0 [R2 2024-01-15 10:00:00 from 192.0.2.9] (100/?) [AS64501i]\n" +
+		"\tType: BGP unicast univ\n" +
+		"10.1.0.0/16         unreachable [static1 2024-01-15] * (200)\n"
+
+	routes := parseRoutes(strings.NewReader(out))["routes"].([]Parsed)
+	if len(routes) != 3 {
+		t.Fatal("Expected 3 routes, got:", routes)
+	}
+
+	expected := []struct {
+		network, protocol string
+		primary           bool
+	}{
+		{"10.0.0.0/8", "R1", true},
+		{"10.0.0.0/8", "R2", false},
+		{"10.1.0.0/16", "static1", true},
+	}
+	for i, e := range expected {
+		route := routes[i]
+		if route["network"] != e.network || route["from_protocol"] != e.protocol || route["primary"] != e.primary {
+			t.Error("Unexpected route", i, ":", route)
+		}
+	}
+}
+
+func TestParseRoutesTableAll(t *testing.T) {
+	out := "BIRD 2.0.7 ready.\n" +
+		"Table master4:\n" +
+		"10.0.0.0/8           unicast [R1 2024-01-15 10:00:00] * (100) [AS64500i]\n" +
+		"\tvia 192.0.2.1 on eth0\n" +
+		"                     unicast [R2 2024-01-15 10:00:00] (100) [AS64501i]\n" +
+		"\tvia 192.0.2.2 on eth0\n" +
+		"\n" +
+		"Table T65001:\n" +
+		"10.0.0.0/8           unicast [R1 2024-01-15 10:00:00] * (100) [AS64500i]\n" +
+		"\tvia 192.0.2.1 on eth0\n"
+
+	routes := parseRoutes(strings.NewReader(out))["routes"].([]Parsed)
+	if len(routes) != 3 {
+		t.Fatal("Expected 3 routes, got:", routes)
+	}
+	for i, table := range []string{"master4", "master4", "T65001"} {
+		if routes[i]["table"] != table || routes[i]["network"] != "10.0.0.0/8" {
+			t.Error("Expected route", i, "in table", table, "got:", routes[i])
+		}
+	}
+}
+
+func TestParseRoutesWarnings(t *testing.T) {
+	out := "BIRD 2.0.7 ready.\n" +
+		"\tBGP.origin: IGP\n" +
+		"10.0.0.0/8           unicast [R1 2024-01-15 10:00:00] * (100) [AS64500i]\n" +
+		"\tvia 192.0.2.1 on eth0\n" +
+		"\tospf_metric1: 20\n" +
+		"10.1.0.0/16          unicast [R1 2024-01-15 10:00:00] * (100) [AS64500i]\n" +
+		"\tdev eth1\n" +
+		"\tospf_metric1: 10\n"
+
+	res := parseRoutes(strings.NewReader(out))
+	if routes := res["routes"].([]Parsed); len(routes) != 2 || routes[1]["interface"] != "eth1" {
+		t.Fatal("Expected 2 routes, got:", routes)
+	}
+
+	warnings := Warnings(res)
+	expected := []string{
+		"Route attributes without a route: BGP.origin: IGP",
+		"Unknown route attribute: ospf_metric1 (2 times)",
+	}
+	if len(warnings) != len(expected) {
+		t.Fatal("Expected", expected, "got:", warnings)
+	}
+	for i, w := range expected {
+		if warnings[i] != w {
+			t.Error("Expected", w, "got:", warnings[i])
+		}
+	}
+}
+
+func TestParseWarningsLimit(t *testing.T) {
+	w := parseWarnings{}
+	for i := 0; i < maxWarnings+5; i++ {
+		w.add("warning %d", i)
+	}
+	warnings := w.list()
+	if len(warnings) != maxWarnings+1 || warnings[maxWarnings] != "... and 5 more warnings" {
+		t.Error("Unexpected warnings:", warnings[maxWarnings:])
+	}
+}
+
+func TestParseLastError(t *testing.T) {
+	tests := []struct {
+		lastError string
+		class     string
+		message   string
+		code      interface{}
+		subcode   interface{}
+	}{
+		{"Socket: Connection closed", "socket", "Connection closed", nil, nil},
+		{"Received: Hold timer expired", "received", "Hold timer expired", int64(4), int64(0)},
+		{"BGP Error: Bad peer AS", "sent", "Bad peer AS", int64(2), int64(2)},
+		{"Received: Administrative shutdown", "received", "Administrative shutdown", int64(6), int64(2)},
+		{"Received: Invalid ROUTE-REFRESH message length", "received", "Invalid ROUTE-REFRESH message length", int64(7), int64(1)},
+		{"Received: Unknown error 6.42", "received", "Unknown error 6.42", int64(6), int64(42)},
+		{"Automatic shutdown: Route limit exceeded", "automatic_shutdown", "Route limit exceeded", nil, nil},
+	}
+
+	for _, test := range tests {
+		res := parseProtocol("BGP1     BGP      master   start  2018-01-01 Active\n" +
+			"  Last error:       " + test.lastError + "\n")
+		if res["last_error_class"] != test.class || res["last_error_message"] != test.message {
+			t.Error("Unexpected class or message of", test.lastError, ":", res)
+		}
+		if res["notification_code"] != test.code || res["notification_subcode"] != test.subcode {
+			t.Error("Unexpected notification of", test.lastError, ":",
+				res["notification_code"], res["notification_subcode"])
+		}
+	}
+}
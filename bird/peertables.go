@@ -0,0 +1,99 @@
+package bird
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// A PeerTableRule maps a peer protocol name to the pipe
+// and table of the peer. The pipe and table are
+// replacement templates for the peer pattern, e.g.
+//
+//	peer = "^R_AS(\d+)_(\d+)$"
+//	pipe = "P_AS${1}_${2}"
+//	table = "T_AS${1}_${2}"
+type PeerTableRule struct {
+	Peer  string `toml:"peer"`
+	Pipe  string `toml:"pipe"`
+	Table string `toml:"table"`
+}
+
+// A PeerTablePrefix maps peer protocols by replacing the
+// prefix of the name, e.g. R_AS65001 -> M_AS65001.
+type PeerTablePrefix struct {
+	Peer  string `toml:"peer"`
+	Pipe  string `toml:"pipe"`
+	Table string `toml:"table"`
+}
+
+type PeerTablesConfig struct {
+	Rules    []PeerTableRule   `toml:"rules"`
+	Prefixes []PeerTablePrefix `toml:"prefixes"`
+}
+
+type peerTableRule struct {
+	peer  *regexp.Regexp
+	pipe  string
+	table string
+}
+
+var peerTableRules []peerTableRule
+
+// prefixRules converts the prefixes to rules replacing the
+// prefix. Longer prefixes are matched first, so a peer
+// RS1_AS65001 is not mapped using the prefix R.
+func prefixRules(prefixes []PeerTablePrefix) []PeerTableRule {
+	sorted := make([]PeerTablePrefix, len(prefixes))
+	copy(sorted, prefixes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Peer) > len(sorted[j].Peer)
+	})
+
+	rules := make([]PeerTableRule, 0, len(sorted))
+	for _, prefix := range sorted {
+		rules = append(rules, PeerTableRule{
+			Peer:  "^" + regexp.QuoteMeta(prefix.Peer) + "(.+)$",
+			Pipe:  prefix.Pipe + "${1}",
+			Table: prefix.Table + "${1}",
+		})
+	}
+	return rules
+}
+
+// ConfigurePeerTables compiles the peer table rules.
+// The rules are matched before the prefixes.
+func ConfigurePeerTables(config PeerTablesConfig) error {
+	definitions := append([]PeerTableRule{}, config.Rules...)
+	definitions = append(definitions, prefixRules(config.Prefixes)...)
+
+	rules := make([]peerTableRule, 0, len(definitions))
+	for _, rule := range definitions {
+		peer, err := regexp.Compile(rule.Peer)
+		if err != nil {
+			return fmt.Errorf("invalid peer pattern %s: %s", rule.Peer, err)
+		}
+		rules = append(rules, peerTableRule{
+			peer:  peer,
+			pipe:  rule.Pipe,
+			table: rule.Table,
+		})
+	}
+	peerTableRules = rules
+	return nil
+}
+
+// PeerPipeAndTable returns the pipe and table of a peer
+// protocol using the first matching rule.
+func PeerPipeAndTable(protocol string) (string, string, bool) {
+	for _, rule := range peerTableRules {
+		match := rule.peer.FindStringSubmatchIndex(protocol)
+		if match == nil {
+			continue
+		}
+		pipe := rule.peer.ExpandString(nil, rule.pipe, protocol, match)
+		table := rule.peer.ExpandString(nil, rule.table, protocol, match)
+		return string(pipe), string(table), true
+	}
+	return "", "", false
+}
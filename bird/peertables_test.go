@@ -0,0 +1,76 @@
+package bird
+
+import (
+	"testing"
+)
+
+func TestPeerPipeAndTable(t *testing.T) {
+	err := ConfigurePeerTables(PeerTablesConfig{
+		Rules: []PeerTableRule{
+			{Peer: `^R_AS(\d+)_(\d+)$`, Pipe: "P_AS${1}_${2}", Table: "T_AS${1}_${2}"},
+			{Peer: `^R(\d+)_(\w+)$`, Pipe: "M${1}_$2", Table: "T${1}_$2"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ConfigurePeerTables(PeerTablesConfig{})
+
+	tests := []struct {
+		protocol, pipe, table string
+		ok                    bool
+	}{
+		{"R_AS65001_1", "P_AS65001_1", "T_AS65001_1", true},
+		{"R194_42", "M194_42", "T194_42", true},
+		{"device1", "", "", false},
+	}
+	for _, test := range tests {
+		pipe, table, ok := PeerPipeAndTable(test.protocol)
+		if pipe != test.pipe || table != test.table || ok != test.ok {
+			t.Error(test.protocol, "expected", test.pipe, test.table, test.ok,
+				"got:", pipe, table, ok)
+		}
+	}
+
+	if err := ConfigurePeerTables(PeerTablesConfig{
+		Rules: []PeerTableRule{{Peer: "R_(", Pipe: "P"}},
+	}); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestPeerTablePrefixes(t *testing.T) {
+	err := ConfigurePeerTables(PeerTablesConfig{
+		Rules: []PeerTableRule{
+			{Peer: `^R_AS(\d+)_(\d+)$`, Pipe: "P_AS${1}_${2}", Table: "T_AS${1}_${2}"},
+		},
+		Prefixes: []PeerTablePrefix{
+			{Peer: "R", Pipe: "M", Table: "T"},
+			{Peer: "RS1_", Pipe: "P1_", Table: "T1_"},
+			{Peer: "C_", Pipe: "CM_", Table: "CT_"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ConfigurePeerTables(PeerTablesConfig{})
+
+	tests := []struct {
+		protocol, pipe, table string
+		ok                    bool
+	}{
+		{"R_AS65001_1", "P_AS65001_1", "T_AS65001_1", true},
+		{"RS1_AS65002", "P1_AS65002", "T1_AS65002", true},
+		{"R194_42", "M194_42", "T194_42", true},
+		{"C_AS65003", "CM_AS65003", "CT_AS65003", true},
+		{"C_", "", "", false},
+		{"device1", "", "", false},
+	}
+	for _, test := range tests {
+		pipe, table, ok := PeerPipeAndTable(test.protocol)
+		if pipe != test.pipe || table != test.table || ok != test.ok {
+			t.Error(test.protocol, "expected", test.pipe, test.table, test.ok,
+				"got:", pipe, table, ok)
+		}
+	}
+}
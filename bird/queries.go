@@ -0,0 +1,111 @@
+package bird
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"text/template"
+)
+
+// QueriesConfig customizes the birdc route queries.
+// Templates are keyed by the query name, e.g.
+//
+//	[queries.templates]
+//	RoutesTable = "route table '{{.Table}}' all filter peer_out"
+//
+// The commands are run as "show <command>".
+type QueriesConfig struct {
+	// Restrict BIRD 2 route queries to the IP version,
+	// default: net.type = NET_IP{{.IPVersion}}
+	ChannelFilter   string `toml:"channel_filter"`
+	NoChannelFilter bool   `toml:"no_channel_filter"`
+
+	Templates map[string]string `toml:"templates"`
+}
+
+// QueryVars are available in the query templates
+type QueryVars struct {
+	Prefix    string
+	Protocol  string
+	Table     string
+	Peer      string
+	Pipe      string
+	Where     string
+	IPVersion string
+}
+
+var queries struct {
+	channelFilter   *template.Template
+	noChannelFilter bool
+	templates       map[string]*template.Template
+}
+
+const defaultChannelFilter = "net.type = NET_IP{{.IPVersion}}"
+
+// ConfigureQueries parses the query templates
+func ConfigureQueries(config QueriesConfig) error {
+	filter := config.ChannelFilter
+	if filter == "" {
+		filter = defaultChannelFilter
+	}
+	channelFilter, err := template.New("channel_filter").Parse(filter)
+	if err != nil {
+		return fmt.Errorf("invalid channel filter: %s", err)
+	}
+
+	templates := make(map[string]*template.Template, len(config.Templates))
+	for name, text := range config.Templates {
+		t, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return fmt.Errorf("invalid query template %s: %s", name, err)
+		}
+		templates[name] = t
+	}
+
+	queries.channelFilter = channelFilter
+	queries.noChannelFilter = config.NoChannelFilter
+	queries.templates = templates
+	return nil
+}
+
+func renderQuery(t *template.Template, vars QueryVars) (string, error) {
+	vars.IPVersion = IPVersion
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// channelFilter returns the filter expression restricting
+// the routes to the IP version, if any.
+func channelFilter() string {
+	if getBirdVersion() < 2 || ClientConf.Dualstack || queries.noChannelFilter {
+		return ""
+	}
+	t := queries.channelFilter
+	if t == nil {
+		t = template.Must(template.New("channel_filter").Parse(defaultChannelFilter))
+	}
+	filter, err := renderQuery(t, QueryVars{})
+	if err != nil {
+		log.Println("Rendering the channel filter failed:", err)
+		return "net.type = NET_IP" + IPVersion
+	}
+	return filter
+}
+
+// queryCommand renders the configured template of the
+// query. Without a template the default command is used.
+func queryCommand(name string, vars QueryVars, cmd string) string {
+	t, ok := queries.templates[name]
+	if !ok {
+		return cmd
+	}
+	rendered, err := renderQuery(t, vars)
+	if err != nil {
+		log.Println("Rendering the query template", name, "failed:", err)
+		return cmd
+	}
+	return rendered
+}
@@ -0,0 +1,65 @@
+package bird
+
+import (
+	"testing"
+)
+
+func TestQueryTemplates(t *testing.T) {
+	defer ConfigureQueries(QueriesConfig{})
+	defer func(v int, ipVersion string) { BirdVersion, IPVersion = v, ipVersion }(BirdVersion, IPVersion)
+	BirdVersion, IPVersion = 2, "4"
+
+	if err := ConfigureQueries(QueriesConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if cmd := routesQuery("table 'T1' all"); cmd != "route table 'T1' all where net.type = NET_IP4" {
+		t.Error("Unexpected default query:", cmd)
+	}
+
+	err := ConfigureQueries(QueriesConfig{
+		NoChannelFilter: true,
+		Templates: map[string]string{
+			"RoutesTable": "route table '{{.Table}}' all filter peer_out_v{{.IPVersion}}",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd := routesQuery("table 'T1' all"); cmd != "route table 'T1' all" {
+		t.Error("Expected no channel filter, got:", cmd)
+	}
+	cmd := queryCommand("RoutesTable", QueryVars{Table: "T1"}, "default")
+	if cmd != "route table 'T1' all filter peer_out_v4" {
+		t.Error("Unexpected templated query:", cmd)
+	}
+	if cmd := queryCommand("RoutesProto", QueryVars{}, "default"); cmd != "default" {
+		t.Error("Expected the default query, got:", cmd)
+	}
+
+	if err := ConfigureQueries(QueriesConfig{Templates: map[string]string{"RoutesTable": "{{.Table"}}); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+}
+
+func TestDefaultTable(t *testing.T) {
+	defer func(conf BirdConfig) { ClientConf = conf }(ClientConf)
+
+	ClientConf.DefaultTable = ""
+	if filter := defaultTable("all protocol 'R1'"); filter != "all protocol 'R1'" {
+		t.Error("Expected an unchanged filter, got:", filter)
+	}
+	if table := remapTable("T1"); table != "T1" {
+		t.Error("Expected the table to be kept, got:", table)
+	}
+
+	ClientConf.DefaultTable = "custom4"
+	if filter := defaultTable("all protocol 'R1'"); filter != "all protocol 'R1' table 'custom4'" {
+		t.Error("Unexpected filter:", filter)
+	}
+	if table := remapTable("master"); table != "custom4" {
+		t.Error("Expected the default table, got:", table)
+	}
+	if table := remapTable("T1"); table != "T1" {
+		t.Error("Expected the table to be kept, got:", table)
+	}
+}
@@ -0,0 +1,74 @@
+package bird
+
+import (
+	"log"
+	"time"
+)
+
+// lastReconfig reads the reconfiguration timestamp from
+// the reconfig_timestamp_source, bypassing the cache.
+func lastReconfig() (string, error) {
+	switch StatusConf.ReconfigTimestampSource {
+	case "config_modified":
+		return lastReconfigTimestampFromFileStat(ClientConf.ConfigFilename), nil
+	case "config_regex":
+		return lastReconfigTimestampFromFileContent(
+			ClientConf.ConfigFilename,
+			StatusConf.ReconfigTimestampMatch,
+		), nil
+	}
+
+	out, err := Run("status")
+	if err != nil {
+		return "", err
+	}
+	parsed, err := parse("status", parseStatus, out)
+	if err != nil {
+		return "", err
+	}
+	status, _ := parsed["status"].(Parsed)
+	timestamp, _ := status["last_reconfig"].(string)
+	return timestamp, nil
+}
+
+// A reconfigWatch remembers the last seen
+// reconfiguration timestamp.
+type reconfigWatch struct {
+	last string
+}
+
+// check reads the reconfiguration timestamp and
+// reports if it changed since the last check.
+func (w *reconfigWatch) check() bool {
+	timestamp, err := lastReconfig()
+	if err != nil {
+		return false // Keep the last timestamp while BIRD is unavailable
+	}
+
+	changed := w.last != "" && timestamp != w.last
+	w.last = timestamp
+	return changed
+}
+
+// WatchReconfig polls the reconfiguration timestamp and
+// flushes the cache when BIRD was reconfigured. The cache
+// is warmed again with the configured rewarm queries.
+func WatchReconfig() {
+	interval := time.Duration(CacheConf.ReconfigInterval) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	go func() {
+		w := &reconfigWatch{}
+		w.check()
+		for range time.Tick(interval) {
+			if w.check() {
+				InvalidateBirdVersion()
+				count := FlushCache()
+				log.Println("Reconfiguration detected, flushed", count, "cached results")
+				rewarmAfterFlush()
+			}
+		}
+	}()
+}
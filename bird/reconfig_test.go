@@ -0,0 +1,65 @@
+package bird
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReconfigWatch(t *testing.T) {
+	file, err := ioutil.TempFile("", "bird.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.Close()
+
+	defer func(conf BirdConfig, status StatusConfig) {
+		ClientConf = conf
+		StatusConf = status
+	}(ClientConf, StatusConf)
+	ClientConf.ConfigFilename = file.Name()
+	StatusConf.ReconfigTimestampSource = "config_modified"
+
+	w := &reconfigWatch{}
+	if w.check() {
+		t.Error("The first check should not report a change")
+	}
+	if w.check() {
+		t.Error("Unchanged config reported as reconfigured")
+	}
+
+	modified := time.Now().Add(time.Hour)
+	if err := os.Chtimes(file.Name(), modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	if !w.check() {
+		t.Error("Expected the modified config to be detected")
+	}
+}
+
+func TestRewarmCache(t *testing.T) {
+	defer withFixtures(t, map[string]string{
+		"protocols_all": readSample(t, "protocols_bgp_pipe.sample"),
+	})()
+	defer func(conf CacheConfig) { CacheConf = conf }(CacheConf)
+
+	if err := CheckRewarmQueries([]string{"protocols", "routes_unknown"}); err == nil {
+		t.Error("Expected an error for an unknown query")
+	}
+
+	// The rewarm is not rate limited
+	RateLimitConf.Conf = RateLimitConfig{Enabled: true}
+	defer func() { RateLimitConf.Conf = RateLimitConfig{} }()
+
+	CacheConf.Rewarm = []string{"protocols", "routes_count", "routes_filtered"}
+	CacheConf.RewarmConcurrency = 2
+	if count := RewarmCache(); count != 3 {
+		t.Error("Expected 3 queries for one established session, got:", count)
+	}
+	if _, fromCache, _ := Protocols(context.Background(), true); !fromCache {
+		t.Error("Expected the protocols to be cached")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -13,6 +14,11 @@ import (
 type RedisCache struct {
 	client    *redis.Client
 	keyPrefix string
+
+	// Keys set by this instance, as the
+	// database may be shared.
+	keysMu sync.Mutex
+	keys   map[string]struct{}
 }
 
 func NewRedisCache(config CacheConfig) (*RedisCache, error) {
@@ -31,6 +37,7 @@ func NewRedisCache(config CacheConfig) (*RedisCache, error) {
 
 	cache := &RedisCache{
 		client: client,
+		keys:   make(map[string]struct{}),
 	}
 
 	return cache, nil
@@ -43,19 +50,26 @@ func (self *RedisCache) Get(key string) (Parsed, error) {
 	key = self.keyPrefix + key //"B" + IPVersion + "_" + key
 	data, err := self.client.Get(ctx, key).Result()
 	if err != nil {
-		return NilParse, err
+		return nil, err
 	}
 
-	parsed := Parsed{}
-	err = json.Unmarshal([]byte(data), &parsed)
+	// The result is decoded for every hit, count it
+	// like a running parse.
+	release := trackInFlight(len(data))
+	defer release()
+
+	parsed, err := decodeParsed([]byte(data))
+	if err != nil {
+		return nil, err
+	}
 
 	ttl, err := parseCacheTTL(parsed["ttl"])
 	if err != nil {
-		return NilParse, fmt.Errorf("invalid TTL value for key: %s", key)
+		return nil, fmt.Errorf("invalid TTL value for key: %s", key)
 	}
 	// Deal with the inband TTL if present
 	if !ttl.Equal(time.Time{}) && ttl.Before(time.Now()) {
-		return NilParse, err // TTL expired
+		return nil, err // TTL expired
 	}
 
 	return parsed, err // cache hit
@@ -78,6 +92,11 @@ func (self *RedisCache) Set(key string, parsed Parsed, ttl int) error {
 		ctx := context.Background()
 		_, err = self.client.Set(
 			ctx, key, payload, time.Duration(ttl)*time.Minute).Result()
+		if err == nil {
+			self.keysMu.Lock()
+			self.keys[key] = struct{}{}
+			self.keysMu.Unlock()
+		}
 		return err
 
 	default: // ttl negative - invalid
@@ -90,9 +109,72 @@ func (self *RedisCache) Expire() int {
 	return 0
 }
 
+// Flush deletes all keys set by this instance.
+func (self *RedisCache) Flush() int {
+	self.keysMu.Lock()
+	keys := make([]string, 0, len(self.keys))
+	for key := range self.keys {
+		keys = append(keys, key)
+	}
+	self.keys = make(map[string]struct{})
+	self.keysMu.Unlock()
+
+	if len(keys) == 0 {
+		return 0
+	}
+
+	ctx := context.Background()
+	count, err := self.client.Del(ctx, keys...).Result()
+	if err != nil {
+		log.Println("Flushing RedisCache failed:", err)
+	}
+	return int(count)
+}
+
 // Helperfunction to decode the cache ttl stored
 // in the cache - which will most likely just be
 // RFC3339 timestamp.
+// decodeParsed decodes a cached result. The objects are
+// restored as Parsed and the routes as []Parsed, like
+// the results of a query, as the endpoints rely on them.
+func decodeParsed(data []byte) (Parsed, error) {
+	decoded := map[string]interface{}{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return restoreParsed(decoded), nil
+}
+
+func restoreParsed(object map[string]interface{}) Parsed {
+	parsed := make(Parsed, len(object))
+	for key, value := range object {
+		if list, ok := value.([]interface{}); ok && key == "routes" {
+			routes := make([]Parsed, 0, len(list))
+			for _, route := range list {
+				if route, ok := route.(map[string]interface{}); ok {
+					routes = append(routes, restoreParsed(route))
+				}
+			}
+			parsed[key] = routes
+			continue
+		}
+		parsed[key] = restoreValue(value)
+	}
+	return parsed
+}
+
+func restoreValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return restoreParsed(v)
+	case []interface{}:
+		for i, item := range v {
+			v[i] = restoreValue(item)
+		}
+	}
+	return value
+}
+
 func parseCacheTTL(cacheTTL interface{}) (time.Time, error) {
 	if cacheTTL == nil {
 		// We preseve the nil value as a zero value
@@ -1,6 +1,8 @@
 package bird
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 	"time"
 )
@@ -100,3 +102,45 @@ func Test_RedisCacheRoutes(t *testing.T) {
 
 	t.Log("Retrieved routes:", len(routes))
 }
+
+func TestDecodeParsed(t *testing.T) {
+	defer withFixtures(t, map[string]string{
+		"route_for_1.2.3.16": "BIRD 1.6.3 ready.\n" +
+			"1.2.3.0/24         via 10.0.0.2 on eno8 [ospf1 2017-06-21 08:17:33] * (150/20) [10.0.0.2]\n" +
+			"\tType: OSPF unicast univ\n",
+	})()
+
+	data, err := json.Marshal(Parsed{
+		"routes": []Parsed{
+			{"network": "16.0.0.0/24", "bgp": Parsed{"next_hop": "1.2.3.16"}},
+		},
+		"cached_at": time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A result from the redis cache is handled like a query result
+	res, err := decodeParsed(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := CachedAt(res); !ok {
+		t.Error("Expected the cached_at timestamp, got:", res["cached_at"])
+	}
+	resolved, _ := ResolveNexthops(context.Background(), false, res)
+	routes, ok := resolved["routes"].([]Parsed)
+	if !ok || len(routes) != 1 {
+		t.Fatal("Expected the routes, got:", resolved["routes"])
+	}
+	if nexthop, ok := routes[0]["resolved_nexthop"].(Parsed); !ok || nexthop["gateway"] != "10.0.0.2" {
+		t.Error("Expected the next hop to be resolved, got:", routes[0])
+	}
+
+	data, _ = json.Marshal(Parsed{"routes": []Parsed{}})
+	if res, _ := decodeParsed(data); res["routes"] == nil {
+		t.Error("Expected empty routes to be restored")
+	} else if _, ok := res["routes"].([]Parsed); !ok {
+		t.Error("Expected empty routes as []Parsed, got:", res["routes"])
+	}
+}
@@ -0,0 +1,29 @@
+package bird
+
+import (
+	"context"
+	"log"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of the context carrying the
+// ID of the HTTP request, which queries include in their logs.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of the context or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// logQuery logs a line of a query, prefixed with the
+// ID of the request running it if there is one.
+func logQuery(ctx context.Context, v ...interface{}) {
+	if id := RequestID(ctx); id != "" {
+		v = append([]interface{}{"Request", id}, v...)
+	}
+	log.Println(v...)
+}
@@ -0,0 +1,113 @@
+package bird
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Queries run for the whole instance
+var rewarmQueries = map[string]func(ctx context.Context){
+	"status":          func(ctx context.Context) { Status(ctx, true) },
+	"protocols":       func(ctx context.Context) { Protocols(ctx, true) },
+	"protocols_bgp":   func(ctx context.Context) { ProtocolsBgp(ctx, true) },
+	"protocols_short": func(ctx context.Context) { ProtocolsShort(ctx, true) },
+}
+
+// Queries run for every BGP session
+var rewarmProtocolQueries = map[string]func(ctx context.Context, protocol string){
+	"routes_count":    func(ctx context.Context, protocol string) { RoutesProtoCount(ctx, true, protocol) },
+	"routes_protocol": func(ctx context.Context, protocol string) { RoutesProto(ctx, true, protocol) },
+	"routes_filtered": func(ctx context.Context, protocol string) { RoutesFiltered(ctx, true, protocol) },
+}
+
+// CheckRewarmQueries validates the names of the cache rewarm queries
+func CheckRewarmQueries(names []string) error {
+	for _, name := range names {
+		_, ok := rewarmQueries[name]
+		_, protocolOk := rewarmProtocolQueries[name]
+		if !ok && !protocolOk {
+			return fmt.Errorf("Unknown rewarm query: %s", name)
+		}
+	}
+	return nil
+}
+
+// rewarmSessions returns the names of the BGP sessions, which are up
+func rewarmSessions(ctx context.Context) []string {
+	res, _, _ := Protocols(ctx, true)
+	protocols, _ := res["protocols"].(Parsed)
+
+	sessions := []string{}
+	for name, p := range protocols {
+		protocol, ok := p.(Parsed)
+		if !ok || protocol["bird_protocol"] != "BGP" || protocol["state"] != "up" {
+			continue
+		}
+		sessions = append(sessions, name)
+	}
+	sort.Strings(sessions)
+	return sessions
+}
+
+// RewarmCache runs the configured hot queries after the cache
+// was flushed, with at most rewarm_concurrency at once. The
+// queries are not rate limited, so they do not take the tokens
+// of API requests. It returns the number of queries.
+func RewarmCache() int {
+	ctx := withoutRateLimit(context.Background())
+	jobs := []func(){}
+	var sessions []string
+	for _, name := range CacheConf.Rewarm {
+		if query, ok := rewarmQueries[name]; ok {
+			query := query
+			jobs = append(jobs, func() { query(ctx) })
+			continue
+		}
+		query, ok := rewarmProtocolQueries[name]
+		if !ok {
+			continue
+		}
+		if sessions == nil {
+			sessions = rewarmSessions(ctx)
+		}
+		for _, protocol := range sessions {
+			protocol := protocol
+			jobs = append(jobs, func() { query(ctx, protocol) })
+		}
+	}
+
+	concurrency := CacheConf.RewarmConcurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	slots := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for _, job := range jobs {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(job func()) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			job()
+		}(job)
+	}
+	wg.Wait()
+
+	return len(jobs)
+}
+
+// rewarmAfterFlush logs the rewarm of the cache
+func rewarmAfterFlush() {
+	if len(CacheConf.Rewarm) == 0 {
+		return
+	}
+	start := time.Now()
+	count := RewarmCache()
+	log.Println("Rewarmed the cache with", count, "queries in", time.Since(start))
+}
@@ -0,0 +1,83 @@
+package bird
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Protocol states for filtering. Passive sessions are
+// in the start state with "Passive" as info.
+var protocolStates = map[string]bool{
+	"up":      true,
+	"down":    true,
+	"start":   true,
+	"passive": true,
+}
+
+// ParseProtocolStates parses a comma separated list of
+// protocol states. An empty value selects all protocols.
+func ParseProtocolStates(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	states := []string{}
+	for _, state := range strings.Split(value, ",") {
+		state = strings.ToLower(strings.TrimSpace(state))
+		if !protocolStates[state] {
+			return nil, fmt.Errorf("Invalid protocol state %s, use up, down, start or passive", state)
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// protocolInState checks the state of a protocol from
+// "show protocols" or "show protocols all".
+func protocolInState(protocol Parsed, states []string) bool {
+	state, _ := protocol["state"].(string)
+	info, ok := protocol["info"].(string)
+	if !ok {
+		info, _ = protocol["connection"].(string)
+	}
+	passive := strings.HasPrefix(strings.ToLower(info), "passive")
+
+	for _, s := range states {
+		if s == "passive" && passive {
+			return true
+		}
+		if strings.EqualFold(state, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterProtocolsState returns a copy of the result with
+// only the protocols in one of the states.
+func FilterProtocolsState(res Parsed, states []string) Parsed {
+	result := Parsed{}
+	for k, v := range res {
+		result[k] = v
+	}
+
+	switch protocols := res["protocols"].(type) {
+	case Parsed:
+		filtered := Parsed{}
+		for name, p := range protocols {
+			if protocol, ok := p.(Parsed); ok && protocolInState(protocol, states) {
+				filtered[name] = protocol
+			}
+		}
+		result["protocols"] = filtered
+	case []Parsed:
+		filtered := []Parsed{}
+		for _, protocol := range protocols {
+			if protocolInState(protocol, states) {
+				filtered = append(filtered, protocol)
+			}
+		}
+		result["protocols"] = filtered
+	}
+	return result
+}
@@ -0,0 +1,42 @@
+package bird
+
+import (
+	"testing"
+)
+
+func TestFilterProtocolsState(t *testing.T) {
+	if _, err := ParseProtocolStates("up,established"); err == nil {
+		t.Error("Expected an error for an unknown state")
+	}
+	states, err := ParseProtocolStates("Down, passive")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := Parsed{
+		"protocols": Parsed{
+			"R1": Parsed{"state": "up", "connection": "Established"},
+			"R2": Parsed{"state": "down", "connection": ""},
+			"R3": Parsed{"state": "start", "connection": "Passive"},
+			"R4": Parsed{"state": "start", "connection": "Active"},
+		},
+		"ttl": "ttl",
+	}
+	filtered := FilterProtocolsState(res, states)
+	protocols := filtered["protocols"].(Parsed)
+	if len(protocols) != 2 || protocols["R2"] == nil || protocols["R3"] == nil {
+		t.Error("Expected R2 and R3, got:", protocols)
+	}
+	if filtered["ttl"] != "ttl" || len(res["protocols"].(Parsed)) != 4 {
+		t.Error("Expected a filtered copy of the result")
+	}
+
+	summary := Parsed{"protocols": []Parsed{
+		{"name": "R1", "state": "up", "info": "Established"},
+		{"name": "R3", "state": "start", "info": "Passive"},
+	}}
+	rows := FilterProtocolsState(summary, []string{"up"})["protocols"].([]Parsed)
+	if len(rows) != 1 || rows[0]["name"] != "R1" {
+		t.Error("Expected R1, got:", rows)
+	}
+}
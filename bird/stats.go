@@ -0,0 +1,322 @@
+package bird
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A GatewayCount is the number of routes via a next hop
+type GatewayCount struct {
+	Gateway string  `json:"gateway"`
+	Routes  int     `json:"routes"`
+	Primary int     `json:"primary"`
+	Share   float64 `json:"share"`
+}
+
+// routeStats copies the cache status of the routes
+// to the computed statistics.
+func routeStats(res Parsed, stats Parsed) Parsed {
+	for _, key := range []string{"ttl", "cached_at"} {
+		if v, ok := res[key]; ok {
+			stats[key] = v
+		}
+	}
+	return stats
+}
+
+// gatewayCounts groups the routes by next hop,
+// the gateway with the most routes first.
+func gatewayCounts(routes []Parsed) []GatewayCount {
+	byGateway := map[string]*GatewayCount{}
+	for _, route := range routes {
+		gateway, _ := route["gateway"].(string)
+		count, ok := byGateway[gateway]
+		if !ok {
+			count = &GatewayCount{Gateway: gateway}
+			byGateway[gateway] = count
+		}
+		count.Routes++
+		if route["primary"] == true {
+			count.Primary++
+		}
+	}
+
+	counts := make([]GatewayCount, 0, len(byGateway))
+	for _, count := range byGateway {
+		count.Share = float64(count.Routes) / float64(len(routes))
+		counts = append(counts, *count)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Routes != counts[j].Routes {
+			return counts[i].Routes > counts[j].Routes
+		}
+		return counts[i].Gateway < counts[j].Gateway
+	})
+	return counts
+}
+
+// RoutesGatewayStats returns the route counts by next hop
+func RoutesGatewayStats(res Parsed, fromCache bool, err error) (Parsed, bool, error) {
+	if err != nil {
+		return nil, false, err // Pass errors through
+	}
+	routes, _ := res["routes"].([]Parsed)
+	return routeStats(res, Parsed{
+		"total":    len(routes),
+		"gateways": gatewayCounts(routes),
+	}), fromCache, nil
+}
+
+// A PrefixLengthCount is the number of routes and distinct
+// prefixes of an address family with a prefix length
+type PrefixLengthCount struct {
+	Family   string `json:"family"`
+	Length   int    `json:"length"`
+	Routes   int    `json:"routes"`
+	Prefixes int    `json:"prefixes"`
+}
+
+// prefixLengthCounts builds the histogram of prefix lengths,
+// ordered by family and length. Invalid prefixes are skipped.
+func prefixLengthCounts(routes []Parsed) []PrefixLengthCount {
+	type bucket struct {
+		family string
+		length int
+	}
+	counts := map[bucket]*PrefixLengthCount{}
+	seen := map[string]bool{}
+	for _, route := range routes {
+		network, _ := route["network"].(string)
+		_, prefix, err := net.ParseCIDR(network)
+		if err != nil {
+			continue
+		}
+		length, bits := prefix.Mask.Size()
+		family := "ipv6"
+		if bits == 32 {
+			family = "ipv4"
+		}
+
+		b := bucket{family, length}
+		count, ok := counts[b]
+		if !ok {
+			count = &PrefixLengthCount{Family: family, Length: length}
+			counts[b] = count
+		}
+		count.Routes++
+		if !seen[network] {
+			seen[network] = true
+			count.Prefixes++
+		}
+	}
+
+	histogram := make([]PrefixLengthCount, 0, len(counts))
+	for _, count := range counts {
+		histogram = append(histogram, *count)
+	}
+	sort.Slice(histogram, func(i, j int) bool {
+		if histogram[i].Family != histogram[j].Family {
+			return histogram[i].Family < histogram[j].Family
+		}
+		return histogram[i].Length < histogram[j].Length
+	})
+	return histogram
+}
+
+// RoutesPrefixLengthStats returns the histogram of prefix lengths
+func RoutesPrefixLengthStats(res Parsed, fromCache bool, err error) (Parsed, bool, error) {
+	if err != nil {
+		return nil, false, err // Pass errors through
+	}
+	routes, _ := res["routes"].([]Parsed)
+	return routeStats(res, Parsed{
+		"total":          len(routes),
+		"prefix_lengths": prefixLengthCounts(routes),
+	}), fromCache, nil
+}
+
+// An OriginCount is the number of routes originated by an AS
+type OriginCount struct {
+	ASN    uint32  `json:"asn"`
+	Routes int     `json:"routes"`
+	Share  float64 `json:"share"`
+}
+
+// RouteOrigin returns the last AS of the path of a route.
+// Locally originated routes and AS sets have no origin.
+func RouteOrigin(route Parsed) (uint32, bool) {
+	bgp, ok := route["bgp"].(Parsed)
+	if !ok {
+		return 0, false
+	}
+	path, ok := bgp["as_path"].([]string)
+	if !ok || len(path) == 0 {
+		return 0, false
+	}
+	last := path[len(path)-1]
+	if strings.ContainsAny(last, "{}") {
+		return 0, false
+	}
+	asn, err := ParseASN(last)
+	return asn, err == nil
+}
+
+// originCounts groups the routes by origin AS, the origin
+// with the most routes first. Routes without an origin are
+// counted separately.
+func originCounts(routes []Parsed) ([]OriginCount, int) {
+	byOrigin := map[uint32]*OriginCount{}
+	unknown := 0
+	for _, route := range routes {
+		asn, ok := RouteOrigin(route)
+		if !ok {
+			unknown++
+			continue
+		}
+		count, ok := byOrigin[asn]
+		if !ok {
+			count = &OriginCount{ASN: asn}
+			byOrigin[asn] = count
+		}
+		count.Routes++
+	}
+
+	counts := make([]OriginCount, 0, len(byOrigin))
+	for _, count := range byOrigin {
+		count.Share = float64(count.Routes) / float64(len(routes))
+		counts = append(counts, *count)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Routes != counts[j].Routes {
+			return counts[i].Routes > counts[j].Routes
+		}
+		return counts[i].ASN < counts[j].ASN
+	})
+	return counts, unknown
+}
+
+// RoutesOriginStats returns the route counts of the top
+// origin ASNs, the remaining origins are summed up in others.
+func RoutesOriginStats(res Parsed, fromCache bool, err error, top int) (Parsed, bool, error) {
+	if err != nil {
+		return nil, false, err // Pass errors through
+	}
+	routes, _ := res["routes"].([]Parsed)
+
+	counts, unknown := originCounts(routes)
+	others := Parsed{"origins": 0, "routes": 0}
+	if len(counts) > top {
+		sum := 0
+		for _, count := range counts[top:] {
+			sum += count.Routes
+		}
+		others = Parsed{"origins": len(counts) - top, "routes": sum}
+		counts = counts[:top]
+	}
+
+	return routeStats(res, Parsed{
+		"total":     len(routes),
+		"origins":   counts,
+		"others":    others,
+		"no_origin": unknown,
+	}), fromCache, nil
+}
+
+// A CommunityCount is the number of routes carrying a community
+type CommunityCount struct {
+	Community string `json:"community"`
+	Type      string `json:"type"`
+	Routes    int    `json:"routes"`
+}
+
+// routeCommunities returns the communities of a route
+// formatted like BIRD by their kind.
+func routeCommunities(route Parsed) map[string][]string {
+	bgp, ok := route["bgp"].(Parsed)
+	if !ok {
+		return nil
+	}
+	communities := map[string][]string{}
+	for kind, key := range map[string]string{
+		"standard": "communities",
+		"large":    "large_communities",
+	} {
+		values, _ := bgp[key].([][]int64)
+		for _, value := range values {
+			parts := make([]string, len(value))
+			for i, v := range value {
+				parts[i] = strconv.FormatInt(v, 10)
+			}
+			communities[kind] = append(communities[kind], strings.Join(parts, ":"))
+		}
+	}
+	values, _ := bgp["ext_communities"].([]interface{})
+	for _, value := range values {
+		parts, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+		community := make([]string, len(parts))
+		for i, part := range parts {
+			community[i] = fmt.Sprintf("%v", part)
+		}
+		communities["extended"] = append(communities["extended"], strings.Join(community, ":"))
+	}
+	return communities
+}
+
+// communityCounts counts the routes carrying each community,
+// the most used community first.
+func communityCounts(routes []Parsed) []CommunityCount {
+	byCommunity := map[[2]string]*CommunityCount{}
+	for _, route := range routes {
+		for kind, communities := range routeCommunities(route) {
+			seen := map[string]bool{}
+			for _, community := range communities {
+				if seen[community] {
+					continue
+				}
+				seen[community] = true
+
+				key := [2]string{kind, community}
+				count, ok := byCommunity[key]
+				if !ok {
+					count = &CommunityCount{Community: community, Type: kind}
+					byCommunity[key] = count
+				}
+				count.Routes++
+			}
+		}
+	}
+
+	counts := make([]CommunityCount, 0, len(byCommunity))
+	for _, count := range byCommunity {
+		counts = append(counts, *count)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Routes != counts[j].Routes {
+			return counts[i].Routes > counts[j].Routes
+		}
+		if counts[i].Type != counts[j].Type {
+			return counts[i].Type < counts[j].Type
+		}
+		return counts[i].Community < counts[j].Community
+	})
+	return counts
+}
+
+// RoutesCommunityStats returns the number of routes
+// carrying each (large, extended) community
+func RoutesCommunityStats(res Parsed, fromCache bool, err error) (Parsed, bool, error) {
+	if err != nil {
+		return nil, false, err // Pass errors through
+	}
+	routes, _ := res["routes"].([]Parsed)
+	return routeStats(res, Parsed{
+		"total":       len(routes),
+		"communities": communityCounts(routes),
+	}), fromCache, nil
+}
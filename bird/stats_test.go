@@ -0,0 +1,122 @@
+package bird
+
+import (
+	"testing"
+)
+
+func TestRoutesGatewayStats(t *testing.T) {
+	res := Parsed{
+		"routes": []Parsed{
+			{"network": "10.0.0.0/8", "gateway": "192.0.2.1", "primary": true},
+			{"network": "10.0.0.0/8", "gateway": "192.0.2.2", "primary": false},
+			{"network": "10.1.0.0/16", "gateway": "192.0.2.2", "primary": true},
+			{"network": "10.2.0.0/16", "gateway": "192.0.2.2", "primary": true},
+		},
+		"ttl": "ttl",
+	}
+
+	stats, _, _ := RoutesGatewayStats(res, true, nil)
+	gateways := stats["gateways"].([]GatewayCount)
+	if stats["total"] != 4 || stats["ttl"] != "ttl" || len(gateways) != 2 {
+		t.Fatal("Unexpected stats:", stats)
+	}
+	if g := gateways[0]; g.Gateway != "192.0.2.2" || g.Routes != 3 || g.Primary != 2 || g.Share != 0.75 {
+		t.Error("Unexpected first gateway:", g)
+	}
+
+	if _, _, err := RoutesGatewayStats(nil, false, ErrTimeout); err != ErrTimeout {
+		t.Error("Expected the error to be passed through")
+	}
+}
+
+func TestRoutesPrefixLengthStats(t *testing.T) {
+	res := Parsed{
+		"routes": []Parsed{
+			{"network": "10.0.0.0/8"},
+			{"network": "10.0.0.0/8"},
+			{"network": "10.1.0.0/24"},
+			{"network": "10.2.0.0/24"},
+			{"network": "2001:db8::/32"},
+			{"network": "invalid"},
+		},
+	}
+
+	stats, _, _ := RoutesPrefixLengthStats(res, false, nil)
+	expected := []PrefixLengthCount{
+		{Family: "ipv4", Length: 8, Routes: 2, Prefixes: 1},
+		{Family: "ipv4", Length: 24, Routes: 2, Prefixes: 2},
+		{Family: "ipv6", Length: 32, Routes: 1, Prefixes: 1},
+	}
+	histogram := stats["prefix_lengths"].([]PrefixLengthCount)
+	if len(histogram) != len(expected) {
+		t.Fatal("Expected", expected, "got:", histogram)
+	}
+	for i, count := range expected {
+		if histogram[i] != count {
+			t.Error("Expected", count, "got:", histogram[i])
+		}
+	}
+}
+
+func TestRoutesOriginStats(t *testing.T) {
+	path := func(asns ...string) Parsed {
+		return Parsed{"bgp": Parsed{"as_path": asns}}
+	}
+	res := Parsed{
+		"routes": []Parsed{
+			path("3356", "65001"),
+			path("3356", "65001"),
+			path("3356", "65002"),
+			path("65003"),
+			path("65004", "{65005", "65006}"),
+			path(),
+			{"network": "10.0.0.0/8"},
+		},
+	}
+
+	stats, _, _ := RoutesOriginStats(res, false, nil, 2)
+	origins := stats["origins"].([]OriginCount)
+	if len(origins) != 2 || origins[0].ASN != 65001 || origins[0].Routes != 2 || origins[1].ASN != 65002 {
+		t.Error("Unexpected origins:", origins)
+	}
+	others := stats["others"].(Parsed)
+	if others["origins"] != 1 || others["routes"] != 1 {
+		t.Error("Unexpected others:", others)
+	}
+	if stats["no_origin"] != 3 {
+		t.Error("Expected 3 routes without origin, got:", stats["no_origin"])
+	}
+}
+
+func TestRoutesCommunityStats(t *testing.T) {
+	res := Parsed{
+		"routes": []Parsed{
+			{"bgp": Parsed{
+				"communities":       [][]int64{{65000, 1}, {65000, 2}, {65000, 1}},
+				"large_communities": [][]int64{{65000, 0, 1}},
+			}},
+			{"bgp": Parsed{
+				"communities":     [][]int64{{65000, 1}},
+				"ext_communities": []interface{}{[]interface{}{"rt", "65000", "10"}},
+			}},
+			{"network": "10.0.0.0/8"},
+		},
+	}
+
+	stats, _, _ := RoutesCommunityStats(res, false, nil)
+	expected := []CommunityCount{
+		{Community: "65000:1", Type: "standard", Routes: 2},
+		{Community: "rt:65000:10", Type: "extended", Routes: 1},
+		{Community: "65000:0:1", Type: "large", Routes: 1},
+		{Community: "65000:2", Type: "standard", Routes: 1},
+	}
+	counts := stats["communities"].([]CommunityCount)
+	if len(counts) != len(expected) {
+		t.Fatal("Expected", expected, "got:", counts)
+	}
+	for i, count := range expected {
+		if counts[i] != count {
+			t.Error("Expected", count, "got:", counts[i])
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package bird
+
+import (
+	"context"
+	"sort"
+)
+
+// protocolsSummary turns the parsed "show protocols"
+// into rows, ordered by name.
+func protocolsSummary(protocols Parsed) []Parsed {
+	names := make([]string, 0, len(protocols))
+	for name := range protocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rows := make([]Parsed, 0, len(names))
+	for _, name := range names {
+		protocol, ok := protocols[name].(Parsed)
+		if !ok {
+			continue
+		}
+		rows = append(rows, Parsed{
+			"name":  name,
+			"proto": protocol["proto"],
+			"table": protocol["table"],
+			"state": protocol["state"],
+			"since": protocol["since"],
+			"info":  protocol["info"],
+		})
+	}
+	return rows
+}
+
+// ProtocolsSummary lists all protocols from the plain
+// "show protocols", which is much cheaper than "protocols all".
+func ProtocolsSummary(ctx context.Context, useCache bool) (Parsed, bool, error) {
+	res, fromCache, err := ProtocolsShort(ctx, useCache)
+	if err != nil {
+		return nil, false, err
+	}
+	protocols, _ := res["protocols"].(Parsed)
+
+	summary := Parsed{"protocols": protocolsSummary(protocols)}
+	// Keep the cache status of the protocols
+	for _, key := range []string{"ttl", "cached_at"} {
+		if v, ok := res[key]; ok {
+			summary[key] = v
+		}
+	}
+	return summary, fromCache, nil
+}
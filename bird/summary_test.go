@@ -0,0 +1,31 @@
+package bird
+
+import (
+	"os"
+	"testing"
+)
+
+func TestProtocolsSummary(t *testing.T) {
+	f, err := os.Open("../test/protocols_short.sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	protocols := parseProtocolsShort(f)["protocols"].(Parsed)
+	rows := protocolsSummary(protocols)
+	if len(rows) != len(protocols) {
+		t.Fatal("Expected a row for each protocol, got:", len(rows))
+	}
+
+	for i, row := range rows {
+		if i > 0 && rows[i-1]["name"].(string) >= row["name"].(string) {
+			t.Error("Rows are not ordered by name:", rows[i-1]["name"], row["name"])
+		}
+	}
+
+	first := rows[0]
+	if first["name"] != "C112_112_ripe" || first["proto"] != "Pipe" || first["state"] != "up" {
+		t.Error("Unexpected row:", first)
+	}
+}
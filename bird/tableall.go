@@ -0,0 +1,83 @@
+package bird
+
+import (
+	"context"
+	"fmt"
+)
+
+// "show route table all" is only available in BIRD 2
+func checkTableAll() error {
+	if getBirdVersion() < 2 {
+		return Unsupported(fmt.Sprintf(
+			"Querying all tables requires BIRD 2, running BIRD %d", getBirdVersion()))
+	}
+	return nil
+}
+
+// RoutesAllTables returns the routes of every table in
+// one query. Each route has the table it belongs to.
+func RoutesAllTables(ctx context.Context, useCache bool) (Parsed, bool, error) {
+	if err := checkTableAll(); err != nil {
+		return nil, false, err
+	}
+	cmd := routesQuery("table all all")
+	cmd = queryCommand("RoutesAllTables", QueryVars{}, cmd)
+	return RunAndParse(
+		ctx,
+		useCache,
+		GetCacheKey("RoutesAllTables"),
+		cmd,
+		parseRoutes,
+		nil)
+}
+
+// RoutesAllTablesWhere returns the routes of every
+// table matching the filter expression.
+func RoutesAllTablesWhere(ctx context.Context, useCache bool, where string) (Parsed, bool, error) {
+	if err := checkTableAll(); err != nil {
+		return nil, false, err
+	}
+	cmd := routesQueryWhere("table all all", where)
+	cmd = queryCommand("RoutesAllTablesWhere", QueryVars{Where: where}, cmd)
+	return RunAndParse(
+		ctx,
+		useCache,
+		GetCacheKey("RoutesAllTablesWhere", where),
+		cmd,
+		parseRoutes,
+		nil)
+}
+
+// RoutesLookupAllTablesMatch looks up a prefix in
+// every table using the given match mode.
+func RoutesLookupAllTablesMatch(ctx context.Context, useCache bool, prefix string, mode string) (Parsed, bool, error) {
+	if err := checkTableAll(); err != nil {
+		return nil, false, err
+	}
+
+	var cmd string
+	switch mode {
+	case MatchExact:
+		cmd = routesQuery(prefix + " table all all")
+	case MatchCovering:
+		lookup, err := parseLookupPrefix(prefix)
+		if err != nil {
+			return nil, false, InvalidRequest(&FieldError{Field: "prefix", Message: err.Error()})
+		}
+		res, fromCache, err := RoutesAllTables(ctx, useCache)
+		if err != nil {
+			return nil, false, err
+		}
+		return filterCoveringRoutes(res, lookup), fromCache, nil
+	default:
+		cmd = routesQuery("for " + prefix + " table all all")
+	}
+
+	return RunAndParse(
+		ctx,
+		useCache,
+		GetCacheKey("RoutesLookupAllTables", prefix, mode),
+		cmd,
+		parseRoutes,
+		nil)
+}
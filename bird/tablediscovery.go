@@ -0,0 +1,131 @@
+package bird
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// A PeerTable is the discovered relation of a
+// peer protocol, its table and the pipe connecting
+// the table to the main table.
+type PeerTable struct {
+	Protocol    string `json:"protocol"`
+	Table       string `json:"table"`
+	TableExists bool   `json:"table_exists"`
+	Pipe        string `json:"pipe,omitempty"`
+	PipeTable   string `json:"pipe_table,omitempty"`
+}
+
+// discoverPeerTables infers the peer tables from the
+// protocols. Pipes are matched by their peer table.
+func discoverPeerTables(protocols Parsed, tables []string) []PeerTable {
+	known := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		known[table] = true
+	}
+
+	pipes := make(map[string]Parsed)
+	for _, p := range protocols {
+		protocol, ok := p.(Parsed)
+		if !ok || protocol["bird_protocol"] != "Pipe" {
+			continue
+		}
+		peerTable, _ := protocol["peer_table"].(string)
+		pipes[peerTable] = protocol
+	}
+
+	peers := []PeerTable{}
+	for name, p := range protocols {
+		protocol, ok := p.(Parsed)
+		if !ok || protocol["bird_protocol"] != "BGP" {
+			continue
+		}
+
+		table, _ := protocol["table"].(string)
+		peer := PeerTable{
+			Protocol:    name,
+			Table:       table,
+			TableExists: known[table],
+		}
+		if pipe, ok := pipes[table]; ok {
+			peer.Pipe, _ = pipe["protocol"].(string)
+			peer.PipeTable, _ = pipe["table"].(string)
+		}
+		peers = append(peers, peer)
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Protocol < peers[j].Protocol
+	})
+	return peers
+}
+
+// peerTableProblems compares the discovered peer
+// tables with the configured rules.
+func peerTableProblems(peers []PeerTable) []string {
+	problems := []string{}
+	for _, peer := range peers {
+		if !peer.TableExists {
+			problems = append(problems, fmt.Sprintf(
+				"table %s of %s is not a routing table", peer.Table, peer.Protocol))
+		}
+
+		pipe, table, ok := PeerPipeAndTable(peer.Protocol)
+		if !ok {
+			continue
+		}
+		if pipe != peer.Pipe || table != peer.Table {
+			problems = append(problems, fmt.Sprintf(
+				"rules map %s to pipe %s and table %s, discovered pipe %s and table %s",
+				peer.Protocol, pipe, table, peer.Pipe, peer.Table))
+		}
+	}
+	return problems
+}
+
+// DiscoverPeerTables infers the peer, pipe and table
+// relationships from the protocols and symbols.
+func DiscoverPeerTables(ctx context.Context, useCache bool) (Parsed, bool, error) {
+	res, fromCache, err := Protocols(ctx, useCache)
+	if err != nil {
+		return nil, false, err
+	}
+	protocols, _ := res["protocols"].(Parsed)
+
+	symbols, _, _ := Symbols(ctx, useCache)
+	tables := []string{}
+	if s, ok := symbols["symbols"].(Parsed); ok {
+		tables, _ = s["routing table"].([]string)
+	}
+
+	peers := discoverPeerTables(protocols, tables)
+	discovered := Parsed{
+		"peers":    peers,
+		"problems": peerTableProblems(peers),
+	}
+	// Keep the cache status of the protocols
+	for _, key := range []string{"ttl", "cached_at"} {
+		if v, ok := res[key]; ok {
+			discovered[key] = v
+		}
+	}
+	return discovered, fromCache, nil
+}
+
+// ResolvePeerPipeAndTable returns the pipe and table of a
+// peer using the configured rules or the discovered tables.
+func ResolvePeerPipeAndTable(ctx context.Context, useCache bool, protocol string) (string, string, bool) {
+	if pipe, table, ok := PeerPipeAndTable(protocol); ok {
+		return pipe, table, true
+	}
+
+	res, _, _ := DiscoverPeerTables(ctx, useCache)
+	peers, _ := res["peers"].([]PeerTable)
+	for _, peer := range peers {
+		if peer.Protocol == protocol && peer.Pipe != "" {
+			return peer.Pipe, peer.Table, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,112 @@
+package bird
+
+import (
+	"testing"
+)
+
+func TestDiscoverPeerTables(t *testing.T) {
+	f, err := openFile("protocols_bgp_pipe.sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	protocols := parseProtocols(f)["protocols"].(Parsed)
+	peers := discoverPeerTables(protocols, []string{"master", "T65001_nada_co_ripe"})
+	if len(peers) != 1 {
+		t.Fatal("Expected one peer, got:", peers)
+	}
+
+	peer := peers[0]
+	if peer.Protocol != "R194_42" || peer.Table != "T65001_nada_co_ripe" ||
+		peer.Pipe != "M65001_nada_co_ripe" || peer.PipeTable != "master" || !peer.TableExists {
+		t.Error("Unexpected peer table:", peer)
+	}
+
+	if problems := peerTableProblems(peers); len(problems) != 0 {
+		t.Error("Expected no problems, got:", problems)
+	}
+
+	ConfigurePeerTables(PeerTablesConfig{
+		Rules: []PeerTableRule{{Peer: `^R(\d+)_(\d+)$`, Pipe: "P${1}", Table: "T${1}"}},
+	})
+	defer ConfigurePeerTables(PeerTablesConfig{})
+
+	peers[0].TableExists = false
+	if problems := peerTableProblems(peers); len(problems) != 2 {
+		t.Error("Expected 2 problems, got:", problems)
+	}
+}
+
+func TestMergePeerTableRoutes(t *testing.T) {
+	peers := []PeerTable{
+		{Protocol: "R1", Table: "T1"},
+		{Protocol: "R2", Table: "T2"},
+	}
+	route := func(network, gateway string) Parsed {
+		return Parsed{
+			"network":       network,
+			"gateway":       gateway,
+			"from_protocol": "R1",
+			"bgp":           Parsed{"as_path": []string{"65001"}},
+			"age":           "2024-01-01",
+		}
+	}
+	routes := map[string][]Parsed{
+		"T1": {route("10.0.0.0/8", "192.0.2.1")},
+		"T2": {route("10.0.0.0/8", "192.0.2.1"), route("10.0.0.0/8", "192.0.2.2")},
+	}
+
+	merged := mergePeerTableRoutes(peers, routes)
+	if len(merged) != 2 {
+		t.Fatal("Expected 2 announcements, got:", merged)
+	}
+	if tables := merged[0]["tables"].([]string); len(tables) != 2 || merged[0]["peer"] != "R1" {
+		t.Error("Expected a deduplicated route of R1 in T1 and T2, got:", merged[0])
+	}
+	if merged[1]["table"] != "T2" || merged[1]["peer"] != "R2" {
+		t.Error("Unexpected source of the second announcement:", merged[1])
+	}
+	if _, ok := routes["T1"][0]["table"]; ok {
+		t.Error("The cached routes must not be modified")
+	}
+}
+
+func TestDedupeRoutes(t *testing.T) {
+	route := func(network, peer, table string, asPath ...string) Parsed {
+		return Parsed{
+			"network":       network,
+			"from_protocol": peer,
+			"table":         table,
+			"peer":          "P" + table,
+			"bgp":           Parsed{"as_path": asPath},
+		}
+	}
+	res := Parsed{
+		"ttl": "2024-01-01",
+		"routes": []Parsed{
+			route("10.0.0.0/8", "R1", "T1", "65001"),
+			route("10.0.0.0/8", "R1", "T2", "65001"),
+			route("10.0.0.0/8", "R2", "T1", "65001"),
+			route("10.0.0.0/8", "R3", "T1", "65003", "65001"),
+		},
+	}
+
+	deduped := DedupeRoutes(res)
+	routes := deduped["routes"].([]Parsed)
+	if len(routes) != 2 || deduped["ttl"] != res["ttl"] {
+		t.Fatal("Expected 2 unique routes, got:", deduped)
+	}
+	if peers := routes[0]["peers"].([]string); len(peers) != 2 || peers[1] != "R2" {
+		t.Error("Expected the peers R1 and R2, got:", peers)
+	}
+	if tables := routes[0]["tables"].([]string); len(tables) != 2 {
+		t.Error("Expected the tables T1 and T2, got:", tables)
+	}
+	if _, ok := routes[0]["peer"]; ok {
+		t.Error("Expected the peer of the first route to be replaced by peers")
+	}
+	if len(res["routes"].([]Parsed)) != 4 || res["routes"].([]Parsed)[0]["peers"] != nil {
+		t.Error("The cached result must not be modified")
+	}
+}
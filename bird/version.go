@@ -0,0 +1,77 @@
+package bird
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The detected major version is kept in BirdVersion
+// until BIRD was reconfigured.
+var birdVersion struct {
+	sync.Mutex
+	lastAttempt time.Time
+}
+
+// Failed detections are retried after this interval, so
+// route queries do not wait for a status call each time.
+const versionRetryInterval = 10 * time.Second
+
+func parseBirdVersion(version string) (int, bool) {
+	major := strings.SplitN(strings.TrimSpace(version), ".", 2)[0]
+	v, err := strconv.Atoi(major)
+	if err != nil || v <= 0 {
+		return 0, false
+	}
+	return v, true
+}
+
+// setBirdVersion remembers the version from a fresh status
+func setBirdVersion(status Parsed) {
+	version, _ := status["version"].(string)
+	v, ok := parseBirdVersion(version)
+	if !ok {
+		return
+	}
+	birdVersion.Lock()
+	BirdVersion = v
+	birdVersion.Unlock()
+}
+
+// InvalidateBirdVersion forgets the detected version,
+// e.g. when BIRD was reconfigured or restarted.
+func InvalidateBirdVersion() {
+	birdVersion.Lock()
+	BirdVersion = 0
+	birdVersion.lastAttempt = time.Time{}
+	birdVersion.Unlock()
+}
+
+// getBirdVersion returns the major version of BIRD or 0
+// if it is unknown. The version is detected from the
+// (cached) status once.
+func getBirdVersion() int {
+	birdVersion.Lock()
+	if BirdVersion != 0 {
+		defer birdVersion.Unlock()
+		return BirdVersion
+	}
+	if time.Since(birdVersion.lastAttempt) < versionRetryInterval {
+		birdVersion.Unlock()
+		return 0
+	}
+	birdVersion.lastAttempt = time.Now()
+	birdVersion.Unlock()
+
+	// A fresh status sets the version while parsing
+	status, _, _ := Status(context.Background(), true)
+	if birdStatus, ok := status["status"].(Parsed); ok {
+		setBirdVersion(birdStatus)
+	}
+
+	birdVersion.Lock()
+	defer birdVersion.Unlock()
+	return BirdVersion
+}
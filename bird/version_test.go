@@ -0,0 +1,28 @@
+package bird
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBirdVersion(t *testing.T) {
+	defer InvalidateBirdVersion()
+
+	for version, expected := range map[string]int{"2.0.7": 2, "1.6.8": 1, " 3.0 ": 3, "x": 0, "": 0} {
+		if v, _ := parseBirdVersion(version); v != expected {
+			t.Error("Expected", expected, "for", version, "got:", v)
+		}
+	}
+
+	setBirdVersion(Parsed{"version": "2.0.7"})
+	if v := getBirdVersion(); v != 2 {
+		t.Error("Expected version 2, got:", v)
+	}
+
+	// A failed detection is not retried immediately
+	InvalidateBirdVersion()
+	birdVersion.lastAttempt = time.Now()
+	if v := getBirdVersion(); v != 0 {
+		t.Error("Expected an unknown version, got:", v)
+	}
+}
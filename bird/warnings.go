@@ -0,0 +1,70 @@
+package bird
+
+import (
+	"fmt"
+)
+
+// At most this many distinct warnings are reported
+const maxWarnings = 50
+
+// parseWarnings collects non-fatal oddities while parsing,
+// e.g. unknown attributes or unparsable timestamps.
+type parseWarnings struct {
+	counts map[string]int
+	order  []string
+}
+
+func (w *parseWarnings) add(format string, args ...interface{}) {
+	if w.counts == nil {
+		w.counts = make(map[string]int)
+	}
+	msg := fmt.Sprintf(format, args...)
+	if _, ok := w.counts[msg]; !ok {
+		w.order = append(w.order, msg)
+	}
+	w.counts[msg]++
+}
+
+func (w *parseWarnings) merge(other parseWarnings) {
+	for _, msg := range other.order {
+		if _, ok := w.counts[msg]; !ok {
+			w.order = append(w.order, msg)
+		}
+		if w.counts == nil {
+			w.counts = make(map[string]int)
+		}
+		w.counts[msg] += other.counts[msg]
+	}
+}
+
+// list returns the warnings in the order of their
+// first occurrence with the number of repetitions.
+func (w *parseWarnings) list() []string {
+	warnings := []string{}
+	for i, msg := range w.order {
+		if i == maxWarnings {
+			warnings = append(warnings, fmt.Sprintf(
+				"... and %d more warnings", len(w.order)-maxWarnings))
+			break
+		}
+		if n := w.counts[msg]; n > 1 {
+			msg = fmt.Sprintf("%s (%d times)", msg, n)
+		}
+		warnings = append(warnings, msg)
+	}
+	return warnings
+}
+
+// attach adds the warnings to the parsed result, if any
+func (w *parseWarnings) attach(res Parsed) Parsed {
+	if len(w.order) > 0 {
+		res["warnings"] = w.list()
+	}
+	return res
+}
+
+// Warnings returns the parser warnings of a result
+func Warnings(res Parsed) []string {
+	warnings, _ := res["warnings"].([]string)
+	return warnings
+}
@@ -0,0 +1,235 @@
+package bird
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Filter expressions passed with ?where= are restricted to
+// these attributes, constants and operators. All other input
+// is rejected before it reaches birdc.
+
+var whereAttributes = map[string]bool{
+	"net":                 true,
+	"gw":                  true,
+	"from":                true,
+	"proto":               true,
+	"source":              true,
+	"scope":               true,
+	"dest":                true,
+	"ifname":              true,
+	"preference":          true,
+	"bgp_path":            true,
+	"bgp_origin":          true,
+	"bgp_next_hop":        true,
+	"bgp_local_pref":      true,
+	"bgp_med":             true,
+	"bgp_community":       true,
+	"bgp_ext_community":   true,
+	"bgp_large_community": true,
+}
+
+var whereMethods = map[string]bool{
+	"len":   true,
+	"first": true,
+	"last":  true,
+	"ip":    true,
+	"type":  true,
+}
+
+var whereConstants = map[string]bool{
+	"true":              true,
+	"false":             true,
+	"NET_IP4":           true,
+	"NET_IP6":           true,
+	"ORIGIN_IGP":        true,
+	"ORIGIN_EGP":        true,
+	"ORIGIN_INCOMPLETE": true,
+	"RTS_BGP":           true,
+	"RTS_STATIC":        true,
+	"RTS_DEVICE":        true,
+	"RTS_OSPF":          true,
+}
+
+var whereOperators = []string{
+	// Longest first
+	"[=", "=]", "!=", "<=", ">=", "!~", "&&", "||",
+	"=", "<", ">", "~", "!", "(", ")", "[", "]", ",", "*", "?",
+}
+
+const whereMaxLength = 256
+
+func isWhereWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+		c >= '0' && c <= '9' || c == '_' || c == '.' || c == ':' || c == '/'
+}
+
+// validWhereWord checks a single attribute, constant or value
+func validWhereWord(word string) bool {
+	if whereAttributes[word] || whereConstants[word] {
+		return true
+	}
+
+	// Attribute methods, e.g. bgp_path.len
+	if i := strings.Index(word, "."); i > 0 && whereAttributes[word[:i]] {
+		return whereMethods[word[i+1:]]
+	}
+
+	if _, err := strconv.ParseUint(word, 10, 32); err == nil {
+		return true
+	}
+
+	// Ranges in sets, e.g. [1..10]
+	if parts := strings.Split(word, ".."); len(parts) == 2 {
+		_, err1 := strconv.ParseUint(parts[0], 10, 32)
+		_, err2 := strconv.ParseUint(parts[1], 10, 32)
+		return err1 == nil && err2 == nil
+	}
+
+	if net.ParseIP(word) != nil {
+		return true
+	}
+
+	// Prefixes and prefix patterns, e.g. 10.0.0.0/8+
+	prefix := strings.TrimRight(word, "+-")
+	if len(word)-len(prefix) > 1 {
+		return false
+	}
+	_, _, err := net.ParseCIDR(prefix)
+	return err == nil
+}
+
+// ValidateWhere checks a filter expression against the
+// whitelist and returns it normalized for birdc.
+func ValidateWhere(expr string) (string, error) {
+	if len(expr) > whereMaxLength {
+		return "", fmt.Errorf("Filter expression is too long")
+	}
+
+	tokens := []string{}
+	depth := 0
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+
+		// Whitespace
+		if c == ' ' || c == '\t' {
+			i++
+			continue
+		}
+
+		// Strings, e.g. ifname = "eth0"
+		if c == '"' {
+			end := strings.IndexByte(expr[i+1:], '"')
+			if end < 0 {
+				return "", fmt.Errorf("Unterminated string in filter expression")
+			}
+			value := expr[i+1 : i+1+end]
+			for j := 0; j < len(value); j++ {
+				if !isWhereWordChar(value[j]) && value[j] != '-' {
+					return "", fmt.Errorf("Invalid character in filter string")
+				}
+			}
+			tokens = append(tokens, "\""+value+"\"")
+			i += end + 2
+			continue
+		}
+
+		// Words
+		if isWhereWordChar(c) {
+			j := i
+			for j < len(expr) && isWhereWordChar(expr[j]) {
+				j++
+			}
+			for j < len(expr) && (expr[j] == '+' || expr[j] == '-') {
+				j++
+			}
+			word := expr[i:j]
+			if !validWhereWord(word) {
+				return "", fmt.Errorf("Invalid token in filter expression: %s", word)
+			}
+			tokens = append(tokens, word)
+			i = j
+			continue
+		}
+
+		// Operators
+		op := ""
+		for _, candidate := range whereOperators {
+			if strings.HasPrefix(expr[i:], candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return "", fmt.Errorf("Invalid character in filter expression: %q", c)
+		}
+
+		switch op {
+		case "(", "[", "[=":
+			depth++
+		case ")", "]", "=]":
+			depth--
+		}
+		if depth < 0 {
+			return "", fmt.Errorf("Unbalanced brackets in filter expression")
+		}
+
+		tokens = append(tokens, op)
+		i += len(op)
+	}
+
+	if depth != 0 {
+		return "", fmt.Errorf("Unbalanced brackets in filter expression")
+	}
+	if len(tokens) == 0 {
+		return "", fmt.Errorf("Empty filter expression")
+	}
+
+	return strings.Join(tokens, " "), nil
+}
+
+// routesQueryWhere is routesQuery with an additional,
+// validated filter expression.
+func routesQueryWhere(filter string, where string) string {
+	cmd := "route " + filter
+
+	channel := channelFilter()
+	if channel == "" {
+		return cmd + " where " + where
+	}
+
+	return cmd + " where " + channel + " && ( " + where + " )"
+}
+
+// RoutesProtoWhere returns the routes of a protocol
+// matching the filter expression.
+func RoutesProtoWhere(ctx context.Context, useCache bool, protocol string, where string) (Parsed, bool, error) {
+	cmd := routesQueryWhere(defaultTable("all protocol '"+protocol+"'"), where)
+	cmd = queryCommand("RoutesProtoWhere", QueryVars{Protocol: protocol, Where: where}, cmd)
+	return RunAndParse(
+		ctx,
+		useCache,
+		GetCacheKey("RoutesProtoWhere", protocol, where),
+		cmd,
+		parseRoutes,
+		nil)
+}
+
+// RoutesTableWhere returns the routes of a table
+// matching the filter expression.
+func RoutesTableWhere(ctx context.Context, useCache bool, table string, where string) (Parsed, bool, error) {
+	table = remapTable(table)
+	cmd := routesQueryWhere("table '"+table+"' all", where)
+	cmd = queryCommand("RoutesTableWhere", QueryVars{Table: table, Where: where}, cmd)
+	return RunAndParse(
+		ctx,
+		useCache,
+		GetCacheKey("RoutesTableWhere", table, where),
+		cmd,
+		parseRoutes,
+		nil)
+}
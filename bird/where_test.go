@@ -0,0 +1,49 @@
+package bird
+
+import (
+	"testing"
+)
+
+func TestValidateWhere(t *testing.T) {
+	valid := map[string]string{
+		"bgp_path ~ [= * 3356 * =]":                          "bgp_path ~ [= * 3356 * =]",
+		"bgp_path.len>3":                                     "bgp_path.len > 3",
+		"(65000,100) ~ bgp_community":                        "( 65000 , 100 ) ~ bgp_community",
+		"net ~ [ 10.0.0.0/8+ ]":                              "net ~ [ 10.0.0.0/8+ ]",
+		"net ~ 2001:db8::/32 && bgp_med <= 100":              "net ~ 2001:db8::/32 && bgp_med <= 100",
+		"gw = fe80::1":                                       "gw = fe80::1",
+		`ifname = "eth0"`:                                    `ifname = "eth0"`,
+		"bgp_path.first ~ [64512..65534]":                    "bgp_path.first ~ [ 64512..65534 ]",
+		"bgp_origin = ORIGIN_IGP || !(bgp_local_pref > 100)": "bgp_origin = ORIGIN_IGP || ! ( bgp_local_pref > 100 )",
+	}
+	for expr, expected := range valid {
+		res, err := ValidateWhere(expr)
+		if err != nil {
+			t.Error("Expected", expr, "to be valid, got:", err)
+			continue
+		}
+		if res != expected {
+			t.Error("Expected", expected, "got:", res)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"bgp_path ~ [= * 3356 * =",
+		"net ~ 10.0.0.0/8 ) (",
+		"print 1",
+		"bgp_path.prepend",
+		"net ~ 10.0.0.0/33",
+		"net ~ 10.0.0.0/8++",
+		`ifname = "eth0; reload"`,
+		"bgp_med = 1; configure",
+		"net = 1.2.3.4 { }",
+		"bgp_med = 1\nconfigure",
+		`ifname = "eth0`,
+	}
+	for _, expr := range invalid {
+		if res, err := ValidateWhere(expr); err == nil {
+			t.Error("Expected", expr, "to be rejected, got:", res)
+		}
+	}
+}
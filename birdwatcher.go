@@ -2,14 +2,26 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 
 	"strings"
 
+	"github.com/alice-lg/birdwatcher/alerts"
 	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/churn"
+	"github.com/alice-lg/birdwatcher/counts"
+	"github.com/alice-lg/birdwatcher/dump"
 	"github.com/alice-lg/birdwatcher/endpoints"
+	"github.com/alice-lg/birdwatcher/enrich"
+	"github.com/alice-lg/birdwatcher/events"
+	"github.com/alice-lg/birdwatcher/flaps"
+	"github.com/alice-lg/birdwatcher/history"
+	"github.com/alice-lg/birdwatcher/tracing"
 	"github.com/gorilla/handlers"
 
 	"github.com/julienschmidt/httprouter"
@@ -28,10 +40,19 @@ func isModuleEnabled(module string, modulesEnabled []string) bool {
 	return false
 }
 
-func makeRouter(config endpoints.ServerConfig) *httprouter.Router {
-	whitelist := config.ModulesEnabled
+// routeList registers an endpoint listing routes
+// for GET and HEAD, which only returns the counts.
+func routeList(r *httprouter.Router, path string, handle httprouter.Handle) {
+	r.GET(path, handle)
+	r.HEAD(path, handle)
+}
+
+func makeRouter(conf *Config) *httprouter.Router {
+	whitelist := conf.Server.ModulesEnabled
 
 	r := httprouter.New()
+	r.PanicHandler = endpoints.PanicHandler
+
 	if isModuleEnabled("status", whitelist) {
 		r.GET("/version", endpoints.Version(VERSION))
 		r.GET("/status", endpoints.Endpoint(endpoints.Status))
@@ -45,6 +66,21 @@ func makeRouter(config endpoints.ServerConfig) *httprouter.Router {
 	if isModuleEnabled("protocols_short", whitelist) {
 		r.GET("/protocols/short", endpoints.Endpoint(endpoints.ProtocolsShort))
 	}
+	if isModuleEnabled("protocols_summary", whitelist) {
+		r.GET("/protocols/summary", endpoints.Endpoint(endpoints.ProtocolsSummary))
+	}
+	if isModuleEnabled("protocols_down", whitelist) {
+		r.GET("/protocols/down", endpoints.Endpoint(endpoints.ProtocolsDown))
+	}
+	if isModuleEnabled("protocols_asn", whitelist) {
+		r.GET("/protocols/asn/:asn", endpoints.Endpoint(endpoints.ProtocolsASN))
+	}
+	if isModuleEnabled("protocols_uptime", whitelist) {
+		r.GET("/protocols/uptime", endpoints.Endpoint(endpoints.ProtocolsUptime))
+	}
+	if isModuleEnabled("protocols_churn", whitelist) {
+		r.GET("/protocols/churn", endpoints.Endpoint(endpoints.ProtocolsChurn))
+	}
 	if isModuleEnabled("symbols", whitelist) {
 		r.GET("/symbols", endpoints.Endpoint(endpoints.Symbols))
 	}
@@ -55,19 +91,22 @@ func makeRouter(config endpoints.ServerConfig) *httprouter.Router {
 		r.GET("/symbols/protocols", endpoints.Endpoint(endpoints.SymbolProtocols))
 	}
 	if isModuleEnabled("routes_protocol", whitelist) {
-		r.GET("/routes/protocol/:protocol", endpoints.Endpoint(endpoints.ProtoRoutes))
+		routeList(r, "/routes/protocol/:protocol", endpoints.Endpoint(endpoints.ProtoRoutes))
 	}
 	if isModuleEnabled("routes_peer", whitelist) {
-		r.GET("/routes/peer/:peer", endpoints.Endpoint(endpoints.PeerRoutes))
+		routeList(r, "/routes/peer/:peer", endpoints.Endpoint(endpoints.PeerRoutes))
 	}
 	if isModuleEnabled("routes_table", whitelist) {
-		r.GET("/routes/table/:table", endpoints.Endpoint(endpoints.TableRoutes))
+		routeList(r, "/routes/table/:table", endpoints.Endpoint(endpoints.TableRoutes))
+	}
+	if isModuleEnabled("routes_all", whitelist) {
+		routeList(r, "/routes/all", endpoints.Endpoint(endpoints.RoutesAll))
 	}
 	if isModuleEnabled("routes_table_filtered", whitelist) {
-		r.GET("/routes/table/:table/filtered", endpoints.Endpoint(endpoints.TableRoutesFiltered))
+		routeList(r, "/routes/table/:table/filtered", endpoints.Endpoint(endpoints.TableRoutesFiltered))
 	}
 	if isModuleEnabled("routes_table_peer", whitelist) {
-		r.GET("/routes/table/:table/peer/:peer", endpoints.Endpoint(endpoints.TableAndPeerRoutes))
+		routeList(r, "/routes/table/:table/peer/:peer", endpoints.Endpoint(endpoints.TableAndPeerRoutes))
 	}
 	if isModuleEnabled("routes_count_protocol", whitelist) {
 		r.GET("/routes/count/protocol/:protocol", endpoints.Endpoint(endpoints.ProtoCount))
@@ -79,41 +118,126 @@ func makeRouter(config endpoints.ServerConfig) *httprouter.Router {
 		r.GET("/routes/count/primary/:protocol", endpoints.Endpoint(endpoints.ProtoPrimaryCount))
 	}
 	if isModuleEnabled("routes_filtered", whitelist) {
-		r.GET("/routes/filtered/:protocol", endpoints.Endpoint(endpoints.RoutesFiltered))
+		routeList(r, "/routes/filtered/:protocol", endpoints.Endpoint(endpoints.RoutesFiltered))
 	}
 	if isModuleEnabled("routes_export", whitelist) {
-		r.GET("/routes/export/:protocol", endpoints.Endpoint(endpoints.RoutesExport))
+		routeList(r, "/routes/export/:protocol", endpoints.Endpoint(endpoints.RoutesExport))
+	}
+	if isModuleEnabled("routes_export_asn", whitelist) {
+		routeList(r, "/routes/asn/:asn/export", endpoints.Endpoint(endpoints.RoutesExportASN))
 	}
 	if isModuleEnabled("routes_noexport", whitelist) {
-		r.GET("/routes/noexport/:protocol", endpoints.Endpoint(endpoints.RoutesNoExport))
+		routeList(r, "/routes/noexport/:protocol", endpoints.Endpoint(endpoints.RoutesNoExport))
 	}
 	if isModuleEnabled("routes_prefixed", whitelist) {
-		r.GET("/routes/prefix", endpoints.Endpoint(endpoints.RoutesPrefixed))
+		routeList(r, "/routes/prefix", endpoints.Endpoint(endpoints.RoutesPrefixed))
+	}
+	if isModuleEnabled("routes_stats", whitelist) {
+		r.GET("/routes/stats/gateways", endpoints.Endpoint(endpoints.RoutesGatewayStats))
+		r.GET("/routes/stats/prefix-lengths", endpoints.Endpoint(endpoints.RoutesPrefixLengthStats))
+		r.GET("/routes/stats/origins", endpoints.Endpoint(endpoints.RoutesOriginStats))
+		r.GET("/routes/stats/communities", endpoints.Endpoint(endpoints.RoutesCommunityStats))
+	}
+	if isModuleEnabled("routes_lookup", whitelist) {
+		r.POST("/routes/lookup", endpoints.Endpoint(endpoints.RoutesLookup))
 	}
 	if isModuleEnabled("route_net", whitelist) {
-		r.GET("/route/net/:net", endpoints.Endpoint(endpoints.RouteNet))
-		r.GET("/route/net/:net/table/:table", endpoints.Endpoint(endpoints.RouteNetTable))
+		routeList(r, "/route/net/:net", endpoints.Endpoint(endpoints.RouteNet))
+		routeList(r, "/route/net/:net/table/:table", endpoints.Endpoint(endpoints.RouteNetTable))
+	}
+	if isModuleEnabled("routes_tables_all", whitelist) {
+		routeList(r, "/routes/tables/all", endpoints.Endpoint(endpoints.AllTablesRoutes))
+		routeList(r, "/route/net/:net/tables/all", endpoints.Endpoint(endpoints.RouteNetAllTables))
 	}
 	if isModuleEnabled("route_net_mask", whitelist) {
-		r.GET("/route/net/:net/mask/:mask", endpoints.Endpoint(endpoints.RouteNetMask))
-		r.GET("/route/net/:net/mask/:mask/table/:table", endpoints.Endpoint(endpoints.RouteNetMaskTable))
+		routeList(r, "/route/net/:net/mask/:mask", endpoints.Endpoint(endpoints.RouteNetMask))
+		routeList(r, "/route/net/:net/mask/:mask/table/:table", endpoints.Endpoint(endpoints.RouteNetMaskTable))
 	}
 	if isModuleEnabled("routes_pipe_filtered_count", whitelist) {
 		r.GET("/routes/pipe/filtered/count", endpoints.Endpoint(endpoints.PipeRoutesFilteredCount))
 	}
 	if isModuleEnabled("routes_pipe_filtered", whitelist) {
-		r.GET("/routes/pipe/filtered", endpoints.Endpoint(endpoints.PipeRoutesFiltered))
+		routeList(r, "/routes/pipe/filtered", endpoints.Endpoint(endpoints.PipeRoutesFiltered))
+	}
+	if isModuleEnabled("ospf_topology", whitelist) {
+		r.GET("/ospf/topology", endpoints.Endpoint(endpoints.OspfTopology))
+	}
+	if isModuleEnabled("config_tables", whitelist) {
+		r.GET("/config/tables", endpoints.Endpoint(endpoints.ConfigTables))
+	}
+	if isModuleEnabled("events", whitelist) {
+		r.GET("/events", endpoints.Endpoint(endpoints.Events))
+	}
+	if isModuleEnabled("alerts", whitelist) {
+		r.GET("/alerts", endpoints.Endpoint(endpoints.Alerts))
+	}
+	if isModuleEnabled("protocol_history", whitelist) {
+		r.GET("/protocol/:protocol/history", endpoints.Endpoint(endpoints.ProtocolHistory))
+	}
+	if isModuleEnabled("protocol_counts", whitelist) {
+		r.GET("/protocol/:protocol/counts", endpoints.Endpoint(endpoints.ProtocolCounts))
+	}
+	if isModuleEnabled("history_protocols", whitelist) {
+		r.GET("/history/protocols", endpoints.Endpoint(endpoints.HistoryProtocols))
+	}
+	if isModuleEnabled("history_diff", whitelist) {
+		r.GET("/history/diff", endpoints.Endpoint(endpoints.HistoryDiff))
+	}
+	if isModuleEnabled("protocols_changes", whitelist) {
+		r.GET("/protocols/changes", endpoints.Endpoint(endpoints.ProtocolsChanges))
+	}
+	if isModuleEnabled("support_bundle", whitelist) {
+		r.GET("/support/bundle", endpoints.Admin(endpoints.SupportBundle(VERSION, conf.Sanitized())))
+	}
+	if isModuleEnabled("ratelimit", whitelist) {
+		r.GET("/ratelimit", endpoints.Admin(endpoints.Endpoint(endpoints.RateLimit)))
+	}
+	if isModuleEnabled("admin_reconfigure", whitelist) {
+		r.POST("/admin/reconfigure", endpoints.Admin(endpoints.Endpoint(endpoints.Reconfigure)))
+	}
+	if isModuleEnabled("metrics", whitelist) {
+		r.GET("/metrics", endpoints.Metrics)
+	}
+	if isModuleEnabled("ui", whitelist) {
+		r.GET("/", endpoints.UI)
 	}
 
 	return r
 }
 
+// configureBird sets up the bird package for an instance
+func configureBird(conf *Config, birdConf bird.BirdConfig) {
+	bird.ClientConf = birdConf
+	bird.StatusConf = conf.Status
+	bird.RateLimitConf.Lock()
+	bird.RateLimitConf.Conf = conf.Ratelimit
+	bird.RateLimitConf.Unlock()
+	bird.MemoryConf = conf.Memory
+	bird.ParserConf = conf.Parser
+	bird.CacheConf = conf.Cache
+	bird.InitializeCache()
+
+	if err := bird.ConfigurePeerTables(conf.PeerTables); err != nil {
+		log.Fatal("Invalid peer table rules: ", err)
+	}
+	if err := bird.ConfigureQueries(conf.Queries); err != nil {
+		log.Fatal("Invalid query templates: ", err)
+	}
+	if err := bird.CheckRewarmQueries(conf.Cache.Rewarm); err != nil {
+		log.Fatal("Invalid cache config: ", err)
+	}
+}
+
 // Print service information like, listen address,
 // access restrictions and configuration flags
 func PrintServiceInfo(conf *Config, birdConf bird.BirdConfig) {
 	// General Info
 	log.Println("Starting Birdwatcher")
-	log.Println("            Using:", birdConf.BirdCmd)
+	if birdConf.Fixtures != "" {
+		log.Println("    Using fixtures:", birdConf.Fixtures)
+	} else {
+		log.Println("            Using:", birdConf.BirdCmd)
+	}
 	log.Println("           Listen:", birdConf.Listen)
 	log.Println("        Cache TTL:", birdConf.CacheTtl)
 
@@ -148,12 +272,37 @@ func (m *MyLogger) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// logRequest writes the access log in the common log
+// format, followed by the request ID.
+func logRequest(w io.Writer, params handlers.LogFormatterParams) {
+	host, _, err := net.SplitHostPort(params.Request.RemoteAddr)
+	if err != nil {
+		host = params.Request.RemoteAddr
+	}
+	fmt.Fprintf(w, "%s - - [%s] \"%s %s %s\" %d %d %s\n",
+		host,
+		params.TimeStamp.Format("02/Jan/2006:15:04:05 -0700"),
+		params.Request.Method,
+		params.URL.RequestURI(),
+		params.Request.Proto,
+		params.StatusCode,
+		params.Size,
+		endpoints.GetRequestID(params.Request))
+}
+
 func main() {
 	// Disable timestamps for the default logger, as they are generated by the syslog implementation
 	log.SetFlags(log.Flags() &^ (log.Ldate | log.Ltime))
+
+	// Subcommands like "birdwatcher parse"
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
 	bird6 := flag.Bool("6", false, "Use bird6 instead of bird")
 	workerPoolSize := flag.Int("worker-pool-size", 8, "Number of go routines used to parse routing tables concurrently")
 	configfile := flag.String("config", "/etc/birdwatcher/birdwatcher.conf", "Configuration file location")
+	captureDir := flag.String("capture-dir", "", "Archive the raw birdc output to this directory")
 
 	// Profiling
 	memoryProfile := flag.String("memprofile", "", "write memory profile to this file")
@@ -166,6 +315,7 @@ func main() {
 	}
 
 	bird.WorkerPoolSize = *workerPoolSize
+	bird.CaptureDir = *captureDir
 
 	conf, err := LoadConfigs([]string{*configfile})
 	if err != nil {
@@ -183,42 +333,114 @@ func main() {
 
 	// Get config according to flags
 	birdConf := conf.Bird
+	eventsConf := conf.Events
+	alertsConf := conf.Alerts
+	flapsConf := conf.Flaps
+	historyConf := conf.History
+	dumpConf := conf.Dump
 	if *bird6 {
 		birdConf = conf.Bird6
+		eventsConf = conf.Events6
+		alertsConf = conf.Alerts6
+		flapsConf = conf.Flaps6
+		historyConf = conf.History6
+		dumpConf = conf.Dump6
 		bird.IPVersion = "6"
 	}
 
 	PrintServiceInfo(conf, birdConf)
 
 	// Configuration
-	bird.ClientConf = birdConf
-	bird.StatusConf = conf.Status
-	bird.RateLimitConf.Lock()
-	bird.RateLimitConf.Conf = conf.Ratelimit
-	bird.RateLimitConf.Unlock()
-	bird.ParserConf = conf.Parser
-	bird.CacheConf = conf.Cache
-	bird.InitializeCache()
+	configureBird(conf, birdConf)
 
 	endpoints.Conf = conf.Server
+	endpoints.AdminConf = conf.Admin
+	enrich.RDNSConf = conf.RDNS
+	enrich.ASNamesConf = conf.ASNames
+	if enrich.ASNamesConf.Enabled {
+		enrich.StartASNamesRefresh()
+	}
+	enrich.FilterReasonsConf = conf.FilterReasons
+	enrich.ConfigMetadataConf = conf.ConfigMetadata
+	enrich.GeoIPConf = conf.GeoIP
+	if enrich.GeoIPConf.Enabled {
+		if err := enrich.OpenGeoIPDatabase(); err != nil {
+			log.Fatal("Opening GeoIP database failed:", err)
+		}
+	}
+	enrich.BogonsConf = conf.Bogons
+	if enrich.BogonsConf.Enabled {
+		if err := enrich.LoadBogons(); err != nil {
+			log.Fatal("Loading bogons failed:", err)
+		}
+	}
+	enrich.ROAConf = conf.ROA
+	if enrich.ROAConf.Enabled {
+		enrich.StartROARefresh()
+	}
 
 	// Make server
-	r := makeRouter(conf.Server)
+	r := makeRouter(conf)
 
 	// Set up our own custom log.Logger without a prefix
 	myquerylog := log.New(os.Stdout, "", 0)
 	// Disable timestamps, as they are contained in the query log
 	myquerylog.SetFlags(myquerylog.Flags() &^ (log.Ldate | log.Ltime))
 	mylogger := &MyLogger{myquerylog}
+	handler := endpoints.RequestID(handlers.CustomLoggingHandler(mylogger, r, logRequest))
 
 	go Housekeeping(conf.Housekeeping, !(bird.CacheConf.UseRedis)) // expire caches only for MemoryCache
 
+	if bird.CacheConf.InvalidateOnReconfig {
+		bird.WatchReconfig()
+	}
+
+	if eventsConf.Enabled {
+		if err := events.Start(eventsConf); err != nil {
+			log.Fatal("Starting event publishing failed:", err)
+		}
+	}
+
+	if alertsConf.Enabled {
+		alerts.Start(alertsConf)
+	}
+
+	if flapsConf.Enabled {
+		flaps.Start(flapsConf)
+	}
+
+	if historyConf.Enabled {
+		if err := history.Start(historyConf); err != nil {
+			log.Fatal("Starting history snapshots failed:", err)
+		}
+	}
+
+	if dumpConf.Enabled {
+		if err := dump.Start(dumpConf); err != nil {
+			log.Fatal("Starting route dumps failed:", err)
+		}
+	}
+
+	if conf.Churn.Enabled {
+		churn.Start(conf.Churn)
+	}
+
+	if conf.Counts.Enabled {
+		counts.Start(conf.Counts)
+	}
+
+	if conf.Tracing.Enabled {
+		if err := tracing.Start(conf.Tracing); err != nil {
+			log.Fatal("Starting tracing failed:", err)
+		}
+	}
+
 	if conf.Server.EnableTLS {
 		if len(conf.Server.Crt) == 0 || len(conf.Server.Key) == 0 {
 			log.Fatalln("You have enabled TLS support but not specified both a .crt and a .key file in the config.")
 		}
-		log.Fatal(http.ListenAndServeTLS(birdConf.Listen, conf.Server.Crt, conf.Server.Key, handlers.LoggingHandler(mylogger, r)))
+		log.Fatal(http.ListenAndServeTLS(birdConf.Listen, conf.Server.Crt, conf.Server.Key, handler))
 	} else {
-		log.Fatal(http.ListenAndServe(birdConf.Listen, handlers.LoggingHandler(mylogger, r)))
+		log.Fatal(http.ListenAndServe(birdConf.Listen, handler))
 	}
 }
@@ -0,0 +1,158 @@
+package churn
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/metrics"
+)
+
+var counters = []string{
+	"import_updates",
+	"import_withdraws",
+	"export_updates",
+	"export_withdraws",
+}
+
+var churnRate = metrics.NewGauge(
+	"birdwatcher_route_churn_per_minute",
+	"Received route changes per minute",
+	"protocol", "counter")
+
+// sample of the received route changes of a protocol
+type sample struct {
+	at     time.Time
+	counts map[string]int64
+}
+
+var collector struct {
+	sync.RWMutex
+	config  Config
+	samples map[string]sample
+	rates   map[string]bird.Parsed
+}
+
+func receivedChanges(protocol bird.Parsed) map[string]int64 {
+	counts := make(map[string]int64)
+	changes, ok := protocol["route_changes"].(bird.Parsed)
+	if !ok {
+		return counts
+	}
+	for _, counter := range counters {
+		if c, ok := changes[counter].(bird.Parsed); ok {
+			if received, ok := c["received"].(int64); ok {
+				counts[counter] = received
+			}
+		}
+	}
+	return counts
+}
+
+func isNoisy(config Config, rates bird.Parsed) bool {
+	updates, _ := rates["import_updates"].(float64)
+	withdraws, _ := rates["import_withdraws"].(float64)
+	if config.MaxUpdatesPerMinute > 0 && updates > float64(config.MaxUpdatesPerMinute) {
+		return true
+	}
+	if config.MaxWithdrawsPerMinute > 0 && withdraws > float64(config.MaxWithdrawsPerMinute) {
+		return true
+	}
+	return false
+}
+
+func update(protocols bird.Parsed, now time.Time) {
+	collector.Lock()
+	defer collector.Unlock()
+
+	// Drop the rates of protocols which are gone
+	for name := range collector.samples {
+		if _, ok := protocols[name]; ok {
+			continue
+		}
+		delete(collector.samples, name)
+		delete(collector.rates, name)
+		for _, counter := range counters {
+			churnRate.Delete(name, counter)
+		}
+	}
+
+	for name, p := range protocols {
+		protocol, ok := p.(bird.Parsed)
+		if !ok {
+			continue
+		}
+
+		current := sample{at: now, counts: receivedChanges(protocol)}
+		previous, ok := collector.samples[name]
+		collector.samples[name] = current
+		if !ok {
+			continue
+		}
+
+		minutes := now.Sub(previous.at).Minutes()
+		if minutes <= 0 {
+			continue
+		}
+
+		rates := bird.Parsed{}
+		for counter, count := range current.counts {
+			prev, ok := previous.counts[counter]
+			if !ok || count < prev {
+				continue // Counters were reset
+			}
+			rate := float64(count-prev) / minutes
+			rates[counter] = rate
+			churnRate.Set(rate, name, counter)
+		}
+		rates["noisy"] = isNoisy(collector.config, rates)
+		rates["sampled_at"] = now
+
+		collector.rates[name] = rates
+	}
+}
+
+// Rates returns the churn rates of all protocols
+func Rates() bird.Parsed {
+	collector.RLock()
+	defer collector.RUnlock()
+
+	res := make(bird.Parsed, len(collector.rates))
+	for name, rates := range collector.rates {
+		res[name] = rates
+	}
+	return res
+}
+
+// Noisy returns the churn rates of all protocols
+// exceeding the configured thresholds.
+func Noisy() bird.Parsed {
+	res := bird.Parsed{}
+	for name, r := range Rates() {
+		if rates := r.(bird.Parsed); rates["noisy"] == true {
+			res[name] = rates
+		}
+	}
+	return res
+}
+
+// Start sampling the route change counters
+func Start(config Config) {
+	collector.Lock()
+	collector.config = config
+	collector.samples = make(map[string]sample)
+	collector.rates = make(map[string]bird.Parsed)
+	collector.Unlock()
+
+	bird.RegisterProtocolsHook(func(p bird.Parsed) {
+		if protocols, ok := p["protocols"].(bird.Parsed); ok {
+			update(protocols, time.Now().UTC())
+		}
+	})
+
+	interval := time.Duration(config.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	bird.PollProtocols(interval)
+}
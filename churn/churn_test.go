@@ -0,0 +1,66 @@
+package churn
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/metrics"
+)
+
+func protocolWithChanges(updates, withdraws int64) bird.Parsed {
+	return bird.Parsed{
+		"R1": bird.Parsed{
+			"route_changes": bird.Parsed{
+				"import_updates":   bird.Parsed{"received": updates},
+				"import_withdraws": bird.Parsed{"received": withdraws},
+			},
+		},
+	}
+}
+
+func TestChurnRates(t *testing.T) {
+	Start(Config{MaxWithdrawsPerMinute: 100})
+
+	now := time.Now().UTC()
+	update(protocolWithChanges(1000, 10), now.Add(-2*time.Minute))
+	update(protocolWithChanges(1100, 1010), now)
+
+	rates := Rates()["R1"].(bird.Parsed)
+	if rates["import_updates"].(float64) != 50 {
+		t.Error("Expected 50 updates per minute, got:", rates["import_updates"])
+	}
+	if rates["import_withdraws"].(float64) != 500 {
+		t.Error("Expected 500 withdraws per minute, got:", rates["import_withdraws"])
+	}
+	if len(Noisy()) != 1 {
+		t.Error("Expected R1 to be noisy")
+	}
+}
+
+func TestStaleProtocols(t *testing.T) {
+	Start(Config{})
+
+	now := time.Now().UTC()
+	update(protocolWithChanges(1000, 10), now.Add(-time.Minute))
+	update(protocolWithChanges(1100, 20), now)
+
+	buf := &bytes.Buffer{}
+	metrics.Write(buf)
+	if !strings.Contains(buf.String(), `birdwatcher_route_churn_per_minute{protocol="R1",counter="import_updates"} 100`) {
+		t.Error("Expected the churn rate of R1:", buf.String())
+	}
+
+	update(bird.Parsed{}, now.Add(time.Minute))
+	if _, ok := Rates()["R1"]; ok {
+		t.Error("Expected the rates of a removed protocol to be dropped")
+	}
+
+	buf.Reset()
+	metrics.Write(buf)
+	if strings.Contains(buf.String(), `protocol="R1"`) {
+		t.Error("Expected the metric series of a removed protocol to be dropped")
+	}
+}
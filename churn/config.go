@@ -0,0 +1,15 @@
+package churn
+
+// Route churn collector configuration
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// Sampling interval in seconds
+	Interval int `toml:"interval"`
+
+	// Flag protocols exceeding these import rates.
+	// Zero disables the threshold.
+	MaxUpdatesPerMinute   int64 `toml:"max_updates_per_minute"`
+	MaxWithdrawsPerMinute int64 `toml:"max_withdraws_per_minute"`
+}
@@ -0,0 +1,158 @@
+package main
+
+// Subcommands for working with birdc output
+// without running the server.
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/support"
+)
+
+var commands = map[string]func(args []string) error{
+	"parse":    parseCommand,
+	"bundle":   bundleCommand,
+	"selftest": selftestCommand,
+	"query":    queryCommand,
+	"replay":   replayCommand,
+	"bench":    benchCommand,
+}
+
+// runCommand executes a subcommand if the first argument
+// names one. Returns false if no subcommand was given.
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		return false
+	}
+
+	if err := command(args[1:]); err != nil {
+		log.Fatal(args[0], ": ", err)
+	}
+	return true
+}
+
+// commandConfig loads the config and sets up the bird
+// instance for running queries from the command line.
+func commandConfig(configfile string, ipv6 bool) (*Config, error) {
+	conf, err := LoadConfigs([]string{configfile})
+	if err != nil {
+		return nil, err
+	}
+
+	birdConf := conf.Bird
+	if ipv6 {
+		birdConf = conf.Bird6
+		bird.IPVersion = "6"
+	}
+
+	// There is no rate limit reset outside of the server
+	conf.Ratelimit.Enabled = false
+	configureBird(conf, birdConf)
+
+	return conf, nil
+}
+
+func parseCommand(args []string) error {
+	flags := flag.NewFlagSet("parse", flag.ExitOnError)
+	outputType := flags.String("type", "routes",
+		"Type of the birdc output: "+strings.Join(bird.ParserTypes(), ", "))
+	ipv6 := flags.Bool("6", false, "Parse the output of bird6")
+	dualstack := flags.Bool("dualstack", false, "Do not filter protocol channels by IP version")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: birdwatcher parse [options] [file]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *ipv6 {
+		bird.IPVersion = "6"
+	}
+	bird.ClientConf.Dualstack = *dualstack
+
+	var input io.Reader = os.Stdin
+	if flags.NArg() > 0 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	res, err := bird.Parse(*outputType, input)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(res)
+}
+
+func bundleCommand(args []string) error {
+	flags := flag.NewFlagSet("bundle", flag.ExitOnError)
+	configfile := flags.String("config", "/etc/birdwatcher/birdwatcher.conf", "Configuration file location")
+	ipv6 := flags.Bool("6", false, "Use bird6 instead of bird")
+	output := flags.String("o", "birdwatcher-support.tar.gz", "Write the support bundle to this file")
+	flags.Parse(args)
+
+	conf, err := commandConfig(*configfile, *ipv6)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := support.WriteBundle(f, conf.Sanitized(), VERSION); err != nil {
+		return err
+	}
+
+	log.Println("Wrote support bundle:", *output)
+	return nil
+}
+
+func selftestCommand(args []string) error {
+	flags := flag.NewFlagSet("selftest", flag.ExitOnError)
+	configfile := flags.String("config", "/etc/birdwatcher/birdwatcher.conf", "Configuration file location")
+	ipv6 := flags.Bool("6", false, "Use bird6 instead of bird")
+	flags.Parse(args)
+
+	if _, err := commandConfig(*configfile, *ipv6); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range support.Selftest() {
+		state := "OK  "
+		if !result.Ok {
+			state = "FAIL"
+			failed++
+		} else if result.Warning {
+			state = "WARN"
+		}
+		fmt.Println(state, result.Name)
+		for _, problem := range result.Problems {
+			fmt.Println("    -", problem)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
@@ -10,20 +10,94 @@ import (
 	"github.com/BurntSushi/toml"
 	"github.com/imdario/mergo"
 
+	"github.com/alice-lg/birdwatcher/alerts"
 	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/churn"
+	"github.com/alice-lg/birdwatcher/counts"
+	"github.com/alice-lg/birdwatcher/dump"
 	"github.com/alice-lg/birdwatcher/endpoints"
+	"github.com/alice-lg/birdwatcher/enrich"
+	"github.com/alice-lg/birdwatcher/events"
+	"github.com/alice-lg/birdwatcher/flaps"
+	"github.com/alice-lg/birdwatcher/history"
+	"github.com/alice-lg/birdwatcher/tracing"
 )
 
 type Config struct {
 	Server endpoints.ServerConfig
 
 	Ratelimit    bird.RateLimitConfig
+	Memory       bird.MemoryConfig
 	Status       bird.StatusConfig
 	Bird         bird.BirdConfig
 	Bird6        bird.BirdConfig
 	Parser       bird.ParserConfig
 	Cache        bird.CacheConfig
 	Housekeeping HousekeepingConfig
+	Events       events.Config
+	Events6      events.Config
+	Alerts       alerts.Config
+	Alerts6      alerts.Config
+	Flaps        flaps.Config
+	Flaps6       flaps.Config
+	History      history.Config
+	History6     history.Config
+	Dump         dump.Config
+	Dump6        dump.Config
+	Churn        churn.Config
+	Counts       counts.Config
+	Tracing      tracing.Config
+	RDNS         enrich.RDNSConfig    `toml:"rdns"`
+	ASNames      enrich.ASNamesConfig `toml:"asn_names"`
+	GeoIP        enrich.GeoIPConfig   `toml:"geoip"`
+	Bogons       enrich.BogonsConfig  `toml:"bogons"`
+	ROA          enrich.ROAConfig     `toml:"roa"`
+
+	FilterReasons  enrich.FilterReasonsConfig  `toml:"filter_reasons"`
+	ConfigMetadata enrich.ConfigMetadataConfig `toml:"config_metadata"`
+	PeerTables     bird.PeerTablesConfig       `toml:"peer_tables"`
+	Queries        bird.QueriesConfig          `toml:"queries"`
+	Admin          endpoints.AdminConfig       `toml:"admin"`
+}
+
+// redact replaces a configured secret
+func redact(value *string) {
+	if *value != "" {
+		*value = "<redacted>"
+	}
+}
+
+// Sanitized returns a copy of the config without secrets.
+// URLs and servers are redacted as well, as they may
+// carry credentials, e.g. in webhook paths.
+func (c *Config) Sanitized() *Config {
+	sanitized := *c
+	for _, value := range []*string{
+		&sanitized.Cache.RedisServer,
+		&sanitized.Cache.RedisPassword,
+		&sanitized.Events.NatsServer,
+		&sanitized.Events.KafkaRestProxy,
+		&sanitized.Events6.NatsServer,
+		&sanitized.Events6.KafkaRestProxy,
+		&sanitized.Alerts.Webhook,
+		&sanitized.Alerts6.Webhook,
+		&sanitized.Tracing.Endpoint,
+		&sanitized.ASNames.PeeringDBURL,
+	} {
+		redact(value)
+	}
+	if len(sanitized.Admin.Tokens) > 0 {
+		sanitized.Admin.Tokens = []string{"<redacted>"}
+	}
+	if len(sanitized.Admin.ScopedTokens) > 0 {
+		scoped := make([]endpoints.ScopedToken, len(sanitized.Admin.ScopedTokens))
+		for i, token := range sanitized.Admin.ScopedTokens {
+			scoped[i] = token
+			scoped[i].Token = "<redacted>"
+		}
+		sanitized.Admin.ScopedTokens = scoped
+	}
+	return &sanitized
 }
 
 // Try to load configfiles as specified in the files
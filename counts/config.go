@@ -0,0 +1,13 @@
+package counts
+
+// Route count time series configuration
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// Sampling interval in seconds
+	Interval int `toml:"interval"`
+
+	// Number of samples kept per protocol
+	Size int `toml:"size"`
+}
@@ -0,0 +1,126 @@
+package counts
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/metrics"
+)
+
+var kinds = []string{
+	"imported",
+	"filtered",
+	"exported",
+	"preferred",
+}
+
+var routeCount = metrics.NewGauge(
+	"birdwatcher_protocol_routes",
+	"Number of routes of the protocol",
+	"protocol", "type", "ip_version")
+
+// A Sample are the route counts of a protocol at a time
+type Sample struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Routes    map[string]int64 `json:"routes"`
+}
+
+var series struct {
+	sync.RWMutex
+	config  Config
+	samples map[string][]Sample
+}
+
+func routeCounts(protocol bird.Parsed) (map[string]int64, bool) {
+	routes, ok := protocol["routes"].(bird.Parsed)
+	if !ok {
+		return nil, false
+	}
+	counts := make(map[string]int64, len(kinds))
+	for _, kind := range kinds {
+		if count, ok := routes[kind].(int64); ok {
+			counts[kind] = count
+		}
+	}
+	return counts, true
+}
+
+func update(protocols bird.Parsed, now time.Time) {
+	series.Lock()
+	defer series.Unlock()
+
+	size := series.config.Size
+	if size <= 0 {
+		size = 1440
+	}
+
+	// Drop the series of protocols which are gone
+	for name := range series.samples {
+		if _, ok := protocols[name]; ok {
+			continue
+		}
+		delete(series.samples, name)
+		for _, kind := range kinds {
+			routeCount.Delete(name, kind, bird.IPVersion)
+		}
+	}
+
+	for name, p := range protocols {
+		protocol, ok := p.(bird.Parsed)
+		if !ok {
+			continue
+		}
+		counts, ok := routeCounts(protocol)
+		if !ok {
+			continue
+		}
+
+		samples := append(series.samples[name], Sample{Timestamp: now, Routes: counts})
+		if len(samples) > size {
+			samples = append([]Sample{}, samples[len(samples)-size:]...)
+		}
+		series.samples[name] = samples
+
+		for kind, count := range counts {
+			routeCount.Set(float64(count), name, kind, bird.IPVersion)
+		}
+	}
+}
+
+// Series returns the samples of the protocol within
+// the window before now, oldest first.
+func Series(protocol string, window time.Duration, now time.Time) []Sample {
+	series.RLock()
+	defer series.RUnlock()
+
+	from := now.Add(-window)
+	res := []Sample{}
+	for _, sample := range series.samples[protocol] {
+		if window > 0 && sample.Timestamp.Before(from) {
+			continue
+		}
+		res = append(res, sample)
+	}
+	return res
+}
+
+// Start sampling the route counts at each protocols refresh
+func Start(config Config) {
+	series.Lock()
+	series.config = config
+	series.samples = make(map[string][]Sample)
+	series.Unlock()
+
+	bird.RegisterProtocolsHook(func(p bird.Parsed) {
+		if protocols, ok := p["protocols"].(bird.Parsed); ok {
+			update(protocols, time.Now().UTC())
+		}
+	})
+
+	interval := time.Duration(config.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	bird.PollProtocols(interval)
+}
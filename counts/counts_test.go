@@ -0,0 +1,67 @@
+package counts
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/metrics"
+)
+
+func protocolWithRoutes(imported int64) bird.Parsed {
+	return bird.Parsed{
+		"R1": bird.Parsed{
+			"routes": bird.Parsed{"imported": imported, "filtered": int64(0)},
+		},
+	}
+}
+
+func TestSeries(t *testing.T) {
+	Start(Config{Size: 3})
+
+	now := time.Now().UTC()
+	for i := int64(0); i < 4; i++ {
+		update(protocolWithRoutes(100*i), now.Add(time.Duration(i-3)*time.Hour))
+	}
+
+	samples := Series("R1", 0, now)
+	if len(samples) != 3 || samples[0].Routes["imported"] != 100 {
+		t.Fatal("Expected the last 3 samples, got:", samples)
+	}
+
+	samples = Series("R1", 90*time.Minute, now)
+	if len(samples) != 2 || samples[1].Routes["imported"] != 300 {
+		t.Error("Expected 2 samples within the window, got:", samples)
+	}
+	if len(Series("R2", 0, now)) != 0 {
+		t.Error("Expected no samples for an unknown protocol")
+	}
+}
+
+func TestStaleProtocols(t *testing.T) {
+	Start(Config{})
+
+	now := time.Now().UTC()
+	update(protocolWithRoutes(23), now)
+
+	buf := &bytes.Buffer{}
+	metrics.Write(buf)
+	expected := `birdwatcher_protocol_routes{protocol="R1",type="imported",ip_version="` +
+		bird.IPVersion + `"} 23`
+	if !strings.Contains(buf.String(), expected) {
+		t.Error("Expected metrics to contain:", expected)
+	}
+
+	update(bird.Parsed{}, now.Add(time.Minute))
+	if len(Series("R1", 0, now)) != 0 {
+		t.Error("Expected the samples of a removed protocol to be dropped")
+	}
+
+	buf.Reset()
+	metrics.Write(buf)
+	if strings.Contains(buf.String(), `protocol="R1"`) {
+		t.Error("Expected the metric series of a removed protocol to be dropped")
+	}
+}
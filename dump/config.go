@@ -0,0 +1,17 @@
+package dump
+
+// Route dump configuration
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// Dumps are written as gzipped json files
+	// into this directory
+	Directory string `toml:"directory"`
+
+	// Dump interval in seconds
+	Interval int `toml:"interval"`
+
+	// Remove dumps older than this number of days
+	Retention int `toml:"retention"`
+}
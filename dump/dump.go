@@ -0,0 +1,202 @@
+package dump
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+const (
+	filePrefix = "routes-"
+	fileSuffix = ".json.gz"
+	fileLayout = "20060102T150405Z"
+)
+
+// A fetchFunc queries the routes of a protocol
+type fetchFunc func(ctx context.Context, useCache bool, protocol string) (bird.Parsed, bool, error)
+
+// fetchRoutes waits for the rate limit instead of
+// failing the dump of the protocol.
+func fetchRoutes(fetch fetchFunc, protocol string) ([]bird.Parsed, error) {
+	for attempt := 0; ; attempt++ {
+		res, _, err := fetch(context.Background(), true, protocol)
+		if err == bird.ErrRateLimited && attempt < 10 {
+			time.Sleep(time.Second)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		routes, _ := res["routes"].([]bird.Parsed)
+		return routes, nil
+	}
+}
+
+// writeDump writes the imported and filtered routes of all
+// protocols as a single json object. The routes of one protocol
+// are fetched at a time to limit the memory use.
+func writeDump(
+	w io.Writer,
+	now time.Time,
+	protocols []string,
+	imported, filtered fetchFunc,
+) error {
+	header, _ := json.Marshal(now)
+	if _, err := fmt.Fprintf(w, `{"timestamp":%s,"ip_version":%q,"protocols":{`,
+		header, bird.IPVersion); err != nil {
+		return err
+	}
+
+	for i, protocol := range protocols {
+		entry := bird.Parsed{}
+		for key, fetch := range map[string]fetchFunc{
+			"imported": imported,
+			"filtered": filtered,
+		} {
+			routes, err := fetchRoutes(fetch, protocol)
+			if err != nil {
+				entry[key+"_error"] = err
+				continue
+			}
+			entry[key] = routes
+		}
+
+		name, _ := json.Marshal(protocol)
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s:%s", name, data); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "}}\n")
+	return err
+}
+
+// bgpProtocols returns the names of all BGP protocols
+func bgpProtocols() ([]string, error) {
+	res, _, err := bird.ProtocolsBgp(context.Background(), true)
+	if err != nil {
+		return nil, err
+	}
+	protocols, _ := res["protocols"].(bird.Parsed)
+
+	names := make([]string, 0, len(protocols))
+	for name := range protocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// dump writes a new dump file. The file is written
+// under a temporary name and renamed when complete.
+func dump(directory string, now time.Time) (string, error) {
+	protocols, err := bgpProtocols()
+	if err != nil {
+		return "", err
+	}
+
+	filename := filepath.Join(directory, filePrefix+now.UTC().Format(fileLayout)+fileSuffix)
+	f, err := os.Create(filename + ".tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(filename + ".tmp")
+
+	gz := gzip.NewWriter(f)
+	err = writeDump(gz, now, protocols, bird.RoutesProto, bird.RoutesFiltered)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return filename, os.Rename(filename+".tmp", filename)
+}
+
+// prune removes all dumps before the retention period
+func prune(directory string, now time.Time, retention int) error {
+	if retention <= 0 {
+		return nil
+	}
+
+	files, err := ioutil.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.UTC().AddDate(0, 0, -retention)
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		t, err := time.Parse(fileLayout,
+			strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil || !t.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(directory, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Start writing dumps in the configured interval
+func Start(config Config) error {
+	if config.Directory == "" {
+		return fmt.Errorf("dump directory is not set")
+	}
+	if err := os.MkdirAll(config.Directory, 0755); err != nil {
+		return err
+	}
+
+	interval := config.Interval
+	if interval <= 0 {
+		interval = 3600
+	}
+
+	go func() {
+		for {
+			now := time.Now().UTC()
+			if filename, err := dump(config.Directory, now); err != nil {
+				log.Println("Writing route dump failed:", err)
+			} else {
+				log.Println("Wrote route dump:", filename)
+			}
+
+			if err := prune(config.Directory, now, config.Retention); err != nil {
+				log.Println("Pruning route dumps failed:", err)
+			}
+
+			time.Sleep(time.Duration(interval) * time.Second)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+func TestWriteDump(t *testing.T) {
+	imported := func(ctx context.Context, useCache bool, protocol string) (bird.Parsed, bool, error) {
+		return bird.Parsed{"routes": []bird.Parsed{{"network": "10.0.0.0/8", "from_protocol": protocol}}}, true, nil
+	}
+	filtered := func(ctx context.Context, useCache bool, protocol string) (bird.Parsed, bool, error) {
+		return nil, false, bird.ErrUnreachable
+	}
+
+	buf := &bytes.Buffer{}
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	if err := writeDump(buf, now, []string{"R1", "R2"}, imported, filtered); err != nil {
+		t.Fatal(err)
+	}
+
+	res := struct {
+		Timestamp time.Time `json:"timestamp"`
+		Protocols map[string]struct {
+			Imported      []bird.Parsed   `json:"imported"`
+			FilteredError bird.QueryError `json:"filtered_error"`
+		} `json:"protocols"`
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatal("Invalid dump:", err, buf.String())
+	}
+	if !res.Timestamp.Equal(now) || len(res.Protocols) != 2 {
+		t.Error("Unexpected dump:", buf.String())
+	}
+	if r := res.Protocols["R2"]; len(r.Imported) != 1 || r.FilteredError.Code != "bird_unreachable" {
+		t.Error("Unexpected routes of R2:", r)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	old := filepath.Join(dir, filePrefix+now.AddDate(0, 0, -8).Format(fileLayout)+fileSuffix)
+	recent := filepath.Join(dir, filePrefix+now.AddDate(0, 0, -1).Format(fileLayout)+fileSuffix)
+	for _, name := range []string{old, recent} {
+		if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := prune(dir, now, 7); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Expected the old dump to be removed")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Error("Expected the recent dump to be kept:", err)
+	}
+}
@@ -0,0 +1,40 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/julienschmidt/httprouter"
+)
+
+var AdminConf AdminConfig
+
+// isAdmin checks the bearer token of the request
+func isAdmin(r *http.Request) bool {
+	token, ok := requestToken(r)
+	if !ok {
+		return false
+	}
+	scoped, ok := lookupToken(token)
+	return ok && scoped.hasScope("admin")
+}
+
+// Admin restricts a handler to requests with an admin token
+func Admin(wrapped httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !isAdmin(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "admin token required", http.StatusUnauthorized)
+			return
+		}
+		wrapped(w, r, ps)
+	}
+}
+
+// Reconfigure runs birdc configure, or configure check
+// as a dry run with ?check=true
+func Reconfigure(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	check := r.URL.Query().Get("check") == "true"
+	res, err := bird.Configure(r.Context(), check)
+	return res, false, err
+}
@@ -0,0 +1,13 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/alice-lg/birdwatcher/alerts"
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/julienschmidt/httprouter"
+)
+
+func Alerts(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	return bird.Parsed{"alerts": alerts.Active()}, false, nil
+}
@@ -0,0 +1,32 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/enrich"
+	"github.com/julienschmidt/httprouter"
+)
+
+// withOnly restricts the routes of the result with ?only=bogons
+// before they are paginated.
+func withOnly(wrapped endpoint) endpoint {
+	return func(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+		switch only := r.URL.Query().Get("only"); only {
+		case "":
+			return wrapped(r, ps, useCache)
+		case "bogons":
+			if !enrich.BogonsConf.Enabled {
+				return invalidParam("only", fmt.Errorf("Bogon detection is not enabled"))
+			}
+			res, fromCache, err := wrapped(r, ps, useCache)
+			if err != nil {
+				return nil, false, err
+			}
+			return enrich.OnlyBogons(res), fromCache, nil
+		default:
+			return invalidParam("only", fmt.Errorf("Invalid only: %s (bogons)", only))
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/churn"
+	"github.com/julienschmidt/httprouter"
+)
+
+func ProtocolsChurn(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	qs := r.URL.Query()
+	if len(qs["noisy"]) == 1 && qs["noisy"][0] == "true" {
+		return bird.Parsed{"churn": churn.Noisy()}, false, nil
+	}
+	return bird.Parsed{"churn": churn.Rates()}, false, nil
+}
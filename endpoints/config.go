@@ -9,4 +9,48 @@ type ServerConfig struct {
 	EnableTLS bool   `toml:"enable_tls"`
 	Crt       string `toml:"crt"`
 	Key       string `toml:"key"`
+
+	// Respond with {"api", "cached", "data"} instead of
+	// adding the result keys next to "api"
+	ResponseEnvelope bool `toml:"response_envelope"`
+
+	// Naming of the response fields: snake_case or camelCase
+	FieldNames string `toml:"field_names"`
+
+	// Fields renamed in or added to the responses, e.g.
+	// neighbor_address = "neighbor_ip"
+	FieldRenames map[string]string `toml:"field_renames"`
+	FieldAliases map[string]string `toml:"field_aliases"`
+
+	// Format of timestamps: rfc3339, unix or both,
+	// empty keeps the formats of BIRD
+	TimestampFormat string `toml:"timestamp_format"`
+
+	// Upper bound of ?timeout= in seconds
+	MaxTimeout int `toml:"max_timeout"`
+
+	// Limits of POST /routes/lookup
+	LookupMaxPrefixes int `toml:"lookup_max_prefixes"`
+	LookupConcurrency int `toml:"lookup_concurrency"`
+}
+
+// Administrative access
+type AdminConfig struct {
+	// Bearer tokens granting the admin role
+	Tokens []string `toml:"tokens"`
+
+	// Reject requests without a token
+	RequireToken bool `toml:"require_token"`
+
+	// Bearer tokens restricted to endpoint groups
+	ScopedTokens []ScopedToken `toml:"scoped_tokens"`
+}
+
+// A token restricted to endpoint groups ("status", "routes"
+// and "admin") and optionally to protocols and tables
+type ScopedToken struct {
+	Token     string   `toml:"token"`
+	Scopes    []string `toml:"scopes"`
+	Protocols []string `toml:"protocols"`
+	Tables    []string `toml:"tables"`
 }
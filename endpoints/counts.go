@@ -0,0 +1,31 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/counts"
+	"github.com/julienschmidt/httprouter"
+)
+
+func ProtocolCounts(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	protocol, err := ValidateProtocolParam(ps.ByName("protocol"))
+	if err != nil {
+		return invalidParam("protocol", err)
+	}
+
+	window := time.Hour
+	if value := r.URL.Query().Get("window"); value != "" {
+		window, err = time.ParseDuration(value)
+		if err != nil || window <= 0 {
+			return invalidParam("window", fmt.Errorf("Invalid window: %s", value))
+		}
+	}
+
+	return bird.Parsed{
+		"protocol": protocol,
+		"counts":   counts.Series(protocol, window, time.Now().UTC()),
+	}, false, nil
+}
@@ -0,0 +1,20 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/julienschmidt/httprouter"
+)
+
+// withDedupe collapses identical announcements of the
+// result with ?dedupe=true before they are paginated.
+func withDedupe(wrapped endpoint) endpoint {
+	return func(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+		res, fromCache, err := wrapped(r, ps, useCache)
+		if r.URL.Query().Get("dedupe") != "true" || err != nil {
+			return res, fromCache, err
+		}
+		return bird.DedupeRoutes(res), fromCache, nil
+	}
+}
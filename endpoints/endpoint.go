@@ -3,19 +3,21 @@ package endpoints
 import (
 	"fmt"
 	"log"
-	"reflect"
 	"strings"
 
 	"compress/gzip"
-	"encoding/json"
 	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/enrich"
+	"github.com/alice-lg/birdwatcher/tracing"
 	"github.com/julienschmidt/httprouter"
 )
 
-type endpoint func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool)
+type endpoint func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool, error)
 
 var Conf ServerConfig
 
@@ -51,6 +53,25 @@ func CheckAccess(req *http.Request) error {
 	return fmt.Errorf("%s is not allowed to access this service", ipStr);
 }
 
+// checkRequest checks the source address and the token of a
+// request and responds with an error if it is rejected. Every
+// handler checks the request with it before serving it.
+func checkRequest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) (*ScopedToken, bool) {
+	if err := CheckAccess(r); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return nil, false
+	}
+	token, status, err := authorize(r, ps)
+	if err != nil {
+		if status == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+		}
+		http.Error(w, err.Error(), status)
+		return nil, false
+	}
+	return token, true
+}
+
 func CheckUseCache(req *http.Request) bool {
 	qs := req.URL.Query()
 
@@ -62,40 +83,190 @@ func CheckUseCache(req *http.Request) bool {
 	return true
 }
 
+// queryError returns the query error of an endpoint, other
+// errors are reported as internal errors with their message.
+func queryError(err error) *bird.QueryError {
+	if queryErr, ok := err.(*bird.QueryError); ok {
+		return queryErr
+	}
+	return &bird.QueryError{Code: "internal_error", Message: err.Error()}
+}
+
+// errorStatus maps query errors to HTTP status codes
+func errorStatus(err error) int {
+	switch queryError(err).Code {
+	case bird.ErrRateLimited.Code:
+		return http.StatusTooManyRequests
+	case bird.ErrTimeout.Code:
+		return http.StatusGatewayTimeout
+	case bird.ErrUnreachable.Code, bird.ErrOverloaded.Code:
+		return http.StatusServiceUnavailable
+	case bird.ErrInvalid.Code:
+		return http.StatusBadRequest
+	case bird.ErrUnsupported.Code:
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// writeQueryError responds with the status of the error and
+// an error object with code, message and retryability.
+// Invalid requests list the invalid parameters in fields.
+func writeQueryError(w http.ResponseWriter, r *http.Request, err *bird.QueryError, api *APIInfo) {
+	if err == bird.ErrRateLimited {
+		w.Header().Set("Retry-After", "1") // The rate limit is reset every second
+	}
+	if err == bird.ErrOverloaded {
+		w.Header().Set("Retry-After", strconv.Itoa(bird.MemoryRetryAfter()))
+	}
+	status := errorStatus(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encodeResponse(w, map[string]interface{}{
+		"api":   api,
+		"error": err,
+	}, fieldNaming(r))
+}
+
+// setCacheHeaders allows clients to cache the result
+// until it expires in the cache of birdwatcher.
+func setCacheHeaders(w http.ResponseWriter, res bird.Parsed, now time.Time) {
+	expires, ok := bird.CacheExpiry(res)
+	maxAge := int(expires.Sub(now).Seconds())
+	if !ok || maxAge <= 0 {
+		w.Header().Set("Cache-Control", "no-cache")
+		return
+	}
+	w.Header().Set("Cache-Control", "max-age="+strconv.Itoa(maxAge))
+	w.Header().Set("Expires", expires.UTC().Format(http.TimeFormat))
+}
+
+// setCacheStatusHeaders reports with X-Cache if the result
+// was served from the cache and with Age how old it is.
+func setCacheStatusHeaders(w http.ResponseWriter, res bird.Parsed, fromCache bool, now time.Time) {
+	if fromCache {
+		w.Header().Set("X-Cache", "HIT")
+	} else {
+		w.Header().Set("X-Cache", "MISS")
+	}
+
+	cachedAt, ok := bird.CachedAt(res)
+	if !ok {
+		return
+	}
+	age := int(now.Sub(cachedAt).Seconds())
+	if age < 0 {
+		age = 0
+	}
+	w.Header().Set("Age", strconv.Itoa(age))
+}
+
+// isCountOnly checks for ?count_only=1, which
+// responds with the route count headers only.
+func isCountOnly(r *http.Request) bool {
+	switch r.URL.Query().Get("count_only") {
+	case "1", "true":
+		return true
+	}
+	return false
+}
+
+// setRouteCountHeaders sets X-Total-Routes to the number of
+// routes in the result. Endpoints listing filtered routes also
+// set X-Filtered-Routes.
+func setRouteCountHeaders(w http.ResponseWriter, r *http.Request, ps httprouter.Params, res bird.Parsed) {
+	routes, ok := res["routes"].([]bird.Parsed)
+	if !ok {
+		return
+	}
+	count := strconv.Itoa(len(routes))
+	if pagination, ok := res["pagination"].(bird.Parsed); ok {
+		count = strconv.Itoa(pagination["total"].(int))
+	}
+	w.Header().Set("X-Total-Routes", count)
+
+	for _, segment := range strings.Split(routePattern(r.URL.Path, ps), "/") {
+		if segment == "filtered" {
+			w.Header().Set("X-Filtered-Routes", count)
+			break
+		}
+	}
+}
+
 func Endpoint(wrapped endpoint) httprouter.Handle {
 	return func(w http.ResponseWriter,
 		r *http.Request,
 		ps httprouter.Params) {
 
 		// Access Control
-		if err := CheckAccess(r); err != nil {
-			http.Error(w, err.Error(), http.StatusForbidden)
+		token, ok := checkRequest(w, r, ps)
+		if !ok {
 			return
 		}
 
+		span := tracing.StartRemoteSpan("HTTP "+r.Method, r.Header.Get("traceparent"))
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.Path)
+		span.SetAttribute("request_id", GetRequestID(r))
+		defer span.End()
+
 		res := make(map[string]interface{})
 
 		useCache := CheckUseCache(r)
-		ret, from_cache := wrapped(r, ps, useCache)
+		child := span.Child("handler")
+		r = r.WithContext(tracing.ContextWithSpan(r.Context(), child))
+		query := withTimeout(withResolveNexthop(withPagination(withOnly(withDedupe(wrapped)))))
+		ret, from_cache, err := query(r, ps, useCache)
+		if err == nil {
+			ret = restrictProtocols(token, ret)
+		}
+		child.End()
+		span.SetAttribute("result_from_cache", from_cache)
+		setCacheStatusHeaders(w, ret, from_cache, time.Now())
+
+		api := GetApiInfo(&ret, from_cache)
+		api.RequestID = GetRequestID(r)
 
-		if reflect.DeepEqual(ret, bird.NilParse) {
-			w.WriteHeader(http.StatusTooManyRequests)
+		if err != nil {
+			log.Println("Request", api.RequestID, "failed:", err)
+			span.SetError(err)
+			span.SetAttribute("http.status_code", errorStatus(err))
+			if err == bird.ErrRateLimited {
+				recordRejection(r, ps)
+			}
+			writeQueryError(w, r, queryError(err), api)
 			return
 		}
-		if reflect.DeepEqual(ret, bird.BirdError) {
-			w.WriteHeader(http.StatusInternalServerError)
+		res["api"] = api
+
+		setRouteCountHeaders(w, r, ps, ret)
+		if r.Method == http.MethodHead || isCountOnly(r) {
 			w.Header().Set("Content-Type", "application/json")
-			js, _ := json.Marshal(ret)
-			w.Write(js)
+			setCacheHeaders(w, ret, time.Now())
+			w.WriteHeader(http.StatusOK)
 			return
 		}
-		res["api"] = GetApiInfo(&ret, from_cache)
 
-		for k, v := range ret {
-			res[k] = v
+		child = span.Child("enrich")
+		ret = enrich.Apply(r, ret)
+		child.End()
+
+		if Conf.ResponseEnvelope {
+			res = envelope(api, ret)
+		} else {
+			for k, v := range ret {
+				if k == "warnings" {
+					continue // Reported in the api info
+				}
+				res[k] = v
+			}
 		}
 
 		w.Header().Set("Content-Type", "application/json")
+		setCacheHeaders(w, ret, time.Now())
+
+		child = span.Child("encode")
+		defer child.End()
 
 		// Check if compression is supported
 		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
@@ -103,17 +274,18 @@ func Endpoint(wrapped endpoint) httprouter.Handle {
 			w.Header().Set("Content-Encoding", "gzip")
 			gz := gzip.NewWriter(w)
 			defer gz.Close()
-			json := json.NewEncoder(gz)
-			json.Encode(res)
+			encodeResponse(gz, res, fieldNaming(r))
 		} else {
-			json := json.NewEncoder(w)
-			json.Encode(res) // Fall back to uncompressed response
+			encodeResponse(w, res, fieldNaming(r)) // Fall back to uncompressed response
 		}
 	}
 }
 
 func Version(version string) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if _, ok := checkRequest(w, r, ps); !ok {
+			return
+		}
 		w.Header().Set("Content-Type", "text/plain")
 		w.Write([]byte(version))
 	}
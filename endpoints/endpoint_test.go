@@ -0,0 +1,687 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/tracing"
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestEndpointQueryErrors(t *testing.T) {
+	tests := []struct {
+		err       *bird.QueryError
+		status    int
+		retryable bool
+	}{
+		{bird.ErrRateLimited, http.StatusTooManyRequests, true},
+		{bird.ErrTimeout, http.StatusGatewayTimeout, true},
+		{bird.ErrUnreachable, http.StatusServiceUnavailable, true},
+		{bird.ErrOverloaded, http.StatusServiceUnavailable, true},
+		{bird.ErrParse, http.StatusInternalServerError, false},
+		{bird.Unsupported("Querying all tables requires BIRD 2"), http.StatusNotImplemented, false},
+	}
+
+	for _, test := range tests {
+		failing := func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool, error) {
+			return nil, false, test.err
+		}
+
+		rec := httptest.NewRecorder()
+		Endpoint(failing)(rec, httptest.NewRequest("GET", "/status", nil), nil)
+
+		if rec.Code != test.status {
+			t.Error(test.err.Code, "expected status", test.status, "got:", rec.Code)
+		}
+
+		res := struct {
+			Error bird.QueryError `json:"error"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Error.Code != test.err.Code || res.Error.Retryable != test.retryable ||
+			res.Error.Message == "" {
+			t.Error("Unexpected error object:", res.Error)
+		}
+	}
+}
+
+func TestEndpointInvalidParams(t *testing.T) {
+	for _, test := range []struct {
+		handler endpoint
+		path    string
+		ps      httprouter.Params
+		field   string
+	}{
+		{ProtoRoutes, "/routes/protocol/R1;", httprouter.Params{{Key: "protocol", Value: "R1;"}}, "protocol"},
+		{RouteNet, "/route/net/10.0.0.0", httprouter.Params{{Key: "net", Value: "10.0.0.0.1"}}, "net"},
+		{RouteNetMask, "/route/net/10.0.0.0/mask/200",
+			httprouter.Params{{Key: "net", Value: "10.0.0.0"}, {Key: "mask", Value: "200"}}, "mask"},
+		{RouteNetMask, "/route/net/10.0.0.0/mask/64",
+			httprouter.Params{{Key: "net", Value: "10.0.0.0"}, {Key: "mask", Value: "64"}}, "mask"},
+		{RouteNetMaskTable, "/route/net/10.0.0.0/mask/33/table/T1", httprouter.Params{
+			{Key: "net", Value: "10.0.0.0"}, {Key: "mask", Value: "33"}, {Key: "table", Value: "T1"}}, "mask"},
+		{PipeRoutesFiltered, "/routes/pipe/filtered?table=T1", nil, "pipe"},
+		{ProtoRoutes, "/routes/protocol/R1?offset=-1", httprouter.Params{{Key: "protocol", Value: "R1"}}, "offset"},
+	} {
+		rec := httptest.NewRecorder()
+		Endpoint(test.handler)(rec, httptest.NewRequest("GET", test.path, nil), test.ps)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Error(test.path, "expected status 400, got:", rec.Code)
+		}
+		res := struct {
+			Error bird.QueryError `json:"error"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Error.Code != bird.ErrInvalid.Code || len(res.Error.Fields) != 1 ||
+			res.Error.Fields[0].Field != test.field {
+			t.Error(test.path, "unexpected error object:", rec.Body.String())
+		}
+	}
+}
+
+func TestPanicHandler(t *testing.T) {
+	r := httprouter.New()
+	r.PanicHandler = PanicHandler
+	r.GET("/panic", Endpoint(func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool, error) {
+		var status bird.Parsed
+		return status["status"].(bird.Parsed), false, nil
+	}))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/panic", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Error("Expected status 500, got:", rec.Code)
+	}
+
+	res := struct {
+		Error bird.QueryError `json:"error"`
+	}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Error.Code != "internal_error" {
+		t.Error("Unexpected error code:", res.Error.Code)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetRequestID(r)
+		if bird.RequestID(r.Context()) != seen {
+			t.Error("Expected the request ID in the context, got:", bird.RequestID(r.Context()))
+		}
+	}))
+
+	req := httptest.NewRequest("GET", "/status", nil)
+	req.Header.Set(RequestIDHeader, "frontend-1234")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if seen != "frontend-1234" || rec.Header().Get(RequestIDHeader) != "frontend-1234" {
+		t.Error("Expected the incoming request ID, got:", seen)
+	}
+
+	// Invalid IDs are replaced
+	req = httptest.NewRequest("GET", "/status", nil)
+	req.Header.Set(RequestIDHeader, "bad id\n")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if len(seen) != 32 || rec.Header().Get(RequestIDHeader) != seen {
+		t.Error("Expected a generated request ID, got:", seen)
+	}
+}
+
+func TestEndpointTrace(t *testing.T) {
+	recorded := tracing.Record()
+	query := func(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+		tracing.StartSpanFromContext(r.Context(), "birdc status").End()
+		return bird.Parsed{}, false, nil
+	}
+	req := httptest.NewRequest("GET", "/status", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	Endpoint(query)(httptest.NewRecorder(), req, nil)
+
+	spans := map[string]*tracing.Span{}
+	for _, span := range recorded() {
+		spans[span.Name()] = span
+	}
+	root, handler, querySpan := spans["HTTP GET"], spans["handler"], spans["birdc status"]
+	if root == nil || handler == nil || querySpan == nil {
+		t.Fatal("Expected the request, handler and query spans, got:", spans)
+	}
+	for _, span := range []*tracing.Span{root, handler, querySpan} {
+		if span.TraceID() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Error("Expected", span.Name(), "in the remote trace, got:", span.TraceID())
+		}
+	}
+	if handler.ParentID() != root.SpanID() || querySpan.ParentID() != handler.SpanID() {
+		t.Error("Expected the query span to be a child of the handler span")
+	}
+}
+
+func TestRateLimitRejections(t *testing.T) {
+	limited := func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool, error) {
+		return nil, false, bird.ErrRateLimited
+	}
+	ps := httprouter.Params{{Key: "protocol", Value: "R192_175"}}
+
+	req := httptest.NewRequest("GET", "/routes/protocol/R192_175", nil)
+
+	before, _, _ := RateLimit(req, nil, true)
+	Endpoint(limited)(httptest.NewRecorder(), req, ps)
+	after, _, _ := RateLimit(req, nil, true)
+
+	for _, key := range []struct{ field, name string }{
+		{"rejected_by_endpoint", "/routes/protocol/:protocol"},
+		{"rejected_by_client", "192.0.2.1"},
+	} {
+		n := after[key.field].(map[string]int)[key.name] -
+			before[key.field].(map[string]int)[key.name]
+		if n != 1 {
+			t.Error("Expected a rejection in", key.field, "for", key.name, "got:", n)
+		}
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	defer func(conf AdminConfig) { AdminConf = conf }(AdminConf)
+	AdminConf.Tokens = []string{"secret"}
+
+	handler := Admin(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for token, status := range map[string]int{
+		"":       http.StatusUnauthorized,
+		"wrong":  http.StatusUnauthorized,
+		"secret": http.StatusNoContent,
+	} {
+		req := httptest.NewRequest("POST", "/admin/reconfigure", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req, nil)
+		if rec.Code != status {
+			t.Error("Expected status", status, "for token", token, "got:", rec.Code)
+		}
+	}
+}
+
+func TestRateLimitAdmin(t *testing.T) {
+	defer func(conf AdminConfig) { AdminConf = conf }(AdminConf)
+	AdminConf = AdminConfig{
+		Tokens:       []string{"secret"},
+		ScopedTokens: []ScopedToken{{Token: "member", Scopes: []string{"status", "routes"}}},
+	}
+
+	handler := Admin(Endpoint(RateLimit))
+	for token, status := range map[string]int{
+		"":       http.StatusUnauthorized,
+		"member": http.StatusUnauthorized,
+		"secret": http.StatusOK,
+	} {
+		req := httptest.NewRequest("GET", "/ratelimit", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req, nil)
+		if rec.Code != status {
+			t.Error("Expected status", status, "for token", token, "got:", rec.Code)
+		}
+		if status != http.StatusOK && strings.Contains(rec.Body.String(), "rejected_by_client") {
+			t.Error("Expected the rejections to be hidden without an admin token")
+		}
+	}
+
+	// Scoped tokens need the admin scope
+	AdminConf.RequireToken = true
+	req := httptest.NewRequest("GET", "/ratelimit", nil)
+	req.Header.Set("Authorization", "Bearer member")
+	rec := httptest.NewRecorder()
+	Endpoint(RateLimit)(rec, req, nil)
+	if rec.Code != http.StatusForbidden {
+		t.Error("Expected a routes token to be forbidden, got:", rec.Code)
+	}
+}
+
+func TestScopedTokens(t *testing.T) {
+	defer func(conf AdminConfig) { AdminConf = conf }(AdminConf)
+	AdminConf = AdminConfig{
+		Tokens:       []string{"secret"},
+		RequireToken: true,
+		ScopedTokens: []ScopedToken{
+			{Token: "status", Scopes: []string{"status"}},
+			{Token: "member", Scopes: []string{"routes"}, Protocols: []string{"R1"}},
+		},
+	}
+
+	protocol := httprouter.Params{{Key: "protocol", Value: "R1"}}
+	other := httprouter.Params{{Key: "protocol", Value: "R2"}}
+	for _, c := range []struct {
+		token, path string
+		ps          httprouter.Params
+		status      int
+	}{
+		{"", "/status", nil, http.StatusUnauthorized},
+		{"wrong", "/status", nil, http.StatusUnauthorized},
+		{"secret", "/routes/protocol/R2", other, 0},
+		{"status", "/status", nil, 0},
+		{"status", "/routes/protocol/R1", protocol, http.StatusForbidden},
+		{"member", "/status", nil, http.StatusForbidden},
+		{"member", "/routes/protocol/R1", protocol, 0},
+		{"member", "/routes/protocol/R2", other, http.StatusForbidden},
+		{"member", "/routes/all", nil, http.StatusForbidden},
+		{"member", "/protocols/bgp", nil, 0},
+	} {
+		req := httptest.NewRequest("GET", c.path, nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		_, status, _ := authorize(req, c.ps)
+		if status != c.status {
+			t.Error("Expected status", c.status, "for", c.token, c.path, "got:", status)
+		}
+	}
+
+	member, _ := lookupToken("member")
+	res := restrictProtocols(member, bird.Parsed{
+		"protocols": bird.Parsed{"R1": bird.Parsed{}, "R2": bird.Parsed{}},
+	})
+	if protocols := res["protocols"].(bird.Parsed); len(protocols) != 1 || protocols["R1"] == nil {
+		t.Error("Expected only the protocol R1, got:", protocols)
+	}
+}
+
+func TestRequireTokenHandlers(t *testing.T) {
+	defer func(conf AdminConfig) { AdminConf = conf }(AdminConf)
+	AdminConf = AdminConfig{
+		Tokens:       []string{"secret"},
+		RequireToken: true,
+		ScopedTokens: []ScopedToken{
+			{Token: "status", Scopes: []string{"status"}},
+		},
+	}
+
+	for _, c := range []struct {
+		token, path string
+		handler     httprouter.Handle
+		status      int
+	}{
+		{"", "/metrics", Metrics, http.StatusUnauthorized},
+		{"status", "/metrics", Metrics, http.StatusForbidden},
+		{"secret", "/metrics", Metrics, http.StatusOK},
+		{"", "/support/bundle", SupportBundle("test", nil), http.StatusUnauthorized},
+		{"status", "/support/bundle", SupportBundle("test", nil), http.StatusForbidden},
+		{"", "/", UI, http.StatusUnauthorized},
+		{"status", "/", UI, http.StatusOK},
+		{"", "/version", Version("test"), http.StatusUnauthorized},
+		{"status", "/version", Version("test"), http.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", c.path, nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		rec := httptest.NewRecorder()
+		c.handler(rec, req, nil)
+		if rec.Code != c.status {
+			t.Error("Expected status", c.status, "for", c.token, c.path, "got:", rec.Code)
+		}
+	}
+}
+
+func TestCacheHeaders(t *testing.T) {
+	now := time.Now()
+
+	rec := httptest.NewRecorder()
+	setCacheHeaders(rec, bird.Parsed{"ttl": now.Add(90 * time.Second)}, now)
+	if cc := rec.Header().Get("Cache-Control"); cc != "max-age=90" {
+		t.Error("Unexpected Cache-Control:", cc)
+	}
+	if rec.Header().Get("Expires") == "" {
+		t.Error("Expected an Expires header")
+	}
+
+	for _, res := range []bird.Parsed{{}, {"ttl": now.Add(-time.Second)}} {
+		rec := httptest.NewRecorder()
+		setCacheHeaders(rec, res, now)
+		if cc := rec.Header().Get("Cache-Control"); cc != "no-cache" {
+			t.Error("Unexpected Cache-Control for", res, "got:", cc)
+		}
+	}
+}
+
+func TestCacheStatusHeaders(t *testing.T) {
+	now := time.Now()
+
+	rec := httptest.NewRecorder()
+	setCacheStatusHeaders(rec, bird.Parsed{"cached_at": now.Add(-42 * time.Second)}, true, now)
+	if rec.Header().Get("X-Cache") != "HIT" || rec.Header().Get("Age") != "42" {
+		t.Error("Unexpected cache status headers:", rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	setCacheStatusHeaders(rec, bird.Parsed{}, false, now)
+	if rec.Header().Get("X-Cache") != "MISS" || rec.Header().Get("Age") != "" {
+		t.Error("Unexpected cache status headers:", rec.Header())
+	}
+}
+
+func TestParseLookupRequest(t *testing.T) {
+	defer func(conf ServerConfig) { Conf = conf }(Conf)
+	Conf.LookupMaxPrefixes = 2
+
+	tests := []struct {
+		body  string
+		valid bool
+	}{
+		{`{"prefixes": ["192.0.2.0/24", "2001:db8::/32"]}`, true},
+		{`{"prefixes": ["192.0.2.0/24"], "table": "T1", "match": "exact"}`, true},
+		{`{"prefixes": []}`, false},
+		{`{"prefixes": ["192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24"]}`, false},
+		{`{"prefixes": ["192.0.2.0/24; reboot"]}`, false},
+		{`{"prefixes": ["192.0.2.0/24"], "match": "fuzzy"}`, false},
+		{`not json`, false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/routes/lookup", strings.NewReader(test.body))
+		req, err := parseLookupRequest(r)
+		if test.valid && err != nil {
+			t.Error("Expected", test.body, "to be valid, got:", err)
+		}
+		if !test.valid && err == nil {
+			t.Error("Expected", test.body, "to be rejected")
+		}
+		if err == nil && req.Table == "" {
+			t.Error("Expected the table to default to master")
+		}
+	}
+}
+
+func TestRouteCountHeaders(t *testing.T) {
+	routes := func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool, error) {
+		return bird.Parsed{"routes": []bird.Parsed{{}, {}, {}}}, false, nil
+	}
+	ps := httprouter.Params{{Key: "protocol", Value: "R1"}}
+
+	tests := []struct {
+		method, path string
+		filtered     string
+	}{
+		{"HEAD", "/routes/protocol/R1", ""},
+		{"GET", "/routes/filtered/R1?count_only=1", "3"},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		Endpoint(routes)(rec, httptest.NewRequest(test.method, test.path, nil), ps)
+
+		if rec.Header().Get("X-Total-Routes") != "3" {
+			t.Error(test.path, "unexpected X-Total-Routes:", rec.Header().Get("X-Total-Routes"))
+		}
+		if rec.Header().Get("X-Filtered-Routes") != test.filtered {
+			t.Error(test.path, "unexpected X-Filtered-Routes:", rec.Header().Get("X-Filtered-Routes"))
+		}
+		if rec.Body.Len() != 0 {
+			t.Error(test.path, "expected no body, got:", rec.Body.String())
+		}
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+	generation := 1
+	routes := func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool, error) {
+		res := []bird.Parsed{}
+		for i := 0; i < 5; i++ {
+			res = append(res, bird.Parsed{"network": fmt.Sprintf("10.%d.%d.0/24", generation, i)})
+		}
+		cachedAt := time.Date(2017, 6, 21, 8, generation, 0, 1234, time.UTC)
+		return bird.Parsed{"routes": res, "cached_at": cachedAt}, true, nil
+	}
+
+	networks := []string{}
+	path := "/routes/table/master?limit=2"
+	for i := 0; i < 5 && path != ""; i++ {
+		res, _, _ := fetchPage(routes, httptest.NewRequest("GET", path, nil), nil, true)
+		for _, route := range res["routes"].([]bird.Parsed) {
+			networks = append(networks, route["network"].(string))
+		}
+		path = ""
+		if cursor, ok := res["pagination"].(bird.Parsed)["next_cursor"].(string); ok {
+			path = "/routes/table/master?cursor=" + cursor
+		}
+	}
+	if len(networks) != 5 || networks[0] != "10.1.0.0/24" || networks[4] != "10.1.4.0/24" {
+		t.Error("Unexpected routes:", networks)
+	}
+
+	// The cursor expires with the cache entry
+	res, _, _ := fetchPage(routes, httptest.NewRequest("GET", "/routes/table/master?limit=2", nil), nil, true)
+	cursor := res["pagination"].(bird.Parsed)["next_cursor"].(string)
+	generation++
+	_, _, err := fetchPage(routes, httptest.NewRequest("GET", "/routes/table/master?cursor="+cursor, nil), nil, true)
+	if !bird.IsQueryError(err, bird.ErrInvalid) {
+		t.Error("Expected an expired cursor, got:", err)
+	}
+
+	res, _, _ = fetchPage(routes, httptest.NewRequest("GET", "/routes/table/master?offset=3", nil), nil, true)
+	if n := len(res["routes"].([]bird.Parsed)); n != 2 {
+		t.Error("Expected 2 routes after the offset, got:", n)
+	}
+
+	_, _, err = fetchPage(routes, httptest.NewRequest("GET", "/routes/table/master?cursor=bogus", nil), nil, true)
+	if !bird.IsQueryError(err, bird.ErrInvalid) {
+		t.Error("Expected an error for an invalid cursor")
+	}
+}
+
+func TestResponseEnvelope(t *testing.T) {
+	defer func(conf ServerConfig) { Conf = conf }(Conf)
+	Conf.ResponseEnvelope = true
+
+	cachedAt := time.Now().UTC().Truncate(time.Second)
+	status := func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool, error) {
+		return bird.Parsed{
+			"status":    bird.Parsed{"version": "2.0.7"},
+			"cached_at": cachedAt,
+			"ttl":       cachedAt.Add(5 * time.Minute),
+		}, true, nil
+	}
+
+	rec := httptest.NewRecorder()
+	Endpoint(status)(rec, httptest.NewRequest("GET", "/status", nil), nil)
+
+	res := struct {
+		API    map[string]interface{} `json:"api"`
+		Cached struct {
+			CachedAt time.Time `json:"cached_at"`
+		} `json:"cached"`
+		Data map[string]interface{} `json:"data"`
+	}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.API == nil || !res.Cached.CachedAt.Equal(cachedAt) {
+		t.Error("Unexpected envelope:", rec.Body.String())
+	}
+	if _, ok := res.Data["status"]; !ok || len(res.Data) != 1 {
+		t.Error("Expected only the status in data, got:", res.Data)
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	protocols := func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool, error) {
+		return bird.Parsed{
+			"protocols": bird.Parsed{
+				"R192_175": bird.Parsed{"bird_protocol": "BGP", "neighbor_as": 1.5},
+			},
+			"route_count":  []interface{}{bird.Parsed{"last_change": nil}},
+			"sessions":     bird.Parsed{"pb_as6695": 2},
+			"table_errors": bird.Parsed{"master_v4": "timeout"},
+		}, false, nil
+	}
+
+	for _, tc := range []struct {
+		config, accept, expected string
+	}{
+		{"", "", `"route_count":[{"last_change":null}]`},
+		{CamelCase, "", `"routeCount":[{"lastChange":null}]`},
+		{CamelCase, "", `"protocols":{"R192_175":{"birdProtocol":"BGP","neighborAs":1.5}}`},
+		{CamelCase, "", `"sessions":{"pb_as6695":2}`},
+		{CamelCase, "", `"tableErrors":{"master_v4":"timeout"}`},
+		{"", `application/json; profile="camelCase"`, `"routeCount"`},
+		{CamelCase, `application/json; profile="snake_case"`, `"route_count"`},
+	} {
+		Conf.FieldNames = tc.config
+		req := httptest.NewRequest("GET", "/protocols", nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		rec := httptest.NewRecorder()
+		Endpoint(protocols)(rec, req, nil)
+		if !strings.Contains(rec.Body.String(), tc.expected) {
+			t.Errorf("Expected %s in %s", tc.expected, rec.Body.String())
+		}
+		if !json.Valid(rec.Body.Bytes()) {
+			t.Error("Invalid json:", rec.Body.String())
+		}
+	}
+	Conf.FieldNames = ""
+}
+
+func TestRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool, error) {
+		<-release
+		return bird.Parsed{"status": "late"}, false, nil
+	}
+
+	rec := httptest.NewRecorder()
+	Endpoint(slow)(rec, httptest.NewRequest("GET", "/status?timeout=20ms", nil), nil)
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Error("Expected a timeout, got:", rec.Code, rec.Body.String())
+	}
+
+	fast := func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool, error) {
+		return bird.Parsed{"status": "ok"}, false, nil
+	}
+	rec = httptest.NewRecorder()
+	Endpoint(fast)(rec, httptest.NewRequest("GET", "/status?timeout=1s", nil), nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"ok"`) {
+		t.Error("Expected the result, got:", rec.Code, rec.Body.String())
+	}
+
+	defer func(conf ServerConfig) { Conf = conf }(Conf)
+	Conf.MaxTimeout = 2
+	req := httptest.NewRequest("GET", "/status?timeout=1h", nil)
+	if timeout, err := requestTimeout(req); err != nil || timeout != 2*time.Second {
+		t.Error("Expected the timeout to be bounded, got:", timeout, err)
+	}
+	req = httptest.NewRequest("GET", "/status?timeout=-1s", nil)
+	if _, err := requestTimeout(req); err == nil {
+		t.Error("Expected an error for a negative timeout")
+	}
+}
+
+func TestFieldAliases(t *testing.T) {
+	protocols := func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool, error) {
+		return bird.Parsed{
+			"protocols": bird.Parsed{
+				"neighbor_as": bird.Parsed{"neighbor_address": "10.0.0.1", "neighbor_as": 65000},
+			},
+		}, false, nil
+	}
+
+	defer func(conf ServerConfig) { Conf = conf }(Conf)
+	Conf.FieldRenames = map[string]string{"neighbor_address": "neighbor_ip"}
+	Conf.FieldAliases = map[string]string{"neighbor_as": "peer_asn"}
+
+	for _, tc := range []struct {
+		naming, expected string
+	}{
+		{SnakeCase, `"protocols":{"neighbor_as":{"neighbor_ip":"10.0.0.1","neighbor_as":65000,"peer_asn":65000}}`},
+		{CamelCase, `"protocols":{"neighbor_as":{"neighbor_ip":"10.0.0.1","neighborAs":65000,"peer_asn":65000}}`},
+	} {
+		Conf.FieldNames = tc.naming
+		rec := httptest.NewRecorder()
+		Endpoint(protocols)(rec, httptest.NewRequest("GET", "/protocols", nil), nil)
+		if !strings.Contains(rec.Body.String(), tc.expected) {
+			t.Errorf("Expected %s in %s", tc.expected, rec.Body.String())
+		}
+		if !json.Valid(rec.Body.Bytes()) {
+			t.Error("Invalid json:", rec.Body.String())
+		}
+	}
+}
+
+func TestTimestampFormat(t *testing.T) {
+	changed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	status := func(*http.Request, httprouter.Params, bool) (bird.Parsed, bool, error) {
+		return bird.Parsed{
+			"status": bird.Parsed{
+				"last_reconfig": changed.Format("2006-01-02 15:04:05"),
+				"message":       "2024-01-02 03:04:05",
+			},
+			"protocol": bird.Parsed{
+				"state_changed":      changed.Format("2006-01-02"), // iso short
+				"state_changed_unix": "kept",
+			},
+			"since": changed,
+		}, false, nil
+	}
+
+	defer func(conf ServerConfig) { Conf = conf }(Conf)
+	rfc3339 := changed.UTC().Format(time.RFC3339)
+	unix := fmt.Sprint(changed.Unix())
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)
+	for _, tc := range []struct {
+		format   string
+		expected []string
+	}{
+		{TimestampRFC3339, []string{`"last_reconfig":"` + rfc3339 + `"`, `"since":"` + rfc3339 + `"`,
+			`"state_changed":"` + day.UTC().Format(time.RFC3339) + `"`}},
+		{TimestampUnix, []string{`"last_reconfig":` + unix, `"since":` + unix,
+			`"state_changed":` + fmt.Sprint(day.Unix())}},
+		{TimestampBoth, []string{`"last_reconfig":"` + rfc3339 + `"`, `"last_reconfig_unix":` + unix,
+			`"state_changed_unix":"kept"}`}},
+	} {
+		Conf.TimestampFormat = tc.format
+		rec := httptest.NewRecorder()
+		Endpoint(status)(rec, httptest.NewRequest("GET", "/status", nil), nil)
+		for _, expected := range append(tc.expected, `"message":"2024-01-02 03:04:05"`) {
+			if !strings.Contains(rec.Body.String(), expected) {
+				t.Errorf("Expected %s with %s in %s", expected, tc.format, rec.Body.String())
+			}
+		}
+		if !json.Valid(rec.Body.Bytes()) {
+			t.Error("Invalid json:", rec.Body.String())
+		}
+		if n := strings.Count(rec.Body.String(), `"state_changed_unix"`); n != 1 {
+			t.Error("Expected the existing state_changed_unix only, got:", rec.Body.String())
+		}
+	}
+}
+
+func TestUI(t *testing.T) {
+	rec := httptest.NewRecorder()
+	UI(rec, httptest.NewRequest("GET", "/", nil), nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Error("Unexpected response:", rec.Code, rec.Header())
+	}
+	if !strings.Contains(rec.Body.String(), `get("protocols/bgp")`) {
+		t.Error("Expected the page to query the API")
+	}
+}
@@ -0,0 +1,42 @@
+package endpoints
+
+import (
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// CacheInfo is the cache status of an enveloped response
+type CacheInfo struct {
+	CachedAt interface{} `json:"cached_at"`
+	TTL      interface{} `json:"ttl"`
+}
+
+// envelope separates the api info, the cache status and the
+// data of a response. Validation errors of the result are
+// moved next to the data.
+func envelope(api *APIInfo, ret bird.Parsed) map[string]interface{} {
+	res := map[string]interface{}{"api": api}
+
+	data := make(bird.Parsed, len(ret))
+	for k, v := range ret {
+		data[k] = v
+	}
+
+	cached, hasCachedAt := data["cached_at"]
+	ttl, hasTTL := data["ttl"]
+	delete(data, "cached_at")
+	delete(data, "ttl")
+	delete(data, "warnings") // Reported in the api info
+	if hasCachedAt || hasTTL {
+		res["cached"] = CacheInfo{CachedAt: cached, TTL: ttl}
+	} else {
+		res["cached"] = nil
+	}
+
+	if err, ok := data["error"]; ok {
+		res["error"] = err
+		delete(data, "error")
+	}
+	res["data"] = data
+
+	return res
+}
@@ -0,0 +1,30 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/events"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Events lists the recent events, newest first.
+// The events can be filtered with ?protocol= and ?type=
+func Events(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	qs := r.URL.Query()
+	protocol := qs.Get("protocol")
+	eventType := qs.Get("type")
+
+	filtered := []*events.Event{}
+	for _, event := range events.Recent() {
+		if protocol != "" && event.Protocol != protocol {
+			continue
+		}
+		if eventType != "" && event.Type != eventType {
+			continue
+		}
+		filtered = append(filtered, event)
+	}
+
+	return bird.Parsed{"events": filtered}, false, nil
+}
@@ -2,6 +2,10 @@ package endpoints
 
 import (
 	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/alice-lg/birdwatcher/bird"
 )
 
 /*
@@ -11,7 +15,7 @@ import (
 // Check if the value is not longer than a given length
 func ValidateLength(value string, maxLength int) error {
 	if len(value) > maxLength {
-		return fmt.Errorf("Provided param value is too long.")
+		return fmt.Errorf("Provided param value is too long, at most %d characters are allowed", maxLength)
 	}
 	return nil
 }
@@ -26,7 +30,7 @@ func ValidateCharset(value string, alphabet string) error {
 			}
 		}
 		if !ok {
-			return fmt.Errorf("Invalid character in param value")
+			return fmt.Errorf("Invalid character %q in param value", check)
 		}
 	}
 	return nil
@@ -47,13 +51,52 @@ func ValidateLengthAndCharset(value string, maxLength int, alphabet string) (str
 }
 
 func ValidateProtocolParam(value string) (string, error) {
+	if value == "" {
+		return "", fmt.Errorf("Missing param value")
+	}
 	return ValidateLengthAndCharset(value, 80, "ABCDEFGHIJKLMNOPQRSTUVWXYZ_:.abcdefghijklmnopqrstuvwxyz1234567890")
 }
 
+// ValidatePrefixParam checks an address or a prefix
+// like 10.0.0.0/8
 func ValidatePrefixParam(value string) (string, error) {
-	return ValidateLengthAndCharset(value, 80, "1234567890abcdef.:/")
+	if _, err := ValidateLengthAndCharset(value, 80, "1234567890abcdef.:/"); err != nil {
+		return "", err
+	}
+	if net.ParseIP(value) == nil {
+		if _, _, err := net.ParseCIDR(value); err != nil {
+			return "", fmt.Errorf("Invalid address or prefix")
+		}
+	}
+	return value, nil
 }
 
 func ValidateNetMaskParam(value string) (string, error) {
-	return ValidateLengthAndCharset(value, 3, "1234567890")
+	if _, err := ValidateLengthAndCharset(value, 3, "1234567890"); err != nil {
+		return "", err
+	}
+	if mask, err := strconv.Atoi(value); err != nil || mask > 128 {
+		return "", fmt.Errorf("Invalid netmask, use 0 to 128")
+	}
+	return value, nil
+}
+
+// ValidatePrefixMask checks the netmask against the
+// address family of the network, e.g. at most 32 for IPv4.
+func ValidatePrefixMask(network, mask string) (string, error) {
+	prefix := network + "/" + mask
+	if _, _, err := net.ParseCIDR(prefix); err != nil {
+		return "", fmt.Errorf("Invalid netmask %s for %s", mask, network)
+	}
+	return prefix, nil
+}
+
+// invalidParam is the result of a request with an invalid
+// parameter. Errors naming another field are kept.
+func invalidParam(field string, err error) (bird.Parsed, bool, error) {
+	fieldErr, ok := err.(*bird.FieldError)
+	if !ok {
+		fieldErr = &bird.FieldError{Field: field, Message: err.Error()}
+	}
+	return nil, false, bird.InvalidRequest(fieldErr)
 }
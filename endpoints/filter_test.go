@@ -36,3 +36,29 @@ func TestValidateProtocol(t *testing.T) {
 	}
 
 }
+
+func TestValidatePrefix(t *testing.T) {
+	for _, param := range []string{"10.0.0.1", "10.0.0.0/8", "2001:db8::/32"} {
+		if _, err := ValidatePrefixParam(param); err != nil {
+			t.Error(param, "should be a valid prefix param:", err)
+		}
+	}
+	for _, param := range []string{"", "10.0.0", "10.0.0.0/33", "10.0.0.0/8/8", "::1::2"} {
+		if _, err := ValidatePrefixParam(param); err == nil {
+			t.Error(param, "should be an invalid prefix param")
+		}
+	}
+}
+
+func TestValidatePrefixMask(t *testing.T) {
+	for network, mask := range map[string]string{"10.0.0.0": "8", "2001:db8::": "64"} {
+		if _, err := ValidatePrefixMask(network, mask); err != nil {
+			t.Error(network, mask, "should be a valid prefix:", err)
+		}
+	}
+	for network, mask := range map[string]string{"10.0.0.0": "33", "10.0.0.0/8": "8", "2001:db8::": "129"} {
+		if _, err := ValidatePrefixMask(network, mask); err == nil {
+			t.Error(network, mask, "should be an invalid prefix")
+		}
+	}
+}
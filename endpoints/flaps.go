@@ -0,0 +1,19 @@
+package endpoints
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/flaps"
+	"github.com/julienschmidt/httprouter"
+)
+
+func ProtocolHistory(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	protocol, err := ValidateProtocolParam(ps.ByName("protocol"))
+	if err != nil {
+		return invalidParam("protocol", err)
+	}
+
+	return flaps.History(protocol, time.Now().UTC()), false, nil
+}
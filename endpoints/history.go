@@ -0,0 +1,138 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/history"
+	"github.com/julienschmidt/httprouter"
+)
+
+// parseTimeParam accepts RFC3339 timestamps and unix
+// seconds. An empty value returns the default.
+func parseTimeParam(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("Invalid time: %s", value)
+	}
+	return t.UTC(), nil
+}
+
+func HistoryProtocols(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	qs := r.URL.Query()
+
+	now := time.Now().UTC()
+	to, err := parseTimeParam(qs.Get("to"), now)
+	if err != nil {
+		return invalidParam("to", err)
+	}
+	from, err := parseTimeParam(qs.Get("from"), to.Add(-24*time.Hour))
+	if err != nil {
+		return invalidParam("from", err)
+	}
+
+	protocol := ""
+	if qs.Get("protocol") != "" {
+		protocol, err = ValidateProtocolParam(qs.Get("protocol"))
+		if err != nil {
+			return invalidParam("protocol", err)
+		}
+	}
+
+	snapshots, err := history.Snapshots(from, to, protocol)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return bird.Parsed{
+		"from":      from,
+		"to":        to,
+		"snapshots": snapshots,
+	}, false, nil
+}
+
+// snapshotParam returns the stored snapshot at the given
+// time, or a snapshot of the current protocols for "now".
+func snapshotParam(value string, useCache bool) (*history.Snapshot, error) {
+	if value == "now" {
+		return history.CurrentSnapshot(useCache)
+	}
+	t, err := parseTimeParam(value, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return history.SnapshotAt(t)
+}
+
+func HistoryDiff(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	qs := r.URL.Query()
+	if qs.Get("from") == "" {
+		return invalidParam("from", fmt.Errorf("need a from timestamp as query parameter"))
+	}
+
+	to := qs.Get("to")
+	if to == "" {
+		to = "now"
+	}
+
+	fromSnapshot, err := snapshotParam(qs.Get("from"), useCache)
+	if err != nil {
+		return invalidParam("from", err)
+	}
+	toSnapshot, err := snapshotParam(to, useCache)
+	if err != nil {
+		return invalidParam("to", err)
+	}
+
+	return bird.Parsed{"diff": history.DiffSnapshots(fromSnapshot, toSnapshot)}, false, nil
+}
+
+// ProtocolsChanges returns the protocols which changed since
+// the snapshot taken at or before ?since=
+func ProtocolsChanges(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	value := r.URL.Query().Get("since")
+	if value == "" {
+		return invalidParam("since", fmt.Errorf("need a since timestamp as query parameter"))
+	}
+	since, err := parseTimeParam(value, time.Time{})
+	if err != nil {
+		return invalidParam("since", err)
+	}
+
+	res, fromCache, err := bird.Protocols(r.Context(), useCache)
+	if err != nil {
+		return nil, false, err
+	}
+	protocols, _ := res["protocols"].(bird.Parsed)
+
+	changes, err := history.ChangesSince(since, protocols)
+	if err != nil {
+		return nil, false, err
+	}
+
+	changed := make(bird.Parsed, len(changes.Changed))
+	for _, name := range changes.Changed {
+		changed[name] = protocols[name]
+	}
+	result := bird.Parsed{
+		"since":     since,
+		"snapshot":  changes.Snapshot,
+		"protocols": changed,
+		"removed":   changes.Removed,
+	}
+	for _, key := range []string{"ttl", "cached_at"} {
+		if v, ok := res[key]; ok {
+			result[key] = v
+		}
+	}
+	return result, fromCache, nil
+}
@@ -0,0 +1,111 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/julienschmidt/httprouter"
+)
+
+// A LookupRequest is the body of a bulk prefix lookup
+type LookupRequest struct {
+	Prefixes []string `json:"prefixes"`
+	Table    string   `json:"table"`
+	Match    string   `json:"match"`
+}
+
+// parseLookupRequest decodes and validates the request body
+func parseLookupRequest(r *http.Request) (*LookupRequest, error) {
+	maxPrefixes := Conf.LookupMaxPrefixes
+	if maxPrefixes <= 0 {
+		maxPrefixes = 100
+	}
+
+	req := &LookupRequest{}
+	body := http.MaxBytesReader(nil, r.Body, 1<<20)
+	if err := json.NewDecoder(body).Decode(req); err != nil {
+		return nil, &bird.FieldError{Field: "body", Message: fmt.Sprintf("Invalid lookup request: %s", err)}
+	}
+
+	if len(req.Prefixes) == 0 {
+		return nil, fmt.Errorf("No prefixes to look up")
+	}
+	if len(req.Prefixes) > maxPrefixes {
+		return nil, fmt.Errorf("Too many prefixes, at most %d are allowed", maxPrefixes)
+	}
+	for i, prefix := range req.Prefixes {
+		if _, err := ValidatePrefixParam(prefix); err != nil {
+			return nil, &bird.FieldError{
+				Field:   fmt.Sprintf("prefixes[%d]", i),
+				Message: fmt.Sprintf("Invalid prefix: %s", prefix),
+			}
+		}
+	}
+
+	if req.Table == "" {
+		req.Table = "master"
+	}
+	if _, err := ValidateProtocolParam(req.Table); err != nil {
+		return nil, &bird.FieldError{Field: "table", Message: err.Error()}
+	}
+
+	mode, err := bird.ParseMatchMode(req.Match)
+	if err != nil {
+		return nil, &bird.FieldError{Field: "match", Message: err.Error()}
+	}
+	req.Match = mode
+
+	return req, nil
+}
+
+// RoutesLookup looks up the routes of many prefixes in a
+// table with bounded concurrency. Failed lookups have an
+// error instead of routes.
+func RoutesLookup(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	req, err := parseLookupRequest(r)
+	if err != nil {
+		return invalidParam("prefixes", err)
+	}
+
+	concurrency := Conf.LookupConcurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	results := make([]bird.Parsed, len(req.Prefixes))
+	cached := make([]bool, len(req.Prefixes))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, prefix := range req.Prefixes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, prefix string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			res, fromCache, err := bird.RoutesLookupTableMatch(r.Context(), useCache, prefix, req.Table, req.Match)
+			result := bird.Parsed{"prefix": prefix}
+			if err != nil {
+				result["error"] = err
+			} else {
+				result["routes"] = res["routes"]
+			}
+			results[i] = result
+			cached[i] = fromCache
+		}(i, prefix)
+	}
+	wg.Wait()
+
+	fromCache := true
+	for _, c := range cached {
+		fromCache = fromCache && c
+	}
+
+	return bird.Parsed{"results": results}, fromCache, nil
+}
@@ -0,0 +1,18 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/alice-lg/birdwatcher/metrics"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Metrics exposes all metrics in the prometheus text format
+func Metrics(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := checkRequest(w, r, ps); !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics.Write(w)
+}
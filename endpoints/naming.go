@@ -0,0 +1,284 @@
+package endpoints
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Field naming conventions of the responses
+const (
+	SnakeCase = "snake_case"
+	CamelCase = "camelCase"
+)
+
+// Objects of these fields are keyed by names, e.g. of
+// protocols, sessions or tables, which must not be renamed.
+var namedObjectFields = map[string]bool{
+	"protocols":            true,
+	"churn":                true,
+	"sessions":             true,
+	"session_errors":       true,
+	"tables":               true,
+	"table_errors":         true,
+	"symbols":              true,
+	"rejected_by_client":   true,
+	"rejected_by_endpoint": true,
+}
+
+var reSnakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(?:_[a-z0-9]+)+$`)
+
+// toCamelCase converts snake_case field names,
+// e.g. cached_at -> cachedAt. Other keys are kept.
+func toCamelCase(key string) string {
+	if !reSnakeCase.MatchString(key) {
+		return key
+	}
+	parts := strings.Split(key, "_")
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.Join(parts, "")
+}
+
+// fieldNaming selects the naming convention from an Accept
+// header like "application/json; profile=camelCase", or
+// the configured field_names.
+func fieldNaming(r *http.Request) string {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		switch params["profile"] {
+		case CamelCase, SnakeCase:
+			return params["profile"]
+		}
+	}
+	if Conf.FieldNames == CamelCase {
+		return CamelCase
+	}
+	return SnakeCase
+}
+
+// fieldRenamer renames a field with the configured renames,
+// other fields follow the naming convention.
+func fieldRenamer(naming string) func(string) string {
+	return func(key string) string {
+		if renamed, ok := Conf.FieldRenames[key]; ok {
+			return renamed
+		}
+		if naming == CamelCase {
+			return toCamelCase(key)
+		}
+		return key
+	}
+}
+
+// encodeResponse writes the response as json using the
+// field naming convention, the configured renames and
+// aliases of fields and the timestamp format.
+func encodeResponse(w io.Writer, res interface{}, naming string) error {
+	if naming != CamelCase && len(Conf.FieldRenames) == 0 && len(Conf.FieldAliases) == 0 &&
+		Conf.TimestampFormat == "" {
+		return json.NewEncoder(w).Encode(res)
+	}
+
+	// Rename the keys while encoding
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(json.NewEncoder(pw).Encode(res))
+	}()
+	err := renameKeys(w, pr, fieldRenamer(naming), Conf.FieldAliases)
+	pr.CloseWithError(err)
+	return err
+}
+
+// A jsonFrame is an object or array while renaming keys
+type jsonFrame struct {
+	object  bool
+	named   bool   // Keys are names and kept
+	tokens  int    // Number of keys and values read
+	lastKey string // Original name of the last key
+
+	// With timestamp_format "both", the original keys of the
+	// object and the unix timestamps added when it is closed
+	keys map[string]bool
+	unix []unixTimestamp
+}
+
+// A unixTimestamp is added to an object as <key>_unix
+type unixTimestamp struct {
+	key   string
+	value interface{}
+}
+
+// renameKeys copies a json document and renames all object keys.
+// The values of fields with an alias are repeated with the alias.
+// Timestamps are written in the configured timestamp format.
+func renameKeys(dst io.Writer, src io.Reader, rename func(string) string, aliases map[string]string) error {
+	dec := json.NewDecoder(src)
+	dec.UseNumber()
+	out := bufio.NewWriter(dst)
+	stack := []*jsonFrame{}
+
+	write := func(v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var top *jsonFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		closing := tok == json.Delim('}') || tok == json.Delim(']')
+		isKey := top != nil && top.object && top.tokens%2 == 0 && !closing
+		if key, ok := tok.(string); ok && isKey && top.keys != nil {
+			top.keys[key] = true
+		}
+
+		// Separators
+		if top != nil && !closing {
+			if top.tokens > 0 {
+				if top.object && top.tokens%2 == 1 {
+					out.WriteByte(':')
+				} else {
+					out.WriteByte(',')
+				}
+			}
+			top.tokens++
+		}
+
+		switch t := tok.(type) {
+		case json.Delim:
+			if t == '}' {
+				if err := writeUnixTimestamps(out, top, rename); err != nil {
+					return err
+				}
+			}
+			out.WriteByte(byte(t))
+			if closing {
+				stack = stack[:len(stack)-1]
+				if len(stack) == 0 {
+					out.WriteByte('\n')
+				}
+				continue
+			}
+			frame := &jsonFrame{
+				object: t == '{',
+				named:  top != nil && top.object && namedObjectFields[top.lastKey],
+			}
+			if frame.object && Conf.TimestampFormat == TimestampBoth {
+				frame.keys = map[string]bool{}
+			}
+			stack = append(stack, frame)
+		case string:
+			if isKey {
+				top.lastKey = t
+				if alias, ok := aliases[t]; ok && !top.named {
+					top.tokens++ // The value is copied here
+					err = copyAliased(out, dec, rename(t), alias, rename, aliases)
+					break
+				}
+				if !top.named {
+					t = rename(t)
+				}
+				err = write(t)
+				break
+			}
+			if top == nil || !top.object {
+				err = write(t)
+				break
+			}
+			value, unix, ok := formatTimestamp(top.lastKey, t)
+			if err = write(value); err != nil || !ok || unix == nil {
+				break
+			}
+			top.unix = append(top.unix, unixTimestamp{key: top.lastKey + "_unix", value: unix})
+		case json.Number:
+			_, err = out.WriteString(t.String())
+		default: // bool and null
+			err = write(t)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+// writeUnixTimestamps adds the unix timestamps to the
+// closed object, unless it has a field with their name.
+func writeUnixTimestamps(out *bufio.Writer, frame *jsonFrame, rename func(string) string) error {
+	for _, unix := range frame.unix {
+		if frame.keys[unix.key] {
+			continue
+		}
+		key, err := json.Marshal(rename(unix.key))
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(unix.value)
+		if err != nil {
+			return err
+		}
+		out.WriteByte(',')
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	return nil
+}
+
+// copyAliased reads the value of a field and writes
+// it with the key and again with the alias.
+func copyAliased(
+	out *bufio.Writer,
+	dec *json.Decoder,
+	key, alias string,
+	rename func(string) string,
+	aliases map[string]string,
+) error {
+	raw := json.RawMessage{}
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	value := bytes.Buffer{}
+	if err := renameKeys(&value, bytes.NewReader(raw), rename, aliases); err != nil {
+		return err
+	}
+	encodedValue := bytes.TrimRight(value.Bytes(), "\n")
+
+	for i, k := range []string{key, alias} {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(k)
+		if err != nil {
+			return err
+		}
+		out.Write(encodedKey)
+		out.WriteByte(':')
+		out.Write(encodedValue)
+	}
+	return nil
+}
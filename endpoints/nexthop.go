@@ -0,0 +1,22 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/julienschmidt/httprouter"
+)
+
+// withResolveNexthop adds the resolved next hops to the
+// routes with ?resolve_nexthop=true. It wraps the pagination,
+// so only the routes of the page are resolved.
+func withResolveNexthop(wrapped endpoint) endpoint {
+	return func(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+		res, fromCache, err := wrapped(r, ps, useCache)
+		if r.URL.Query().Get("resolve_nexthop") != "true" || err != nil {
+			return res, fromCache, err
+		}
+		resolved, cached := bird.ResolveNexthops(r.Context(), useCache, res)
+		return resolved, fromCache && cached, nil
+	}
+}
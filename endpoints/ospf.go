@@ -0,0 +1,26 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/julienschmidt/httprouter"
+)
+
+// OspfTopology returns the routers, networks and links of
+// the OSPF areas, of the protocol given with ?protocol=.
+// With ?state=true stub networks and externals are included.
+func OspfTopology(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	qs := r.URL.Query()
+
+	protocol := ""
+	if qs.Get("protocol") != "" {
+		var err error
+		protocol, err = ValidateProtocolParam(qs.Get("protocol"))
+		if err != nil {
+			return invalidParam("protocol", err)
+		}
+	}
+
+	return bird.OspfTopology(r.Context(), useCache, protocol, qs.Get("state") == "true")
+}
@@ -0,0 +1,142 @@
+package endpoints
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/julienschmidt/httprouter"
+)
+
+const maxPageSize = 10000
+
+// encodeCursor encodes the time the result was cached
+// and the offset and limit of the next page.
+func encodeCursor(cachedAt time.Time, offset, limit int) string {
+	cursor := fmt.Sprintf("%d:%d:%d", cachedAt.UnixNano(), offset, limit)
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor))
+}
+
+func decodeCursor(cursor string) (time.Time, int, int, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, 0, fmt.Errorf("Invalid cursor")
+	}
+	parts := strings.Split(string(buf), ":")
+	if len(parts) != 3 {
+		return time.Time{}, 0, 0, fmt.Errorf("Invalid cursor")
+	}
+	cachedAt, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, 0, fmt.Errorf("Invalid cursor")
+	}
+	offset, err := strconv.Atoi(parts[1])
+	if err != nil || offset < 0 {
+		return time.Time{}, 0, 0, fmt.Errorf("Invalid cursor")
+	}
+	limit, err := strconv.Atoi(parts[2])
+	if err != nil || limit < 1 || limit > maxPageSize {
+		return time.Time{}, 0, 0, fmt.Errorf("Invalid cursor")
+	}
+	return time.Unix(0, cachedAt), offset, limit, nil
+}
+
+// pageParams reads ?limit= and ?offset=
+func pageParams(r *http.Request) (limit, offset int, err error) {
+	qs := r.URL.Query()
+	if v := qs.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, 0, fmt.Errorf("Invalid limit, use 1 to %d", maxPageSize)
+		}
+	}
+	if v := qs.Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, &bird.FieldError{Field: "offset", Message: "Invalid offset"}
+		}
+	}
+	return limit, offset, nil
+}
+
+// page returns a copy of the result with the routes of the
+// page and the pagination info. Pages of cached results with
+// a limit include a next_cursor for the following page.
+func page(result bird.Parsed, routes []bird.Parsed, limit, offset int) bird.Parsed {
+	res := make(bird.Parsed, len(result)+1)
+	for k, v := range result {
+		res[k] = v
+	}
+
+	end := len(routes)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	start := offset
+	if start > end {
+		start = end
+	}
+	res["routes"] = routes[start:end]
+
+	pagination := bird.Parsed{
+		"offset": offset,
+		"limit":  limit,
+		"total":  len(routes),
+	}
+	if cachedAt, ok := bird.CachedAt(result); ok && limit > 0 && end < len(routes) {
+		pagination["next_cursor"] = encodeCursor(cachedAt, end, limit)
+	}
+	res["pagination"] = pagination
+	return res
+}
+
+// fetchPage calls the endpoint and paginates the routes with
+// ?limit= and ?offset=. A page of a cached result started with
+// a limit includes a cursor for the next page. Requests with
+// ?cursor= are answered from the same cache entry, and fail
+// once it was refreshed.
+func fetchPage(wrapped endpoint, r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	limit, offset, err := pageParams(r)
+	if err != nil {
+		return invalidParam("limit", err)
+	}
+
+	var cursorCachedAt time.Time
+	cursor := r.URL.Query().Get("cursor")
+	if cursor != "" {
+		var cursorLimit int
+		cursorCachedAt, offset, cursorLimit, err = decodeCursor(cursor)
+		if err != nil {
+			return invalidParam("cursor", err)
+		}
+		if limit == 0 {
+			limit = cursorLimit
+		}
+	}
+
+	res, fromCache, err := wrapped(r, ps, useCache)
+	if err != nil {
+		return nil, false, err
+	}
+	if cursor != "" {
+		if cachedAt, ok := bird.CachedAt(res); !ok || !cachedAt.Equal(cursorCachedAt) {
+			return invalidParam("cursor", fmt.Errorf("Cursor expired, start again without a cursor"))
+		}
+	}
+	routes, ok := res["routes"].([]bird.Parsed)
+	if !ok || (limit == 0 && offset == 0) {
+		return res, fromCache, nil
+	}
+	return page(res, routes, limit, offset), fromCache, nil
+}
+
+// withPagination paginates the routes of the endpoint
+func withPagination(wrapped endpoint) endpoint {
+	return func(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+		return fetchPage(wrapped, r, ps, useCache)
+	}
+}
@@ -1,20 +1,60 @@
 package endpoints
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/alice-lg/birdwatcher/bird"
 	"github.com/julienschmidt/httprouter"
 )
 
-func Protocols(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
-	return bird.Protocols(useCache)
+// protocolsInState filters the result of the query
+// by the ?state= parameter.
+func protocolsInState(r *http.Request, useCache bool, query func(context.Context, bool) (bird.Parsed, bool, error)) (bird.Parsed, bool, error) {
+	states, err := bird.ParseProtocolStates(r.URL.Query().Get("state"))
+	if err != nil {
+		return invalidParam("state", err)
+	}
+
+	res, fromCache, err := query(r.Context(), useCache)
+	if len(states) == 0 || err != nil {
+		return res, fromCache, err
+	}
+	return bird.FilterProtocolsState(res, states), fromCache, nil
+}
+
+func Protocols(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	return protocolsInState(r, useCache, bird.Protocols)
 }
 
-func Bgp(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
-	return bird.ProtocolsBgp(useCache)
+func Bgp(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	return protocolsInState(r, useCache, bird.ProtocolsBgp)
 }
 
-func ProtocolsShort(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
-	return bird.ProtocolsShort(useCache)
+func ProtocolsShort(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	return protocolsInState(r, useCache, bird.ProtocolsShort)
+}
+
+func ProtocolsSummary(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	return protocolsInState(r, useCache, bird.ProtocolsSummary)
+}
+
+func ProtocolsASN(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	asn, err := bird.ParseASN(ps.ByName("asn"))
+	if err != nil {
+		return invalidParam("asn", err)
+	}
+
+	return protocolsInState(r, useCache, func(ctx context.Context, useCache bool) (bird.Parsed, bool, error) {
+		return bird.ProtocolsASN(ctx, useCache, asn)
+	})
+}
+
+func ProtocolsDown(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	minDuration, err := bird.ParseMinDuration(r.URL.Query().Get("min_duration"))
+	if err != nil {
+		return invalidParam("min_duration", err)
+	}
+
+	return bird.ProtocolsDown(r.Context(), useCache, minDuration)
 }
@@ -0,0 +1,72 @@
+package endpoints
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Rejected requests by client and by endpoint
+var rejections = struct {
+	sync.Mutex
+	clients   map[string]int
+	endpoints map[string]int
+}{
+	clients:   map[string]int{},
+	endpoints: map[string]int{},
+}
+
+// routePattern restores the route of a request by
+// replacing the parameter values in the path with
+// their names, e.g. /routes/protocol/:protocol
+func routePattern(path string, ps httprouter.Params) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		for _, p := range ps {
+			if segment != "" && segment == p.Value {
+				segments[i] = ":" + p.Key
+				break
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// recordRejection counts a rate limited request
+func recordRejection(r *http.Request, ps httprouter.Params) {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+
+	rejections.Lock()
+	defer rejections.Unlock()
+	rejections.clients[client]++
+	rejections.endpoints[routePattern(r.URL.Path, ps)]++
+}
+
+// RateLimit shows the rate limiter configuration, the
+// remaining requests and the rejections per client and endpoint.
+func RateLimit(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	res := bird.RateLimitStatus()
+
+	rejections.Lock()
+	defer rejections.Unlock()
+
+	clients := make(map[string]int, len(rejections.clients))
+	for k, v := range rejections.clients {
+		clients[k] = v
+	}
+	endpoints := make(map[string]int, len(rejections.endpoints))
+	for k, v := range rejections.endpoints {
+		endpoints[k] = v
+	}
+	res["rejected_by_client"] = clients
+	res["rejected_by_endpoint"] = endpoints
+
+	return res, false, nil
+}
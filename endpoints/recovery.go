@@ -0,0 +1,35 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/metrics"
+)
+
+var panicsRecovered = metrics.NewCounter(
+	"birdwatcher_panics_recovered_total",
+	"Number of panics in request handlers recovered")
+
+// PanicHandler responds with an internal server error
+// when a handler panics, e.g. in a type assertion on
+// unexpected bird output, and logs the stack trace.
+func PanicHandler(w http.ResponseWriter, r *http.Request, err interface{}) {
+	panicsRecovered.Inc()
+	log.Printf("Recovered panic in %s %s (request %s): %v\n%s",
+		r.Method, r.URL.Path, GetRequestID(r), err, debug.Stack())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	js, _ := json.Marshal(map[string]interface{}{
+		"request_id": GetRequestID(r),
+		"error": &bird.QueryError{
+			Code:    "internal_error",
+			Message: "internal server error",
+		},
+	})
+	w.Write(js)
+}
@@ -0,0 +1,50 @@
+package endpoints
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// Incoming request IDs are used if they are not
+// longer than this and only use a safe charset.
+const maxRequestIDLength = 64
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	return ValidateCharset(id,
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_.") == nil
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// RequestID honors or generates an X-Request-ID for every
+// request. The ID is set on the request, so handlers and
+// the access log can use it, on the request context for the
+// logs of the bird queries and on the response.
+func RequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		h.ServeHTTP(w, r.WithContext(bird.WithRequestID(r.Context(), id)))
+	})
+}
+
+// GetRequestID returns the ID of the request
+func GetRequestID(r *http.Request) string {
+	return r.Header.Get(RequestIDHeader)
+}
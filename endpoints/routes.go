@@ -1,235 +1,339 @@
 package endpoints
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/enrich"
 	"github.com/julienschmidt/httprouter"
 )
 
-func ProtoRoutes(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func ProtoRoutes(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	protocol, err := ValidateProtocolParam(ps.ByName("protocol"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("protocol", err)
 	}
 
-	return bird.RoutesProto(useCache, protocol)
+	if where := r.URL.Query().Get("where"); where != "" {
+		where, err := bird.ValidateWhere(where)
+		if err != nil {
+			return invalidParam("where", err)
+		}
+		return bird.RoutesProtoWhere(r.Context(), useCache, protocol, where)
+	}
+
+	return bird.RoutesProto(r.Context(), useCache, protocol)
 }
 
-func RoutesFiltered(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func RoutesFiltered(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	protocol, err := ValidateProtocolParam(ps.ByName("protocol"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("protocol", err)
 	}
 
-	return bird.RoutesFiltered(useCache, protocol)
+	return enrich.FilterReasons(bird.RoutesFiltered(r.Context(), useCache, protocol))
 }
 
-func RoutesExport(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func RoutesExport(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	protocol, err := ValidateProtocolParam(ps.ByName("protocol"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("protocol", err)
+	}
+
+	return bird.RoutesExport(r.Context(), useCache, protocol)
+}
+
+func RoutesExportASN(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	asn, err := bird.ParseASN(ps.ByName("asn"))
+	if err != nil {
+		return invalidParam("asn", err)
 	}
 
-	return bird.RoutesExport(useCache, protocol)
+	return bird.RoutesExportASN(r.Context(), useCache, asn)
 }
 
-func RoutesNoExport(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func RoutesNoExport(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	protocol, err := ValidateProtocolParam(ps.ByName("protocol"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("protocol", err)
 	}
 
-	return bird.RoutesNoExport(useCache, protocol)
+	return bird.RoutesNoExport(r.Context(), useCache, protocol)
 }
 
-func RoutesPrefixed(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func RoutesPrefixed(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	qs := r.URL.Query()
 	prefixl := qs["prefix"]
 	if len(prefixl) != 1 {
-		return bird.Parsed{"error": "need a prefix as single query parameter"}, false
+		return invalidParam("prefix", fmt.Errorf("need a prefix as single query parameter"))
 	}
 
 	prefix, err := ValidatePrefixParam(prefixl[0])
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("prefix", err)
 	}
 
-	return bird.RoutesPrefixed(useCache, prefix)
+	return bird.RoutesPrefixed(r.Context(), useCache, prefix)
 }
 
-func TableRoutes(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func TableRoutes(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	table, err := ValidateProtocolParam(ps.ByName("table"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("table", err)
 	}
 
-	return bird.RoutesTable(useCache, table)
+	if where := r.URL.Query().Get("where"); where != "" {
+		where, err := bird.ValidateWhere(where)
+		if err != nil {
+			return invalidParam("where", err)
+		}
+		return bird.RoutesTableWhere(r.Context(), useCache, table, where)
+	}
+
+	return bird.RoutesTable(r.Context(), useCache, table)
 }
 
-func TableRoutesFiltered(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func TableRoutesFiltered(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	table, err := ValidateProtocolParam(ps.ByName("table"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("table", err)
 	}
 
-	return bird.RoutesTableFiltered(useCache, table)
+	return enrich.FilterReasons(bird.RoutesTableFiltered(r.Context(), useCache, table))
 }
 
-func TableAndPeerRoutes(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func TableAndPeerRoutes(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	table, err := ValidateProtocolParam(ps.ByName("table"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("table", err)
 	}
 
 	peer, err := ValidatePrefixParam(ps.ByName("peer"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("peer", err)
 	}
 
-	return bird.RoutesTableAndPeer(useCache, table, peer)
+	return bird.RoutesTableAndPeer(r.Context(), useCache, table, peer)
 }
 
-func ProtoCount(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func ProtoCount(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	protocol, err := ValidateProtocolParam(ps.ByName("protocol"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("protocol", err)
 	}
 
-	return bird.RoutesProtoCount(useCache, protocol)
+	return bird.RoutesProtoCount(r.Context(), useCache, protocol)
 }
 
-func ProtoPrimaryCount(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func ProtoPrimaryCount(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	protocol, err := ValidateProtocolParam(ps.ByName("protocol"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("protocol", err)
 	}
-	return bird.RoutesProtoPrimaryCount(useCache, protocol)
+	return bird.RoutesProtoPrimaryCount(r.Context(), useCache, protocol)
 }
 
-func TableCount(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func TableCount(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	table, err := ValidateProtocolParam(ps.ByName("table"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("table", err)
 	}
 
-	return bird.RoutesTableCount(useCache, table)
+	return bird.RoutesTableCount(r.Context(), useCache, table)
 }
 
-func RouteNet(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func RouteNet(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	net, err := ValidatePrefixParam(ps.ByName("net"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("net", err)
+	}
+
+	mode, err := bird.ParseMatchMode(r.URL.Query().Get("match"))
+	if err != nil {
+		return invalidParam("match", err)
 	}
 
-	return bird.RoutesLookupTable(useCache, net, "master")
+	return bird.RoutesLookupTableMatch(r.Context(), useCache, net, "master", mode)
 }
 
-func RouteNetMask(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func RouteNetMask(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	net, err := ValidatePrefixParam(ps.ByName("net"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("net", err)
 	}
 
 	mask, err := ValidateNetMaskParam(ps.ByName("mask"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("mask", err)
+	}
+	prefix, err := ValidatePrefixMask(net, mask)
+	if err != nil {
+		return invalidParam("mask", err)
+	}
+
+	mode, err := bird.ParseMatchMode(r.URL.Query().Get("match"))
+	if err != nil {
+		return invalidParam("match", err)
 	}
 
-	return bird.RoutesLookupTable(useCache, net+"/"+mask, "master")
+	return bird.RoutesLookupTableMatch(r.Context(), useCache, prefix, "master", mode)
 }
 
-func RouteNetTable(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func RouteNetTable(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	net, err := ValidatePrefixParam(ps.ByName("net"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("net", err)
 	}
 
 	table, err := ValidateProtocolParam(ps.ByName("table"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("table", err)
+	}
+
+	mode, err := bird.ParseMatchMode(r.URL.Query().Get("match"))
+	if err != nil {
+		return invalidParam("match", err)
 	}
 
-	return bird.RoutesLookupTable(useCache, net, table)
+	return bird.RoutesLookupTableMatch(r.Context(), useCache, net, table, mode)
 }
 
-func RouteNetMaskTable(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func RouteNetMaskTable(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	net, err := ValidatePrefixParam(ps.ByName("net"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("net", err)
 	}
 
 	mask, err := ValidateNetMaskParam(ps.ByName("mask"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("mask", err)
+	}
+	prefix, err := ValidatePrefixMask(net, mask)
+	if err != nil {
+		return invalidParam("mask", err)
 	}
 
 	table, err := ValidateProtocolParam(ps.ByName("table"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("table", err)
+	}
+
+	mode, err := bird.ParseMatchMode(r.URL.Query().Get("match"))
+	if err != nil {
+		return invalidParam("match", err)
 	}
 
-	return bird.RoutesLookupTable(useCache, net+"/"+mask, table)
+	return bird.RoutesLookupTableMatch(r.Context(), useCache, prefix, table, mode)
 }
 
-func PipeRoutesFiltered(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
-	qs := r.URL.Query()
+// pipeAndTableParams returns the pipe and table from the
+// query, or resolves them for a peer ?protocol= using the
+// configured peer table rules or the discovered tables.
+func pipeAndTableParams(ctx context.Context, qs url.Values, useCache bool) (string, string, error) {
+	if len(qs["protocol"]) == 1 && len(qs["pipe"]) == 0 && len(qs["table"]) == 0 {
+		protocol, err := ValidateProtocolParam(qs["protocol"][0])
+		if err != nil {
+			return "", "", &bird.FieldError{Field: "protocol", Message: err.Error()}
+		}
+		pipe, table, ok := bird.ResolvePeerPipeAndTable(ctx, useCache, protocol)
+		if !ok {
+			return "", "", &bird.FieldError{Field: "protocol",
+				Message: fmt.Sprintf("no pipe and table found for protocol %s", protocol)}
+		}
+		return pipe, table, nil
+	}
 
 	if len(qs["table"]) != 1 {
-		return bird.Parsed{"error": "need a table as single query parameter"}, false
+		return "", "", fmt.Errorf("need a table as single query parameter")
 	}
 	table, err := ValidateProtocolParam(qs["table"][0])
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return "", "", err
 	}
 
 	if len(qs["pipe"]) != 1 {
-		return bird.Parsed{"error": "need a pipe as single query parameter"}, false
+		return "", "", &bird.FieldError{Field: "pipe", Message: "need a pipe as single query parameter"}
 	}
 	pipe, err := ValidateProtocolParam(qs["pipe"][0])
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return "", "", &bird.FieldError{Field: "pipe", Message: err.Error()}
 	}
 
-	return bird.PipeRoutesFiltered(useCache, pipe, table)
+	return pipe, table, nil
 }
 
-func PipeRoutesFilteredCount(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func PipeRoutesFiltered(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	qs := r.URL.Query()
 
-	if len(qs["table"]) != 1 {
-		return bird.Parsed{"error": "need a table as single query parameter"}, false
-	}
-	table, err := ValidateProtocolParam(qs["table"][0])
+	pipe, table, err := pipeAndTableParams(r.Context(), qs, useCache)
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("table", err)
 	}
 
-	if len(qs["pipe"]) != 1 {
-		return bird.Parsed{"error": "need a pipe as single query parameter"}, false
-	}
-	pipe, err := ValidateProtocolParam(qs["pipe"][0])
+	return enrich.FilterReasons(bird.PipeRoutesFiltered(r.Context(), useCache, pipe, table))
+}
+
+func PipeRoutesFilteredCount(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	qs := r.URL.Query()
+
+	pipe, table, err := pipeAndTableParams(r.Context(), qs, useCache)
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("table", err)
 	}
 
 	if len(qs["address"]) != 1 {
-		return bird.Parsed{"error": "need a address as single query parameter"}, false
+		return invalidParam("address", fmt.Errorf("need a address as single query parameter"))
 	}
 	address, err := ValidatePrefixParam(qs["address"][0])
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("address", err)
 	}
 
-	return bird.PipeRoutesFilteredCount(useCache, pipe, table, address)
+	return bird.PipeRoutesFilteredCount(r.Context(), useCache, pipe, table, address)
 }
 
-func PeerRoutes(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
+func PeerRoutes(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
 	peer, err := ValidatePrefixParam(ps.ByName("peer"))
 	if err != nil {
-		return bird.Parsed{"error": fmt.Sprintf("%s", err)}, false
+		return invalidParam("peer", err)
+	}
+
+	return bird.RoutesPeer(r.Context(), useCache, peer)
+}
+
+// RoutesAll merges the routes of all peer tables
+func RoutesAll(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	return bird.RoutesAllPeerTables(r.Context(), useCache)
+}
+
+// AllTablesRoutes returns the routes of every table
+// with "show route table all" (BIRD 2 only)
+func AllTablesRoutes(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	if where := r.URL.Query().Get("where"); where != "" {
+		where, err := bird.ValidateWhere(where)
+		if err != nil {
+			return invalidParam("where", err)
+		}
+		return bird.RoutesAllTablesWhere(r.Context(), useCache, where)
+	}
+
+	return bird.RoutesAllTables(r.Context(), useCache)
+}
+
+// RouteNetAllTables looks up a prefix in every table
+func RouteNetAllTables(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	net, err := ValidatePrefixParam(ps.ByName("net"))
+	if err != nil {
+		return invalidParam("net", err)
+	}
+
+	mode, err := bird.ParseMatchMode(r.URL.Query().Get("match"))
+	if err != nil {
+		return invalidParam("match", err)
 	}
 
-	return bird.RoutesPeer(useCache, peer)
+	return bird.RoutesLookupAllTablesMatch(r.Context(), useCache, net, mode)
 }
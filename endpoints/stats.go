@@ -0,0 +1,72 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	defaultStatsTop = 10
+	maxStatsTop     = 1000
+)
+
+// statsRoutes queries the routes of the ?protocol= or
+// the ?table= (default: master) for the statistics.
+func statsRoutes(r *http.Request, useCache bool) (bird.Parsed, bool, error) {
+	qs := r.URL.Query()
+	if protocol := qs.Get("protocol"); protocol != "" {
+		protocol, err := ValidateProtocolParam(protocol)
+		if err != nil {
+			return invalidParam("protocol", err)
+		}
+		return bird.RoutesProto(r.Context(), useCache, protocol)
+	}
+
+	table := qs.Get("table")
+	if table == "" {
+		table = "master"
+	}
+	table, err := ValidateProtocolParam(table)
+	if err != nil {
+		return invalidParam("table", err)
+	}
+	return bird.RoutesTable(r.Context(), useCache, table)
+}
+
+func RoutesGatewayStats(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	return bird.RoutesGatewayStats(statsRoutes(r, useCache))
+}
+
+func RoutesPrefixLengthStats(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	return bird.RoutesPrefixLengthStats(statsRoutes(r, useCache))
+}
+
+func RoutesCommunityStats(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	return bird.RoutesCommunityStats(statsRoutes(r, useCache))
+}
+
+// statsTop reads the number of entries of ?top=
+func statsTop(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("top")
+	if value == "" {
+		return defaultStatsTop, nil
+	}
+	top, err := strconv.Atoi(value)
+	if err != nil || top < 1 || top > maxStatsTop {
+		return 0, fmt.Errorf("Invalid top: %s (1-%d)", value, maxStatsTop)
+	}
+	return top, nil
+}
+
+func RoutesOriginStats(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	top, err := statsTop(r)
+	if err != nil {
+		return invalidParam("top", err)
+	}
+	res, fromCache, err := statsRoutes(r, useCache)
+	return bird.RoutesOriginStats(res, fromCache, err, top)
+}
@@ -7,6 +7,6 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
-func Status(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
-	return bird.Status(useCache)
+func Status(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	return bird.Status(r.Context(), useCache)
 }
@@ -0,0 +1,28 @@
+package endpoints
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/support"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SupportBundle serves a gzipped tar archive for bug reports.
+// The config must be sanitized.
+func SupportBundle(version string, config interface{}) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if _, ok := checkRequest(w, r, ps); !ok {
+			return
+		}
+
+		filename := "birdwatcher-support-" + time.Now().UTC().Format("20060102-150405") + ".tar.gz"
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+
+		if err := support.WriteBundle(w, config, version); err != nil {
+			log.Println("Creating support bundle failed:", err)
+		}
+	}
+}
@@ -7,22 +7,22 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
-func Symbols(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
-	return bird.Symbols(useCache)
+func Symbols(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	return bird.Symbols(r.Context(), useCache)
 }
 
-func SymbolTables(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
-	val, from_cache := bird.Symbols(useCache)
-	if bird.IsSpecial(val) {
-		return val, from_cache
+func SymbolTables(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	val, from_cache, err := bird.Symbols(r.Context(), useCache)
+	if err != nil {
+		return nil, false, err
 	}
-	return bird.Parsed{"symbols": val["symbols"].(bird.Parsed)["routing table"]}, from_cache
+	return bird.Parsed{"symbols": val["symbols"].(bird.Parsed)["routing table"]}, from_cache, nil
 }
 
-func SymbolProtocols(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool) {
-	val, from_cache := bird.Symbols(useCache)
-	if bird.IsSpecial(val) {
-		return val, from_cache
+func SymbolProtocols(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	val, from_cache, err := bird.Symbols(r.Context(), useCache)
+	if err != nil {
+		return nil, false, err
 	}
-	return bird.Parsed{"symbols": val["symbols"].(bird.Parsed)["protocol"]}, from_cache
+	return bird.Parsed{"symbols": val["symbols"].(bird.Parsed)["protocol"]}, from_cache, nil
 }
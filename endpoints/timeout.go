@@ -0,0 +1,80 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Default maximum of ?timeout= in seconds
+const defaultMaxTimeout = 60
+
+// requestTimeout parses the ?timeout= parameter, e.g. "5s".
+// The timeout is bounded by the configured max_timeout.
+func requestTimeout(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get("timeout")
+	if value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("Invalid timeout: %s", value)
+	}
+
+	maxTimeout := time.Duration(Conf.MaxTimeout) * time.Second
+	if Conf.MaxTimeout <= 0 {
+		maxTimeout = defaultMaxTimeout * time.Second
+	}
+	if timeout > maxTimeout {
+		timeout = maxTimeout
+	}
+	return timeout, nil
+}
+
+type endpointResult struct {
+	res       bird.Parsed
+	fromCache bool
+	err       error
+	panicked  interface{}
+}
+
+// withTimeout stops waiting for the query after the
+// requested timeout and responds with a timeout error.
+// The birdc execution is shared with other requests and
+// continues, so a later request can use the cached result.
+func withTimeout(wrapped endpoint) endpoint {
+	return func(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+		timeout, err := requestTimeout(r)
+		if err != nil {
+			return invalidParam("timeout", err)
+		}
+		if timeout == 0 {
+			return wrapped(r, ps, useCache)
+		}
+
+		done := make(chan endpointResult, 1)
+		go func() {
+			result := endpointResult{}
+			defer func() {
+				result.panicked = recover()
+				done <- result
+			}()
+			result.res, result.fromCache, result.err = wrapped(r, ps, useCache)
+		}()
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case result := <-done:
+			if result.panicked != nil {
+				panic(result.panicked) // Handled by the PanicHandler
+			}
+			return result.res, result.fromCache, result.err
+		case <-timer.C:
+			return nil, false, bird.ErrTimeout
+		}
+	}
+}
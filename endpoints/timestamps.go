@@ -0,0 +1,83 @@
+package endpoints
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// Formats of the timestamps in the responses
+const (
+	TimestampRFC3339 = "rfc3339"
+	TimestampUnix    = "unix"
+	TimestampBoth    = "both"
+)
+
+// Fields holding timestamps, besides fields ending with _at
+var timestampFields = map[string]bool{
+	"ttl":            true,
+	"date":           true,
+	"since":          true,
+	"last_reconfig":  true,
+	"last_reboot":    true,
+	"current_server": true,
+	"state_changed":  true,
+	"uptime":         true,
+	"age":            true,
+	"timestamp":      true,
+	"from":           true,
+	"to":             true,
+}
+
+// Layouts of the timestamps of birdwatcher and BIRD,
+// which are in the local time of the router. Other
+// timeformats of BIRD are parsed by the bird package.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+func isTimestampField(key string) bool {
+	return timestampFields[key] || strings.HasSuffix(key, "_at")
+}
+
+// parseTimestamp parses a timestamp with date and time, seconds
+// may have a fraction, or with only the date or the time like
+// the iso short timeformat of BIRD.
+func parseTimestamp(value string) (time.Time, bool) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return bird.ParseTimestamp(value)
+}
+
+// formatTimestamp converts the value of a timestamp field to the
+// configured timestamp_format. With "both" the unix timestamp is
+// returned as a second value. Other values are kept.
+func formatTimestamp(key, value string) (interface{}, interface{}, bool) {
+	switch Conf.TimestampFormat {
+	case TimestampRFC3339, TimestampUnix, TimestampBoth:
+	default:
+		return value, nil, false
+	}
+	if !isTimestampField(key) {
+		return value, nil, false
+	}
+	t, ok := parseTimestamp(value)
+	if !ok {
+		return value, nil, false
+	}
+
+	rfc3339 := t.UTC().Format(time.RFC3339Nano)
+	switch Conf.TimestampFormat {
+	case TimestampUnix:
+		return t.Unix(), nil, true
+	case TimestampBoth:
+		return rfc3339, t.Unix(), true
+	}
+	return rfc3339, nil, true
+}
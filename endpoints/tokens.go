@@ -0,0 +1,167 @@
+package endpoints
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Endpoints listing protocols, which are filtered
+// for tokens restricted to protocols
+var protocolListings = map[string]bool{
+	"/protocols":       true,
+	"/protocols/bgp":   true,
+	"/protocols/short": true,
+}
+
+// requestToken returns the bearer token of the request
+func requestToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return "", false
+	}
+	return strings.TrimPrefix(auth, "Bearer "), true
+}
+
+func tokenEqual(token, configured string) bool {
+	return configured != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(configured)) == 1
+}
+
+// lookupToken returns the scope of a token,
+// admin tokens are not restricted.
+func lookupToken(token string) (*ScopedToken, bool) {
+	for _, admin := range AdminConf.Tokens {
+		if tokenEqual(token, admin) {
+			return &ScopedToken{Scopes: []string{"status", "routes", "admin"}}, true
+		}
+	}
+	for i, scoped := range AdminConf.ScopedTokens {
+		if tokenEqual(token, scoped.Token) {
+			return &AdminConf.ScopedTokens[i], true
+		}
+	}
+	return nil, false
+}
+
+// endpointGroup returns the scope required for a path.
+// The metrics, support bundle and rate limiter (listing client
+// addresses) expose the whole instance and require the admin scope.
+func endpointGroup(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/admin/"),
+		path == "/metrics", path == "/support/bundle", path == "/ratelimit":
+		return "admin"
+	case path == "/status" || path == "/version" || path == "/":
+		return "status"
+	}
+	return "routes"
+}
+
+func (t *ScopedToken) hasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// allows checks the protocol and table of a request: Each of
+// them has to be allowed if the token is restricted, and at
+// least one of them has to be named.
+func (t *ScopedToken) allows(path string, ps httprouter.Params) error {
+	if len(t.Protocols) == 0 && len(t.Tables) == 0 {
+		return nil
+	}
+
+	named := false
+	if protocol := ps.ByName("protocol"); protocol != "" && len(t.Protocols) > 0 {
+		if !contains(t.Protocols, protocol) {
+			return fmt.Errorf("token is not allowed for protocol %s", protocol)
+		}
+		named = true
+	}
+	if table := ps.ByName("table"); table != "" && len(t.Tables) > 0 {
+		if !contains(t.Tables, table) {
+			return fmt.Errorf("token is not allowed for table %s", table)
+		}
+		named = true
+	}
+	if named || (len(t.Protocols) > 0 && protocolListings[path]) {
+		return nil
+	}
+	return fmt.Errorf("token is restricted to protocols or tables")
+}
+
+// authorize checks the token of a request against the
+// endpoint. Without scoped tokens and require_token,
+// only the admin endpoints check tokens.
+func authorize(r *http.Request, ps httprouter.Params) (*ScopedToken, int, error) {
+	if !AdminConf.RequireToken && len(AdminConf.ScopedTokens) == 0 {
+		return nil, 0, nil
+	}
+
+	token, ok := requestToken(r)
+	if !ok {
+		if AdminConf.RequireToken {
+			return nil, http.StatusUnauthorized, fmt.Errorf("token required")
+		}
+		return nil, 0, nil
+	}
+	scoped, ok := lookupToken(token)
+	if !ok {
+		return nil, http.StatusUnauthorized, fmt.Errorf("invalid token")
+	}
+
+	group := endpointGroup(r.URL.Path)
+	if !scoped.hasScope(group) {
+		return nil, http.StatusForbidden,
+			fmt.Errorf("token is not allowed for %s endpoints", group)
+	}
+	if group == "status" {
+		return scoped, 0, nil
+	}
+	if err := scoped.allows(r.URL.Path, ps); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	return scoped, 0, nil
+}
+
+// restrictProtocols removes the protocols a
+// restricted token is not allowed to see.
+func restrictProtocols(token *ScopedToken, res bird.Parsed) bird.Parsed {
+	if token == nil || len(token.Protocols) == 0 {
+		return res
+	}
+	protocols, ok := res["protocols"].(bird.Parsed)
+	if !ok {
+		return res
+	}
+
+	allowed := bird.Parsed{}
+	for name, protocol := range protocols {
+		if contains(token.Protocols, name) {
+			allowed[name] = protocol
+		}
+	}
+	restricted := bird.Parsed{}
+	for k, v := range res {
+		restricted[k] = v
+	}
+	restricted["protocols"] = allowed
+	return restricted
+}
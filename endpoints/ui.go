@@ -0,0 +1,158 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// UI serves a single page to browse the status, the
+// protocols and to look up prefixes using the API.
+func UI(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, ok := checkRequest(w, r, ps); !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy",
+		"default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	w.Write([]byte(uiPage))
+}
+
+const uiPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>birdwatcher</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+nav a { margin-right: 1em; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 2px 8px; border-bottom: 1px solid #ddd; }
+tr.down td { color: #b00; }
+pre { background: #f4f4f4; padding: 1em; overflow: auto; }
+#error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>birdwatcher</h1>
+<nav>
+<a href="#status">Status</a>
+<a href="#protocols">Protocols</a>
+<a href="#lookup">Prefix lookup</a>
+</nav>
+<form id="lookup-form" hidden>
+<input id="prefix" placeholder="10.0.0.0/8" size="40">
+<button>Look up</button>
+</form>
+<p id="error"></p>
+<div id="content"></div>
+<script>
+var content = document.getElementById("content");
+var error = document.getElementById("error");
+
+function text(value) {
+	return document.createTextNode(value === undefined || value === null ? "" : String(value));
+}
+
+function get(path) {
+	error.textContent = "";
+	return fetch(path, {headers: {"Accept": "application/json"}}).then(function(res) {
+		return res.json().then(function(data) {
+			if (!res.ok) {
+				var e = data.error || {};
+				var fields = (e.fields || []).map(function(f) { return f.field + ": " + f.message; });
+				throw new Error(fields.join(", ") || e.message || e || res.statusText);
+			}
+			return data;
+		});
+	}).catch(function(err) {
+		error.textContent = path + ": " + err.message;
+		throw err;
+	});
+}
+
+function showJSON(data) {
+	var pre = document.createElement("pre");
+	pre.appendChild(text(JSON.stringify(data, null, 2)));
+	content.replaceChildren(pre);
+}
+
+function showTable(columns, rows, rowClass) {
+	var table = document.createElement("table");
+	var head = table.insertRow();
+	columns.forEach(function(column) {
+		var th = document.createElement("th");
+		th.appendChild(text(column.title));
+		head.appendChild(th);
+	});
+	rows.forEach(function(row) {
+		var tr = table.insertRow();
+		tr.className = rowClass ? rowClass(row) : "";
+		columns.forEach(function(column) {
+			tr.insertCell().appendChild(text(column.value(row)));
+		});
+	});
+	content.replaceChildren(table);
+}
+
+function showProtocols() {
+	get("protocols/bgp").then(function(data) {
+		var protocols = Object.keys(data.protocols || {}).sort().map(function(name) {
+			var p = data.protocols[name];
+			p.name = name;
+			return p;
+		});
+		showTable([
+			{title: "Protocol", value: function(p) { return p.name; }},
+			{title: "Neighbor", value: function(p) { return p.neighbor_address; }},
+			{title: "AS", value: function(p) { return p.neighbor_as; }},
+			{title: "State", value: function(p) { return p.state + " " + (p.bgp_state || ""); }},
+			{title: "Since", value: function(p) { return p.state_changed; }},
+			{title: "Imported", value: function(p) { return p.routes && p.routes.imported; }},
+			{title: "Filtered", value: function(p) { return p.routes && p.routes.filtered; }},
+			{title: "Description", value: function(p) { return p.description; }},
+			{title: "Last error", value: function(p) { return p.last_error; }}
+		], protocols, function(p) { return p.state === "up" ? "" : "down"; });
+	});
+}
+
+function showRoutes(prefix) {
+	get("routes/prefix?prefix=" + encodeURIComponent(prefix)).then(function(data) {
+		showTable([
+			{title: "Network", value: function(r) { return r.network; }},
+			{title: "Gateway", value: function(r) { return r.gateway; }},
+			{title: "From", value: function(r) { return r.from_protocol; }},
+			{title: "Primary", value: function(r) { return r.primary ? "*" : ""; }},
+			{title: "AS path", value: function(r) { return r.bgp && (r.bgp.as_path || []).join(" "); }},
+			{title: "Age", value: function(r) { return r.age; }}
+		], data.routes || []);
+	});
+}
+
+function route() {
+	var page = location.hash.slice(1) || "status";
+	document.getElementById("lookup-form").hidden = (page !== "lookup");
+	content.replaceChildren();
+	if (page === "protocols") {
+		showProtocols();
+	} else if (page === "lookup") {
+		var prefix = document.getElementById("prefix").value;
+		if (prefix) {
+			showRoutes(prefix);
+		}
+	} else {
+		get("status").then(showJSON);
+	}
+}
+
+document.getElementById("lookup-form").addEventListener("submit", function(e) {
+	e.preventDefault();
+	route();
+});
+window.addEventListener("hashchange", route);
+route();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,13 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/alice-lg/birdwatcher/bird"
+	"github.com/alice-lg/birdwatcher/flaps"
+	"github.com/julienschmidt/httprouter"
+)
+
+func ProtocolsUptime(r *http.Request, ps httprouter.Params, useCache bool) (bird.Parsed, bool, error) {
+	return bird.Parsed{"uptime": flaps.Uptime()}, false, nil
+}
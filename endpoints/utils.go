@@ -20,6 +20,8 @@ type APIInfo struct {
 	Version         string
 	ResultFromCache bool        `json:"result_from_cache"`
 	CacheStatus     CacheStatus `json:"cache_status"`
+	RequestID       string      `json:"request_id,omitempty"`
+	Warnings        []string    `json:"warnings,omitempty"`
 }
 
 // go generate does not work in subdirectories. Beautious.
@@ -58,6 +60,7 @@ func GetApiInfo(res *bird.Parsed, from_cache bool) *APIInfo {
 	}
 
 	ai.CacheStatus = cacheInfo
+	ai.Warnings = bird.Warnings(api)
 
 	return ai
 }
@@ -0,0 +1,180 @@
+package enrich
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// ASNamesConfig configures the source of
+// the ASN to organization name mapping.
+type ASNamesConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Source  string `toml:"source"` // peeringdb or file
+
+	File         string `toml:"file"`
+	PeeringDBURL string `toml:"peeringdb_url"`
+
+	RefreshInterval int `toml:"refresh_interval"` // in minutes
+}
+
+var ASNamesConf ASNamesConfig
+
+const defaultPeeringDBURL = "https://www.peeringdb.com/api/net?fields=asn,name"
+
+var asNames struct {
+	sync.RWMutex
+	names map[int64]string
+}
+
+var asNamesLineRx = regexp.MustCompile(`^(?:AS)?(\d+)\s+(.+?)\s*$`)
+
+// parseASNamesFile reads lines like:
+//
+//	AS13335 CLOUDFLARENET - Cloudflare, Inc., US
+//	3320 DTAG Deutsche Telekom AG
+func parseASNamesFile(reader io.Reader) (map[int64]string, error) {
+	names := make(map[int64]string)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		groups := asNamesLineRx.FindStringSubmatch(line)
+		if groups == nil {
+			continue
+		}
+		asn, err := strconv.ParseInt(groups[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		names[asn] = groups[2]
+	}
+	return names, scanner.Err()
+}
+
+type peeringDBNets struct {
+	Data []struct {
+		ASN  int64  `json:"asn"`
+		Name string `json:"name"`
+	} `json:"data"`
+}
+
+func parsePeeringDBNets(reader io.Reader) (map[int64]string, error) {
+	nets := peeringDBNets{}
+	if err := json.NewDecoder(reader).Decode(&nets); err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string, len(nets.Data))
+	for _, net := range nets.Data {
+		names[net.ASN] = net.Name
+	}
+	return names, nil
+}
+
+func loadASNames() (map[int64]string, error) {
+	switch ASNamesConf.Source {
+	case "file":
+		f, err := os.Open(ASNamesConf.File)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return parseASNamesFile(f)
+	case "peeringdb":
+		url := ASNamesConf.PeeringDBURL
+		if url == "" {
+			url = defaultPeeringDBURL
+		}
+		client := &http.Client{Timeout: 60 * time.Second}
+		res, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("peeringdb responded with: %s", res.Status)
+		}
+		return parsePeeringDBNets(res.Body)
+	}
+	return nil, fmt.Errorf("unknown asn names source: %s", ASNamesConf.Source)
+}
+
+// StartASNamesRefresh loads the dataset and refreshes
+// it periodically in the background.
+func StartASNamesRefresh() {
+	interval := time.Duration(ASNamesConf.RefreshInterval) * time.Minute
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	go func() {
+		for {
+			names, err := loadASNames()
+			if err != nil {
+				log.Println("Loading ASN names failed:", err)
+			} else {
+				asNames.Lock()
+				asNames.names = names
+				asNames.Unlock()
+				log.Println("Loaded", len(names), "ASN names")
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func asName(asn int64) string {
+	asNames.RLock()
+	defer asNames.RUnlock()
+	return asNames.names[asn]
+}
+
+func enrichASNames(res bird.Parsed) bird.Parsed {
+	res = mapRoutes(res, func(route bird.Parsed) {
+		bgp, ok := route["bgp"].(bird.Parsed)
+		if !ok {
+			return
+		}
+		path, ok := bgp["as_path"].([]string)
+		if !ok {
+			return
+		}
+
+		names := make(map[string]string)
+		for _, hop := range path {
+			asn, err := strconv.ParseInt(hop, 10, 64)
+			if err != nil {
+				continue
+			}
+			if name := asName(asn); name != "" {
+				names[hop] = name
+			}
+		}
+
+		bgp = copyParsed(bgp)
+		bgp["as_names"] = names
+		route["bgp"] = bgp
+	})
+	res = mapProtocols(res, func(protocol bird.Parsed) {
+		if asn, ok := protocol["neighbor_as"].(int64); ok {
+			if name := asName(asn); name != "" {
+				protocol["neighbor_as_name"] = name
+			}
+		}
+	})
+
+	return res
+}
@@ -0,0 +1,415 @@
+package enrich
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// ConfigMetadataConfig enables reading protocol
+// metadata from the BIRD configuration file.
+type ConfigMetadataConfig struct {
+	Enabled bool `toml:"enabled"`
+}
+
+var ConfigMetadataConf ConfigMetadataConfig
+
+// Interval of checking the configuration for changes
+const birdConfigCheckInterval = 10 * time.Second
+
+// A protocolConfig is the metadata of a protocol
+// or template in the BIRD configuration.
+type protocolConfig struct {
+	template        string
+	description     string
+	neighborAddress string
+	neighborAS      int64
+	importFilter    string
+	exportFilter    string
+	channels        map[string]*channelConfig
+}
+
+type channelConfig struct {
+	importFilter string
+	exportFilter string
+}
+
+var birdConfig struct {
+	sync.Mutex
+	checked   time.Time
+	modified  time.Time
+	protocols map[string]*protocolConfig
+}
+
+// tokenizeConfig splits the configuration into words,
+// quoted strings and the characters { } ; without comments.
+func tokenizeConfig(config string) []string {
+	tokens := []string{}
+	word := strings.Builder{}
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for i := 0; i < len(config); i++ {
+		c := config[i]
+		switch {
+		case c == '#':
+			flush()
+			for i < len(config) && config[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(config) && config[i+1] == '*':
+			flush()
+			end := strings.Index(config[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 3
+		case c == '"':
+			flush()
+			start := i
+			for i++; i < len(config) && config[i] != '"'; i++ {
+				if config[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(config) {
+				return append(tokens, config[start:])
+			}
+			tokens = append(tokens, config[start:i+1])
+		case c == '{' || c == '}' || c == ';':
+			flush()
+			tokens = append(tokens, string(c))
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			flush()
+		default:
+			word.WriteByte(c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+func unquote(token string) string {
+	if value, err := strconv.Unquote(token); err == nil {
+		return value
+	}
+	return strings.Trim(token, `"`)
+}
+
+// readConfigTokens reads the tokens of a configuration
+// file and replaces include statements by the tokens of
+// the included files.
+func readConfigTokens(filename string, depth int) []string {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		log.Println("Reading BIRD config failed:", err)
+		return nil
+	}
+
+	tokens := []string{}
+	raw := tokenizeConfig(string(data))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == "include" && i+1 < len(raw) && depth < 8 {
+			pattern := unquote(raw[i+1])
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(filepath.Dir(filename), pattern)
+			}
+			files, _ := filepath.Glob(pattern)
+			for _, file := range files {
+				tokens = append(tokens, readConfigTokens(file, depth+1)...)
+			}
+			for i < len(raw) && raw[i] != ";" {
+				i++
+			}
+			continue
+		}
+		tokens = append(tokens, raw[i])
+	}
+	return tokens
+}
+
+// skipBlock returns the position after the block
+// starting at the opening brace at i.
+func skipBlock(tokens []string, i int) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+// parseStatement applies a statement of a protocol or
+// channel block like "import filter name".
+func parseStatement(statement []string, p *protocolConfig, channel *channelConfig) {
+	if len(statement) == 0 {
+		return
+	}
+	switch statement[0] {
+	case "description":
+		if len(statement) > 1 && channel == nil {
+			p.description = unquote(statement[1])
+		}
+	case "neighbor":
+		if channel != nil {
+			return
+		}
+		for i := 1; i < len(statement); i++ {
+			if statement[i] == "as" && i+1 < len(statement) {
+				p.neighborAS, _ = strconv.ParseInt(statement[i+1], 10, 64)
+				i++
+			} else if i == 1 && statement[i] != "range" {
+				p.neighborAddress = strings.SplitN(statement[i], "%", 2)[0]
+			}
+		}
+	case "import", "export":
+		if len(statement) != 3 || statement[1] != "filter" {
+			return
+		}
+		importFilter, exportFilter := &p.importFilter, &p.exportFilter
+		if channel != nil {
+			importFilter, exportFilter = &channel.importFilter, &channel.exportFilter
+		}
+		if statement[0] == "import" {
+			*importFilter = statement[2]
+		} else {
+			*exportFilter = statement[2]
+		}
+	}
+}
+
+// parseProtocolBlock reads the statements of a protocol or
+// template block starting after the opening brace at i.
+// Nested blocks of channels like "ipv4 { ... }" are read,
+// other blocks (e.g. inline filters) are skipped.
+func parseProtocolBlock(tokens []string, i int, p *protocolConfig) int {
+	statement := []string{}
+	var channel *channelConfig
+	for i < len(tokens) {
+		token := tokens[i]
+		switch token {
+		case ";":
+			parseStatement(statement, p, channel)
+			statement = statement[:0]
+			i++
+		case "{":
+			if channel == nil && len(statement) > 0 && isChannelName(statement[0]) {
+				channel = &channelConfig{}
+				p.channels[strings.Join(statement, " ")] = channel
+				statement = statement[:0]
+				i++
+				continue
+			}
+			i = skipBlock(tokens, i)
+		case "}":
+			parseStatement(statement, p, channel)
+			statement = statement[:0]
+			i++
+			if channel == nil {
+				return i
+			}
+			channel = nil
+		default:
+			statement = append(statement, token)
+			i++
+		}
+	}
+	return i
+}
+
+func isChannelName(name string) bool {
+	switch name {
+	case "ipv4", "ipv6", "vpn4", "vpn6", "flow4", "flow6", "mpls":
+		return true
+	}
+	return false
+}
+
+// parseBirdConfig reads the protocols and templates of the
+// configuration, e.g. protocol bgp R1 from rs_client { ... }
+func parseBirdConfig(tokens []string) (map[string]*protocolConfig, map[string]*protocolConfig) {
+	protocols := map[string]*protocolConfig{}
+	templates := map[string]*protocolConfig{}
+
+	for i := 0; i < len(tokens); {
+		if tokens[i] != "protocol" && tokens[i] != "template" {
+			if tokens[i] == "{" {
+				i = skipBlock(tokens, i)
+			} else {
+				i++
+			}
+			continue
+		}
+
+		header := []string{}
+		j := i + 1
+		for ; j < len(tokens) && tokens[j] != "{" && tokens[j] != ";"; j++ {
+			header = append(header, tokens[j])
+		}
+		if j >= len(tokens) || tokens[j] != "{" {
+			i = j + 1
+			continue
+		}
+		if len(header) < 2 || header[1] == "from" {
+			i = skipBlock(tokens, j) // Unnamed protocols can not be matched
+			continue
+		}
+
+		p := &protocolConfig{channels: map[string]*channelConfig{}}
+		if len(header) >= 4 && header[2] == "from" {
+			p.template = header[3]
+		}
+		if tokens[i] == "protocol" {
+			protocols[header[1]] = p
+		} else {
+			templates[header[1]] = p
+		}
+		i = parseProtocolBlock(tokens, j+1, p)
+	}
+	return protocols, templates
+}
+
+// resolve merges the settings of the templates into
+// the protocol, settings of the protocol take precedence.
+func (p *protocolConfig) resolve(templates map[string]*protocolConfig, depth int) *protocolConfig {
+	template, ok := templates[p.template]
+	if !ok || depth > 8 {
+		return p
+	}
+	base := template.resolve(templates, depth+1)
+
+	merged := *p
+	merged.channels = map[string]*channelConfig{}
+	for name, c := range base.channels {
+		copied := *c
+		merged.channels[name] = &copied
+	}
+	for name, c := range p.channels {
+		copied := *c
+		if b, ok := merged.channels[name]; ok {
+			if copied.importFilter == "" {
+				copied.importFilter = b.importFilter
+			}
+			if copied.exportFilter == "" {
+				copied.exportFilter = b.exportFilter
+			}
+		}
+		merged.channels[name] = &copied
+	}
+	if merged.description == "" {
+		merged.description = base.description
+	}
+	if merged.neighborAddress == "" {
+		merged.neighborAddress = base.neighborAddress
+	}
+	if merged.neighborAS == 0 {
+		merged.neighborAS = base.neighborAS
+	}
+	if merged.importFilter == "" {
+		merged.importFilter = base.importFilter
+	}
+	if merged.exportFilter == "" {
+		merged.exportFilter = base.exportFilter
+	}
+	return &merged
+}
+
+// filters returns the import and export filter of the
+// channel of the IP version, or of the protocol.
+func (p *protocolConfig) filters() (string, string) {
+	importFilter, exportFilter := p.importFilter, p.exportFilter
+	if channel, ok := p.channels["ipv"+bird.IPVersion]; ok {
+		if channel.importFilter != "" {
+			importFilter = channel.importFilter
+		}
+		if channel.exportFilter != "" {
+			exportFilter = channel.exportFilter
+		}
+	}
+	return importFilter, exportFilter
+}
+
+func loadBirdConfig(filename string) map[string]*protocolConfig {
+	protocols, templates := parseBirdConfig(readConfigTokens(filename, 0))
+	for name, p := range protocols {
+		protocols[name] = p.resolve(templates, 0)
+	}
+	return protocols
+}
+
+// protocolConfigs returns the protocols of the configuration,
+// which is read again if the file was modified.
+func protocolConfigs() map[string]*protocolConfig {
+	birdConfig.Lock()
+	defer birdConfig.Unlock()
+
+	now := time.Now()
+	if now.Sub(birdConfig.checked) < birdConfigCheckInterval && birdConfig.protocols != nil {
+		return birdConfig.protocols
+	}
+	birdConfig.checked = now
+
+	filename := bird.ClientConf.ConfigFilename
+	info, err := os.Stat(filename)
+	if err != nil {
+		return birdConfig.protocols
+	}
+	if birdConfig.protocols == nil || !info.ModTime().Equal(birdConfig.modified) {
+		birdConfig.protocols = loadBirdConfig(filename)
+		birdConfig.modified = info.ModTime()
+	}
+	return birdConfig.protocols
+}
+
+// enrichBirdConfig adds the description, neighbor and filters
+// from the configuration to protocols, if BIRD did not report them.
+func enrichBirdConfig(res bird.Parsed) bird.Parsed {
+	if _, ok := res["protocols"].(bird.Parsed); !ok {
+		return res
+	}
+	configs := protocolConfigs()
+	if len(configs) == 0 {
+		return res
+	}
+
+	return mapProtocols(res, func(protocol bird.Parsed) {
+		name, _ := protocol["protocol"].(string)
+		config, ok := configs[name]
+		if !ok {
+			return
+		}
+		setMissing := func(key string, value interface{}) {
+			if value == "" || value == int64(0) {
+				return
+			}
+			if _, ok := protocol[key]; !ok {
+				protocol[key] = value
+			}
+		}
+
+		importFilter, exportFilter := config.filters()
+		setMissing("description", config.description)
+		setMissing("neighbor_address", config.neighborAddress)
+		setMissing("neighbor_as", config.neighborAS)
+		setMissing("import_filter", importFilter)
+		setMissing("export_filter", exportFilter)
+	})
+}
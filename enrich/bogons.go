@@ -0,0 +1,121 @@
+package enrich
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// BogonsConfig enables the annotation of routes in bogon
+// and martian ranges. Prefixes extend the built-in list.
+type BogonsConfig struct {
+	Enabled  bool     `toml:"enabled"`
+	Prefixes []string `toml:"prefixes"`
+}
+
+var BogonsConf BogonsConfig
+
+// Reserved and unallocated address space, which
+// should never be announced in the global table.
+var builtinBogons = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/8",
+	"100::/64",
+	"2001:2::/48",
+	"2001:10::/28",
+	"2001:db8::/32",
+	"2002::/16",
+	"3ffe::/16",
+	"fc00::/7",
+	"fe80::/10",
+	"fec0::/10",
+	"ff00::/8",
+}
+
+var bogons []*net.IPNet
+
+func parseBogons(prefixes []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		_, ipnet, err := net.ParseCIDR(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid bogon prefix: %s", prefix)
+		}
+		nets = append(nets, ipnet)
+	}
+	return nets, nil
+}
+
+// LoadBogons builds the list of bogon ranges from
+// the built-in and the configured prefixes.
+func LoadBogons() error {
+	nets, err := parseBogons(append(builtinBogons, BogonsConf.Prefixes...))
+	if err != nil {
+		return err
+	}
+	bogons = nets
+	return nil
+}
+
+// IsBogon checks if the network falls into a bogon range
+func IsBogon(network string) bool {
+	_, prefix, err := net.ParseCIDR(network)
+	if err != nil {
+		return false
+	}
+	length, bits := prefix.Mask.Size()
+	for _, bogon := range bogons {
+		bogonLength, bogonBits := bogon.Mask.Size()
+		if bits == bogonBits && length >= bogonLength && bogon.Contains(prefix.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBogonRoute(route bird.Parsed) bool {
+	network, _ := route["network"].(string)
+	return IsBogon(network)
+}
+
+// enrichBogons adds "bogon": true to the routes in bogon ranges
+func enrichBogons(res bird.Parsed) bird.Parsed {
+	return mapRoutes(res, func(route bird.Parsed) {
+		if isBogonRoute(route) {
+			route["bogon"] = true
+		}
+	})
+}
+
+// OnlyBogons keeps the routes in bogon ranges
+func OnlyBogons(res bird.Parsed) bird.Parsed {
+	routes, ok := res["routes"].([]bird.Parsed)
+	if !ok {
+		return res
+	}
+
+	filtered := []bird.Parsed{}
+	for _, route := range routes {
+		if isBogonRoute(route) {
+			filtered = append(filtered, route)
+		}
+	}
+
+	res = copyParsed(res)
+	res["routes"] = filtered
+	return res
+}
@@ -0,0 +1,130 @@
+package enrich
+
+// Enrichment of parsed BIRD results with
+// information from external sources.
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// Apply all enabled enrichments to the result.
+// The result is not modified, as it might be
+// shared with the cache.
+func Apply(r *http.Request, res bird.Parsed) bird.Parsed {
+	if RDNSConf.Enabled && isRequested(r, "rdns") {
+		res = enrichRDNS(r.Context(), res)
+	}
+	if ASNamesConf.Enabled && isRequested(r, "asn") {
+		res = enrichASNames(res)
+	}
+	if GeoIPConf.Enabled && isRequested(r, "geo") {
+		res = enrichGeoIP(res)
+	}
+	if BogonsConf.Enabled {
+		res = enrichBogons(res)
+	}
+	if ROAConf.Enabled {
+		res = enrichROA(res)
+	}
+	if ConfigMetadataConf.Enabled {
+		res = enrichBirdConfig(res)
+	}
+	return res
+}
+
+// isRequested checks if the enrichment was requested
+// with a query parameter like ?enrich=asn,geo
+func isRequested(r *http.Request, name string) bool {
+	for _, value := range r.URL.Query()["enrich"] {
+		for _, e := range strings.Split(value, ",") {
+			if strings.TrimSpace(e) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyParsed creates a shallow copy
+func copyParsed(p bird.Parsed) bird.Parsed {
+	c := make(bird.Parsed, len(p)+2)
+	for k, v := range p {
+		c[k] = v
+	}
+	return c
+}
+
+// mapRoutes applies fn to a copy of every route in the result
+func mapRoutes(res bird.Parsed, fn func(route bird.Parsed)) bird.Parsed {
+	routes, ok := res["routes"].([]bird.Parsed)
+	if !ok {
+		return res
+	}
+
+	enriched := make([]bird.Parsed, 0, len(routes))
+	for _, route := range routes {
+		route = copyParsed(route)
+		fn(route)
+		enriched = append(enriched, route)
+	}
+
+	res = copyParsed(res)
+	res["routes"] = enriched
+	return res
+}
+
+// mapProtocols applies fn to a copy of every protocol in the result
+func mapProtocols(res bird.Parsed, fn func(protocol bird.Parsed)) bird.Parsed {
+	protocols, ok := res["protocols"].(bird.Parsed)
+	if !ok {
+		return res
+	}
+
+	enriched := make(bird.Parsed, len(protocols))
+	for name, p := range protocols {
+		protocol, ok := p.(bird.Parsed)
+		if !ok {
+			enriched[name] = p
+			continue
+		}
+		protocol = copyParsed(protocol)
+		fn(protocol)
+		enriched[name] = protocol
+	}
+
+	res = copyParsed(res)
+	res["protocols"] = enriched
+	return res
+}
+
+// collectValues returns the unique string values of a field
+func collectValues(res bird.Parsed, routeField, protocolField string) []string {
+	seen := make(map[string]bool)
+	values := []string{}
+	add := func(v interface{}) {
+		s, ok := v.(string)
+		if !ok || s == "" || seen[s] {
+			return
+		}
+		seen[s] = true
+		values = append(values, s)
+	}
+
+	if routes, ok := res["routes"].([]bird.Parsed); ok {
+		for _, route := range routes {
+			add(route[routeField])
+		}
+	}
+	if protocols, ok := res["protocols"].(bird.Parsed); ok {
+		for _, p := range protocols {
+			if protocol, ok := p.(bird.Parsed); ok {
+				add(protocol[protocolField])
+			}
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,303 @@
+package enrich
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+func TestMapRoutesCopies(t *testing.T) {
+	route := bird.Parsed{"network": "10.0.0.0/8"}
+	res := bird.Parsed{"routes": []bird.Parsed{route}}
+
+	enriched := mapRoutes(res, func(r bird.Parsed) {
+		r["foo"] = "bar"
+	})
+
+	if _, ok := route["foo"]; ok {
+		t.Error("The original route should not be modified")
+	}
+	routes := enriched["routes"].([]bird.Parsed)
+	if routes[0]["foo"] != "bar" {
+		t.Error("Expected route to be enriched")
+	}
+}
+
+func TestCollectValues(t *testing.T) {
+	res := bird.Parsed{
+		"routes": []bird.Parsed{
+			{"gateway": "10.0.0.1"},
+			{"gateway": "10.0.0.2"},
+			{"gateway": "10.0.0.1"},
+		},
+	}
+
+	values := collectValues(res, "gateway", "neighbor_address")
+	if len(values) != 2 {
+		t.Error("Expected 2 unique values, got:", values)
+	}
+}
+
+func TestParseASNamesFile(t *testing.T) {
+	input := `# ASN names
+AS13335 CLOUDFLARENET - Cloudflare, Inc., US
+3320 DTAG Deutsche Telekom AG
+invalid line
+`
+	names, err := parseASNamesFile(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 {
+		t.Fatal("Expected 2 names, got:", names)
+	}
+	if names[13335] != "CLOUDFLARENET - Cloudflare, Inc., US" {
+		t.Error("Unexpected name:", names[13335])
+	}
+	if names[3320] != "DTAG Deutsche Telekom AG" {
+		t.Error("Unexpected name:", names[3320])
+	}
+}
+
+func TestIsRequested(t *testing.T) {
+	r := httptest.NewRequest("GET", "/routes/protocol/R1?enrich=geo,asn", nil)
+	if !isRequested(r, "asn") || !isRequested(r, "geo") {
+		t.Error("Expected asn and geo to be requested")
+	}
+	if isRequested(r, "rdns") {
+		t.Error("Did not expect rdns to be requested")
+	}
+}
+
+func TestRDNS(t *testing.T) {
+	RDNSConf = RDNSConfig{Enabled: true, Timeout: 50}
+	defer func() { RDNSConf = RDNSConfig{} }()
+
+	// A resolver answering one address and hanging for the other
+	defer func(lookup func(context.Context, string) ([]string, error)) {
+		lookupAddr = lookup
+	}(lookupAddr)
+	lookupAddr = func(ctx context.Context, addr string) ([]string, error) {
+		if addr == "192.0.2.1" {
+			return []string{"gw1.example.com."}, nil
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	res := bird.Parsed{"routes": []bird.Parsed{
+		{"network": "10.0.0.0/8", "gateway": "192.0.2.1"},
+		{"network": "10.1.0.0/16", "gateway": "192.0.2.2"},
+	}}
+
+	r := httptest.NewRequest("GET", "/routes/protocol/R1", nil)
+	if enriched := Apply(r, res); enriched["routes"].([]bird.Parsed)[0]["gateway_hostname"] != nil {
+		t.Error("Expected no lookups without ?enrich=rdns")
+	}
+
+	r = httptest.NewRequest("GET", "/routes/protocol/R1?enrich=rdns", nil)
+	start := time.Now()
+	routes := Apply(r, res)["routes"].([]bird.Parsed)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("Expected the lookups to be bound by the timeout, took:", elapsed)
+	}
+	if routes[0]["gateway_hostname"] != "gw1.example.com" {
+		t.Error("Unexpected hostname:", routes[0]["gateway_hostname"])
+	}
+	if _, ok := routes[1]["gateway_hostname"]; ok {
+		t.Error("Expected no hostname after the timeout, got:", routes[1])
+	}
+
+	ptrCache.Lock()
+	_, cached := ptrCache.entries["192.0.2.2"]
+	ptrCache.Unlock()
+	if cached {
+		t.Error("Expected lookups ended by the timeout not to be cached")
+	}
+}
+
+func TestFilterReasons(t *testing.T) {
+	FilterReasonsConf = FilterReasonsConfig{
+		Enabled:          true,
+		Communities:      map[string]string{"65000:666": "Blackhole"},
+		LargeCommunities: map[string]string{"65000:1000:3": "RPKI invalid"},
+	}
+	defer func() { FilterReasonsConf = FilterReasonsConfig{} }()
+
+	res := bird.Parsed{
+		"routes": []bird.Parsed{
+			{"network": "10.0.0.0/8", "bgp": bird.Parsed{
+				"communities":       [][]int64{{65000, 666}},
+				"large_communities": [][]int64{{65000, 1000, 3}, {65000, 1, 1}},
+			}},
+			{"network": "10.1.0.0/16", "bgp": bird.Parsed{
+				"communities": [][]int64{{65000, 1}},
+			}},
+		},
+	}
+
+	enriched, _, _ := FilterReasons(res, false, nil)
+	routes := enriched["routes"].([]bird.Parsed)
+	if routes[0]["filter_reason"] != "RPKI invalid" {
+		t.Error("Unexpected filter reason:", routes[0]["filter_reason"])
+	}
+	if reasons := routes[0]["filter_reasons"].([]string); len(reasons) != 2 || reasons[1] != "Blackhole" {
+		t.Error("Unexpected filter reasons:", reasons)
+	}
+	if _, ok := routes[1]["filter_reason"]; ok {
+		t.Error("Expected no filter reason for unmapped communities")
+	}
+	if _, ok := res["routes"].([]bird.Parsed)[0]["filter_reason"]; ok {
+		t.Error("Expected the original result to be unchanged")
+	}
+}
+
+func TestBogons(t *testing.T) {
+	BogonsConf = BogonsConfig{Enabled: true, Prefixes: []string{"23.128.0.0/10"}}
+	defer func() { BogonsConf = BogonsConfig{} }()
+	if err := LoadBogons(); err != nil {
+		t.Fatal(err)
+	}
+
+	res := bird.Parsed{
+		"routes": []bird.Parsed{
+			{"network": "10.1.0.0/16"},
+			{"network": "8.0.0.0/8"},
+			{"network": "0.0.0.0/0"},
+			{"network": "2001:db8:1::/48"},
+			{"network": "2001:db8::/31"},
+			{"network": "23.128.1.0/24"},
+		},
+	}
+
+	routes := enrichBogons(res)["routes"].([]bird.Parsed)
+	expected := []bool{true, false, false, true, false, true}
+	for i, route := range routes {
+		if (route["bogon"] == true) != expected[i] {
+			t.Error("Unexpected bogon annotation of", route["network"])
+		}
+	}
+
+	only := OnlyBogons(res)["routes"].([]bird.Parsed)
+	if len(only) != 3 {
+		t.Error("Expected 3 bogon routes, got:", only)
+	}
+
+	BogonsConf.Prefixes = []string{"invalid"}
+	if err := LoadBogons(); err == nil {
+		t.Error("Expected an error for an invalid prefix")
+	}
+}
+
+func TestValidateOrigin(t *testing.T) {
+	export := `{"roas": [
+		{"prefix": "10.0.0.0/8", "maxLength": 16, "asn": "AS65000"},
+		{"prefix": "10.1.0.0/16", "maxLength": 24, "asn": 65001},
+		{"prefix": "2001:db8::/32", "asn": "AS65002"}
+	]}`
+	table, count, err := parseROAFile(strings.NewReader(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Error("Expected 3 ROAs, got:", count)
+	}
+
+	tests := []struct {
+		network  string
+		origin   uint32
+		expected string
+	}{
+		{"10.0.0.0/8", 65000, ROAValid},
+		{"10.2.0.0/16", 65000, ROAValid},
+		{"10.2.0.0/24", 65000, ROAInvalid},
+		{"10.1.1.0/24", 65001, ROAValid},
+		{"10.1.1.0/24", 65000, ROAInvalid},
+		{"10.1.0.0/16", 65099, ROAInvalid},
+		{"8.8.8.0/24", 15169, ROAUnknown},
+		{"2001:db8::/32", 65002, ROAValid},
+		{"2001:db8::/48", 65002, ROAInvalid},
+	}
+	for _, test := range tests {
+		if v := table.validateOrigin(test.network, test.origin, true); v != test.expected {
+			t.Error("Expected", test.network, "from", test.origin, "to be", test.expected, "got:", v)
+		}
+	}
+
+	routes := `[{"prefix": "192.0.2.0/24", "origin": "AS65003"}]`
+	table, _, err = parseROAFile(strings.NewReader(routes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := table.validateOrigin("192.0.2.0/25", 65003, true); v != ROAInvalid {
+		t.Error("Expected more specifics of route objects to be invalid, got:", v)
+	}
+	if v := table.validateOrigin("192.0.2.0/24", 0, false); v != ROAInvalid {
+		t.Error("Expected routes without origin to be invalid, got:", v)
+	}
+}
+
+func TestEnrichBirdConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "birdconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := `
+# Route server clients
+template bgp rs_client {
+	local as 65000;
+	/* default policy */
+	ipv4 { import filter rs_import; export filter rs_export; };
+}
+
+filter rs_import { if net ~ [ 10.0.0.0/8+ ] then reject; accept; }
+
+protocol device { scan time 10; }
+include "peers/*.conf";
+`
+	peer := `
+protocol bgp R1 from rs_client {
+	description "Peer \"one\"";
+	neighbor 192.0.2.1 as 65001;
+	ipv4 { export filter { accept; }; };
+}
+protocol bgp R2 from rs_client {
+	neighbor 192.0.2.2 as 65002;
+	ipv4 { import filter r2_import; };
+}
+`
+	os.Mkdir(filepath.Join(dir, "peers"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "bird.conf"), []byte(config), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "peers", "r.conf"), []byte(peer), 0644)
+
+	bird.IPVersion = "4"
+	bird.ClientConf.ConfigFilename = filepath.Join(dir, "bird.conf")
+	defer func() { bird.ClientConf.ConfigFilename = "" }()
+
+	res := enrichBirdConfig(bird.Parsed{
+		"protocols": bird.Parsed{
+			"R1": bird.Parsed{"protocol": "R1", "neighbor_as": int64(65001)},
+			"R2": bird.Parsed{"protocol": "R2", "description": "From BIRD"},
+		},
+	})
+	protocols := res["protocols"].(bird.Parsed)
+	r1 := protocols["R1"].(bird.Parsed)
+	if r1["description"] != `Peer "one"` || r1["neighbor_address"] != "192.0.2.1" || r1["import_filter"] != "rs_import" ||
+		r1["export_filter"] != "rs_export" {
+		t.Error("Unexpected R1:", r1)
+	}
+	r2 := protocols["R2"].(bird.Parsed)
+	if r2["description"] != "From BIRD" || r2["neighbor_as"] != int64(65002) ||
+		r2["import_filter"] != "r2_import" {
+		t.Error("Unexpected R2:", r2)
+	}
+}
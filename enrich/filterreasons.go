@@ -0,0 +1,68 @@
+package enrich
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// FilterReasonsConfig maps communities like "65000:666"
+// and large communities like "65000:1000:3" to the
+// reason a route was filtered.
+type FilterReasonsConfig struct {
+	Enabled          bool              `toml:"enabled"`
+	Communities      map[string]string `toml:"communities"`
+	LargeCommunities map[string]string `toml:"large_communities"`
+}
+
+var FilterReasonsConf FilterReasonsConfig
+
+func communityKey(community []int64) string {
+	parts := make([]string, len(community))
+	for i, v := range community {
+		parts[i] = strconv.FormatInt(v, 10)
+	}
+	return strings.Join(parts, ":")
+}
+
+// filterReasons returns the reasons matching the
+// communities of a route. Large communities first.
+func filterReasons(route bird.Parsed) []string {
+	bgp, ok := route["bgp"].(bird.Parsed)
+	if !ok {
+		return nil
+	}
+
+	reasons := []string{}
+	lookup := func(field string, mapping map[string]string) {
+		communities, _ := bgp[field].([][]int64)
+		for _, community := range communities {
+			if reason, ok := mapping[communityKey(community)]; ok {
+				reasons = append(reasons, reason)
+			}
+		}
+	}
+	lookup("large_communities", FilterReasonsConf.LargeCommunities)
+	lookup("communities", FilterReasonsConf.Communities)
+
+	return reasons
+}
+
+// FilterReasons adds a filter_reason field to all filtered
+// routes with a community in the configured mapping. If
+// more than one community matches, all reasons are listed
+// in filter_reasons.
+func FilterReasons(res bird.Parsed, fromCache bool, err error) (bird.Parsed, bool, error) {
+	if !FilterReasonsConf.Enabled || err != nil {
+		return res, fromCache, err
+	}
+	return mapRoutes(res, func(route bird.Parsed) {
+		reasons := filterReasons(route)
+		if len(reasons) == 0 {
+			return
+		}
+		route["filter_reason"] = reasons[0]
+		route["filter_reasons"] = reasons
+	}), fromCache, nil
+}
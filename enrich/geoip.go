@@ -0,0 +1,104 @@
+package enrich
+
+import (
+	"log"
+	"net"
+	"sync"
+
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// GeoIPConfig configures the MaxMind (or compatible)
+// city or country database used for annotating routes.
+type GeoIPConfig struct {
+	Enabled  bool   `toml:"enabled"`
+	Database string `toml:"database"`
+}
+
+var GeoIPConf GeoIPConfig
+
+var geoDB struct {
+	sync.RWMutex
+	reader *maxminddb.Reader
+}
+
+type geoRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// OpenGeoIPDatabase opens the configured database
+func OpenGeoIPDatabase() error {
+	reader, err := maxminddb.Open(GeoIPConf.Database)
+	if err != nil {
+		return err
+	}
+
+	geoDB.Lock()
+	if geoDB.reader != nil {
+		geoDB.reader.Close()
+	}
+	geoDB.reader = reader
+	geoDB.Unlock()
+
+	log.Println("Using GeoIP database:", GeoIPConf.Database)
+	return nil
+}
+
+func lookupGeo(addr string) bird.Parsed {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil
+	}
+
+	geoDB.RLock()
+	defer geoDB.RUnlock()
+	if geoDB.reader == nil {
+		return nil
+	}
+
+	record := geoRecord{}
+	if err := geoDB.reader.Lookup(ip, &record); err != nil {
+		return nil
+	}
+	if record.Country.ISOCode == "" {
+		return nil
+	}
+
+	geo := bird.Parsed{"country": record.Country.ISOCode}
+	if city, ok := record.City.Names["en"]; ok {
+		geo["city"] = city
+	}
+	return geo
+}
+
+func enrichGeoIP(res bird.Parsed) bird.Parsed {
+	cache := make(map[string]bird.Parsed)
+	lookup := func(addr string) bird.Parsed {
+		geo, ok := cache[addr]
+		if !ok {
+			geo = lookupGeo(addr)
+			cache[addr] = geo
+		}
+		return geo
+	}
+
+	return mapRoutes(res, func(route bird.Parsed) {
+		if gw, ok := route["gateway"].(string); ok {
+			if geo := lookup(gw); geo != nil {
+				route["gateway_geo"] = geo
+			}
+		}
+		if from, ok := route["learnt_from"].(string); ok {
+			if geo := lookup(from); geo != nil {
+				route["learnt_from_geo"] = geo
+			}
+		}
+	})
+}
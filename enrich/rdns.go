@@ -0,0 +1,137 @@
+package enrich
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// RDNSConfig configures the reverse DNS lookups
+// of gateway and neighbor addresses.
+type RDNSConfig struct {
+	Enabled bool `toml:"enabled"`
+	Timeout int  `toml:"timeout"` // for all lookups of a request, in milliseconds
+	TTL     int  `toml:"ttl"`     // in minutes
+	Workers int  `toml:"workers"`
+}
+
+var RDNSConf RDNSConfig
+
+var lookupAddr = net.DefaultResolver.LookupAddr
+
+type ptrEntry struct {
+	hostname string
+	expires  time.Time
+}
+
+var ptrCache struct {
+	sync.Mutex
+	entries map[string]ptrEntry
+}
+
+// Resolve the PTR record of an address. Failed lookups
+// are cached as well, to not hit the timeout again, unless
+// the request was done before.
+func lookupPTR(ctx context.Context, addr string) string {
+	now := time.Now()
+
+	ptrCache.Lock()
+	entry, ok := ptrCache.entries[addr]
+	ptrCache.Unlock()
+	if ok && entry.expires.After(now) {
+		return entry.hostname
+	}
+	if ctx.Err() != nil {
+		return ""
+	}
+
+	ttl := time.Duration(RDNSConf.TTL) * time.Minute
+	if ttl <= 0 {
+		ttl = 60 * time.Minute
+	}
+
+	hostname := ""
+	names, err := lookupAddr(ctx, addr)
+	if err == nil && len(names) > 0 {
+		hostname = strings.TrimSuffix(names[0], ".")
+	}
+	if ctx.Err() != nil {
+		return hostname
+	}
+
+	ptrCache.Lock()
+	if ptrCache.entries == nil {
+		ptrCache.entries = make(map[string]ptrEntry)
+	}
+	ptrCache.entries[addr] = ptrEntry{hostname: hostname, expires: now.Add(ttl)}
+	ptrCache.Unlock()
+
+	return hostname
+}
+
+// Resolve all addresses with a limited number of workers
+func lookupPTRs(ctx context.Context, addrs []string) map[string]string {
+	workers := RDNSConf.Workers
+	if workers <= 0 {
+		workers = 8
+	}
+
+	jobs := make(chan string)
+	results := make(map[string]string, len(addrs))
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range jobs {
+				hostname := lookupPTR(ctx, addr)
+				lock.Lock()
+				results[addr] = hostname
+				lock.Unlock()
+			}
+		}()
+	}
+	for _, addr := range addrs {
+		jobs <- addr
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// enrichRDNS resolves the addresses of the result. All
+// lookups share the timeout, which bounds the delay of
+// the response, and end with the request.
+func enrichRDNS(ctx context.Context, res bird.Parsed) bird.Parsed {
+	addrs := collectValues(res, "gateway", "neighbor_address")
+	if len(addrs) == 0 {
+		return res
+	}
+
+	timeout := time.Duration(RDNSConf.Timeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 500 * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	hostnames := lookupPTRs(ctx, addrs)
+
+	res = mapRoutes(res, func(route bird.Parsed) {
+		if gw, ok := route["gateway"].(string); ok && hostnames[gw] != "" {
+			route["gateway_hostname"] = hostnames[gw]
+		}
+	})
+	res = mapProtocols(res, func(protocol bird.Parsed) {
+		if addr, ok := protocol["neighbor_address"].(string); ok && hostnames[addr] != "" {
+			protocol["neighbor_hostname"] = hostnames[addr]
+		}
+	})
+
+	return res
+}
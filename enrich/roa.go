@@ -0,0 +1,206 @@
+package enrich
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// ROAConfig configures the dataset of prefix origins
+// the routes are validated against.
+type ROAConfig struct {
+	Enabled bool   `toml:"enabled"`
+	File    string `toml:"file"`
+
+	RefreshInterval int `toml:"refresh_interval"` // in minutes
+}
+
+var ROAConf ROAConfig
+
+const (
+	ROAValid   = "valid"
+	ROAInvalid = "invalid"
+	ROAUnknown = "unknown"
+)
+
+// A roaEntry authorizes an origin AS to announce the
+// prefix up to the max length.
+type roaEntry struct {
+	asn       uint32
+	maxLength int
+}
+
+// roaTable indexes the entries by address family, prefix
+// length and network address.
+type roaTable map[int]map[int]map[string][]roaEntry
+
+var roas struct {
+	sync.RWMutex
+	table roaTable
+}
+
+// A roaObject is an entry of a ROA export, like
+//
+//	{"prefix": "10.0.0.0/8", "maxLength": 24, "asn": "AS65000"}
+//
+// or of RPSL route objects without a max length:
+//
+//	{"prefix": "10.0.0.0/8", "origin": "AS65000"}
+type roaObject struct {
+	Prefix    string          `json:"prefix"`
+	ASN       json.RawMessage `json:"asn"`
+	Origin    json.RawMessage `json:"origin"`
+	MaxLength int             `json:"maxLength"`
+	MaxLen    int             `json:"max_length"`
+}
+
+func parseROAASN(raw json.RawMessage) (uint32, error) {
+	value := strings.Trim(string(raw), `"`)
+	return bird.ParseASN(value)
+}
+
+// parseROAFile reads a JSON list of objects, or an object
+// with the list in "roas" or "routes".
+func parseROAFile(reader io.Reader) (roaTable, int, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	objects := []roaObject{}
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		export := struct {
+			ROAs   []roaObject `json:"roas"`
+			Routes []roaObject `json:"routes"`
+		}{}
+		if err := json.Unmarshal(data, &export); err != nil {
+			return nil, 0, err
+		}
+		objects = append(export.ROAs, export.Routes...)
+	} else if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, 0, err
+	}
+
+	table := roaTable{}
+	for _, object := range objects {
+		_, prefix, err := net.ParseCIDR(object.Prefix)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Invalid ROA prefix: %s", object.Prefix)
+		}
+		raw := object.ASN
+		if len(raw) == 0 {
+			raw = object.Origin
+		}
+		asn, err := parseROAASN(raw)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Invalid ROA origin of %s: %s", object.Prefix, raw)
+		}
+
+		length, bits := prefix.Mask.Size()
+		maxLength := object.MaxLength
+		if maxLength == 0 {
+			maxLength = object.MaxLen
+		}
+		if maxLength < length {
+			maxLength = length
+		}
+
+		if table[bits] == nil {
+			table[bits] = map[int]map[string][]roaEntry{}
+		}
+		if table[bits][length] == nil {
+			table[bits][length] = map[string][]roaEntry{}
+		}
+		key := prefix.IP.String()
+		table[bits][length][key] = append(table[bits][length][key], roaEntry{asn, maxLength})
+	}
+	return table, len(objects), nil
+}
+
+func loadROAs() (roaTable, int, error) {
+	f, err := os.Open(ROAConf.File)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	return parseROAFile(f)
+}
+
+// StartROARefresh loads the dataset and reloads
+// it periodically in the background.
+func StartROARefresh() {
+	interval := time.Duration(ROAConf.RefreshInterval) * time.Minute
+	if interval <= 0 {
+		interval = 60 * time.Minute
+	}
+
+	go func() {
+		for {
+			table, count, err := loadROAs()
+			if err != nil {
+				log.Println("Loading ROAs failed:", err)
+			} else {
+				roas.Lock()
+				roas.table = table
+				roas.Unlock()
+				log.Println("Loaded", count, "ROAs")
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// validateOrigin checks the origin of the prefix against
+// all covering entries, as described in RFC 6811.
+func (table roaTable) validateOrigin(network string, origin uint32, hasOrigin bool) string {
+	_, prefix, err := net.ParseCIDR(network)
+	if err != nil {
+		return ROAUnknown
+	}
+	length, bits := prefix.Mask.Size()
+
+	covered := false
+	for l := 0; l <= length; l++ {
+		entries, ok := table[bits][l]
+		if !ok {
+			continue
+		}
+		key := prefix.IP.Mask(net.CIDRMask(l, bits)).String()
+		for _, entry := range entries[key] {
+			covered = true
+			if hasOrigin && entry.asn != 0 && entry.asn == origin && length <= entry.maxLength {
+				return ROAValid
+			}
+		}
+	}
+	if covered {
+		return ROAInvalid
+	}
+	return ROAUnknown
+}
+
+// enrichROA adds the origin_validation of the routes
+func enrichROA(res bird.Parsed) bird.Parsed {
+	roas.RLock()
+	table := roas.table
+	roas.RUnlock()
+	if table == nil {
+		return res // Not loaded yet
+	}
+
+	return mapRoutes(res, func(route bird.Parsed) {
+		network, _ := route["network"].(string)
+		origin, ok := bird.RouteOrigin(route)
+		route["origin_validation"] = table.validateOrigin(network, origin, ok)
+	})
+}
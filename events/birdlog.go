@@ -0,0 +1,172 @@
+package events
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+var (
+	// 2024-01-15 10:23:45.123 <INFO> R192_175: State changed to up
+	reLogLine = regexp.MustCompile(
+		`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?) <(\w+)> (.*)$`)
+
+	// Jan 15 10:23:45 rs1 bird[1234]: R192_175: State changed to up
+	reSyslogLine = regexp.MustCompile(`\sbird6?(?:\[\d+\])?: (.*)$`)
+
+	reLogProtocol    = regexp.MustCompile(`^([\w.-]+): (.*)$`)
+	reLogStateChange = regexp.MustCompile(`^State changed to (\S+)`)
+)
+
+// parseLogLine creates an event for a protocol state
+// change or error. Other lines are ignored.
+func parseLogLine(line string, now time.Time) *Event {
+	line = strings.TrimSpace(line)
+
+	timestamp := now
+	level := ""
+	message := ""
+	if m := reLogLine.FindStringSubmatch(line); m != nil {
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05", m[1], time.Local); err == nil {
+			timestamp = t.UTC()
+		}
+		level = m[2]
+		message = m[3]
+	} else if m := reSyslogLine.FindStringSubmatch(line); m != nil {
+		message = m[1] // Syslog timestamps lack the year
+	} else {
+		return nil
+	}
+
+	m := reLogProtocol.FindStringSubmatch(message)
+	if m == nil {
+		return nil
+	}
+	protocol, text := m[1], m[2]
+
+	event := &Event{
+		Protocol:  protocol,
+		IPVersion: bird.IPVersion,
+		Timestamp: timestamp,
+		Source:    "log",
+		Message:   text,
+	}
+
+	if state := reLogStateChange.FindStringSubmatch(text); state != nil {
+		event.Type = TypeStateChange
+		event.State = state[1]
+		return event
+	}
+
+	switch level {
+	case "ERR", "WARN", "BUG", "FATAL":
+		event.Type = TypeLogError
+		return event
+	}
+	if strings.HasPrefix(text, "Error: ") {
+		event.Type = TypeLogError
+		return event
+	}
+
+	return nil
+}
+
+// A logTail follows a file like tail -F,
+// starting at the end of the file.
+type logTail struct {
+	filename string
+	file     *os.File
+	reader   *bufio.Reader
+	partial  string
+}
+
+// open (re)opens the file. A rotated file is read from
+// the start, otherwise the existing content is skipped.
+func (t *logTail) open(fromStart bool) error {
+	file, err := os.Open(t.filename)
+	if err != nil {
+		return err
+	}
+	if !fromStart {
+		if _, err := file.Seek(0, io.SeekEnd); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if t.file != nil {
+		t.file.Close()
+	}
+	t.file = file
+	t.reader = bufio.NewReader(file)
+	t.partial = ""
+	return nil
+}
+
+// rotated checks if the file was replaced or truncated
+func (t *logTail) rotated() bool {
+	info, err := os.Stat(t.filename)
+	if err != nil {
+		return false // Wait for the new file
+	}
+	current, err := t.file.Stat()
+	if err != nil {
+		return true
+	}
+	if !os.SameFile(info, current) {
+		return true
+	}
+	offset, err := t.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return true
+	}
+	return info.Size() < offset-int64(t.reader.Buffered())
+}
+
+// poll passes all complete new lines to the handler
+func (t *logTail) poll(handle func(string)) error {
+	if t.file == nil {
+		if err := t.open(false); err != nil {
+			return err
+		}
+	}
+
+	for {
+		line, err := t.reader.ReadString('\n')
+		t.partial += line
+		if err != nil {
+			break // Keep the incomplete line for the next poll
+		}
+		handle(t.partial)
+		t.partial = ""
+	}
+
+	if t.rotated() {
+		return t.open(true)
+	}
+	return nil
+}
+
+// tailLog follows the BIRD log and emits events
+func tailLog(filename string, emit func(*Event)) {
+	t := &logTail{filename: filename}
+	handle := func(line string) {
+		if event := parseLogLine(line, time.Now().UTC()); event != nil {
+			emit(event)
+		}
+	}
+
+	for {
+		if err := t.poll(handle); err != nil {
+			log.Println("Reading BIRD log failed:", err)
+			time.Sleep(10 * time.Second)
+			continue
+		}
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseLogLine(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		line     string
+		typ      string
+		protocol string
+		state    string
+	}{
+		{"2024-01-15 10:23:45.123 <INFO> R192_175: State changed to up", TypeStateChange, "R192_175", "up"},
+		{"Jan 15 10:23:45 rs1 bird[1234]: R192_175: State changed to stop", TypeStateChange, "R192_175", "stop"},
+		{"2024-01-15 10:23:46.001 <ERR> R194_42: Error: Hold timer expired", TypeLogError, "R194_42", ""},
+		{"Jan 15 10:23:45 rs1 bird6: R194_42: Error: Bad peer AS: 64500", TypeLogError, "R194_42", ""},
+		{"2024-01-15 10:23:47.000 <INFO> Reconfigured", "", "", ""},
+		{"2024-01-15 10:23:47.000 <TRACE> R192_175: Got KEEPALIVE", "", "", ""},
+		{"some unrelated line", "", "", ""},
+	}
+
+	for _, test := range tests {
+		event := parseLogLine(test.line, now)
+		if test.typ == "" {
+			if event != nil {
+				t.Error("Expected", test.line, "to be ignored, got:", event)
+			}
+			continue
+		}
+		if event == nil {
+			t.Error("Expected an event for:", test.line)
+			continue
+		}
+		if event.Type != test.typ || event.Protocol != test.protocol ||
+			event.State != test.state || event.Source != "log" {
+			t.Error("Unexpected event for", test.line, "got:", event)
+		}
+	}
+}
+
+func TestLogTail(t *testing.T) {
+	file, err := ioutil.TempFile("", "bird.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("old line\n")
+
+	lines := []string{}
+	handle := func(line string) { lines = append(lines, line) }
+
+	tail := &logTail{filename: file.Name()}
+	if err := tail.poll(handle); err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("first\nsec")
+	tail.poll(handle)
+	file.WriteString("ond\n")
+	tail.poll(handle)
+	file.Close()
+
+	if len(lines) != 2 || lines[0] != "first\n" || lines[1] != "second\n" {
+		t.Error("Unexpected lines:", lines)
+	}
+
+	// Rotation
+	if err := ioutil.WriteFile(file.Name(), []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tail.poll(handle)
+	tail.poll(handle)
+	if len(lines) != 3 || lines[2] != "new\n" {
+		t.Error("Expected the truncated file to be read from the start, got:", lines)
+	}
+}
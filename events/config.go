@@ -0,0 +1,31 @@
+package events
+
+// Event publishing configuration
+
+type Config struct {
+	Enabled bool   `toml:"enabled"`
+	Backend string `toml:"backend"` // nats, kafka or empty
+
+	NatsServer  string `toml:"nats_server"`
+	NatsSubject string `toml:"nats_subject"`
+
+	KafkaRestProxy string `toml:"kafka_rest_proxy"`
+	KafkaTopic     string `toml:"kafka_topic"`
+
+	// Refresh the protocols periodically (in seconds),
+	// otherwise changes are only detected when the
+	// protocols are requested through the API.
+	PollInterval int `toml:"poll_interval"`
+
+	// A route count delta is significant if it
+	// exceeds both thresholds. Zero disables a threshold.
+	RouteDeltaMin     int64 `toml:"route_delta_min"`
+	RouteDeltaPercent int64 `toml:"route_delta_percent"`
+
+	// Follow the BIRD log file for state changes and
+	// errors between protocols refreshes.
+	LogFile string `toml:"log_file"`
+
+	// Number of recent events available via /events
+	Recent int `toml:"recent"`
+}
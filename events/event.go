@@ -0,0 +1,152 @@
+package events
+
+import (
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+const (
+	TypeStateChange = "protocol_state"
+	TypeRouteDelta  = "route_count"
+	TypeLogError    = "log_error"
+)
+
+// An Event describes a change of a protocol between
+// two consecutive protocols refreshes, or a state
+// change or error from the BIRD log.
+type Event struct {
+	Type      string    `json:"type"`
+	Protocol  string    `json:"protocol"`
+	IPVersion string    `json:"ip_version"`
+	Timestamp time.Time `json:"timestamp"`
+
+	PreviousState string `json:"previous_state,omitempty"`
+	State         string `json:"state,omitempty"`
+
+	// The code and subcode of a BGP notification, set
+	// together as the subcode 0 is a valid subcode.
+	LastError           string `json:"last_error,omitempty"`
+	NotificationCode    *int64 `json:"notification_code,omitempty"`
+	NotificationSubcode *int64 `json:"notification_subcode,omitempty"`
+
+	// The imported routes of a route delta, set for
+	// route deltas only as a drop to 0 is a valid count.
+	PreviousRoutes *int64 `json:"previous_routes,omitempty"`
+	Routes         *int64 `json:"routes,omitempty"`
+
+	Source  string `json:"source,omitempty"` // "log" for events from the BIRD log
+	Message string `json:"message,omitempty"`
+}
+
+type protocolSnapshot struct {
+	state    string
+	imported int64
+
+	lastError           string
+	notificationCode    *int64
+	notificationSubcode *int64
+}
+
+func protocolState(protocol bird.Parsed) string {
+	state, _ := protocol["state"].(string)
+	if bgpState, ok := protocol["bgp_state"].(string); ok {
+		state += " " + bgpState
+	}
+	return state
+}
+
+func importedRoutes(protocol bird.Parsed) int64 {
+	routes, ok := protocol["routes"].(bird.Parsed)
+	if !ok {
+		return 0
+	}
+	imported, _ := routes["imported"].(int64)
+	return imported
+}
+
+func takeSnapshots(protocols bird.Parsed) map[string]protocolSnapshot {
+	snapshots := make(map[string]protocolSnapshot)
+	for name, p := range protocols {
+		protocol, ok := p.(bird.Parsed)
+		if !ok {
+			continue
+		}
+		lastError, _ := protocol["last_error"].(string)
+		snapshot := protocolSnapshot{
+			state:     protocolState(protocol),
+			imported:  importedRoutes(protocol),
+			lastError: lastError,
+		}
+		code, hasCode := protocol["notification_code"].(int64)
+		subcode, hasSubcode := protocol["notification_subcode"].(int64)
+		if hasCode && hasSubcode {
+			snapshot.notificationCode = &code
+			snapshot.notificationSubcode = &subcode
+		}
+		snapshots[name] = snapshot
+	}
+	return snapshots
+}
+
+func isSignificantDelta(config Config, previous, current int64) bool {
+	delta := current - previous
+	if delta < 0 {
+		delta = -delta
+	}
+	if delta == 0 {
+		return false
+	}
+	if config.RouteDeltaMin > 0 && delta < config.RouteDeltaMin {
+		return false
+	}
+	if config.RouteDeltaPercent > 0 && previous > 0 &&
+		delta*100/previous < config.RouteDeltaPercent {
+		return false
+	}
+	return true
+}
+
+// diffSnapshots creates events for all protocols which changed
+// their state or had a significant change in imported routes.
+func diffSnapshots(
+	config Config,
+	previous, current map[string]protocolSnapshot,
+	now time.Time,
+) []*Event {
+	events := []*Event{}
+	for name, cur := range current {
+		prev, ok := previous[name]
+		if !ok {
+			continue
+		}
+
+		if prev.state != cur.state {
+			events = append(events, &Event{
+				Type:          TypeStateChange,
+				Protocol:      name,
+				IPVersion:     bird.IPVersion,
+				Timestamp:     now,
+				PreviousState: prev.state,
+				State:         cur.state,
+
+				LastError:           cur.lastError,
+				NotificationCode:    cur.notificationCode,
+				NotificationSubcode: cur.notificationSubcode,
+			})
+		}
+
+		if isSignificantDelta(config, prev.imported, cur.imported) {
+			previousRoutes, routes := prev.imported, cur.imported
+			events = append(events, &Event{
+				Type:           TypeRouteDelta,
+				Protocol:       name,
+				IPVersion:      bird.IPVersion,
+				Timestamp:      now,
+				PreviousRoutes: &previousRoutes,
+				Routes:         &routes,
+			})
+		}
+	}
+	return events
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	previous := takeSnapshots(bird.Parsed{
+		"R1": bird.Parsed{
+			"state":     "up",
+			"bgp_state": "Established",
+			"routes":    bird.Parsed{"imported": int64(1000)},
+		},
+		"R2": bird.Parsed{
+			"state":  "up",
+			"routes": bird.Parsed{"imported": int64(1000)},
+		},
+	})
+	current := takeSnapshots(bird.Parsed{
+		"R1": bird.Parsed{
+			"state":     "start",
+			"bgp_state": "Active",
+			"routes":    bird.Parsed{"imported": int64(0)},
+
+			"last_error":           "Received: Hold timer expired",
+			"notification_code":    int64(4),
+			"notification_subcode": int64(0),
+		},
+		"R2": bird.Parsed{
+			"state":  "up",
+			"routes": bird.Parsed{"imported": int64(1010)},
+		},
+		"R3": bird.Parsed{
+			"state": "up",
+		},
+	})
+
+	config := Config{RouteDeltaMin: 50, RouteDeltaPercent: 10}
+	events := diffSnapshots(config, previous, current, time.Now())
+	if len(events) != 2 {
+		t.Fatal("Expected 2 events, got:", len(events))
+	}
+
+	for _, e := range events {
+		if e.Protocol != "R1" {
+			t.Error("Unexpected event for protocol:", e.Protocol)
+		}
+		if e.Type == TypeStateChange && e.State != "start Active" {
+			t.Error("Unexpected state:", e.State)
+		}
+		if e.Type == TypeStateChange && (e.LastError != "Received: Hold timer expired" ||
+			e.NotificationCode == nil || *e.NotificationCode != 4) {
+			t.Error("Unexpected last error:", e.LastError, e.NotificationCode)
+		}
+		if e.Type == TypeStateChange {
+			// The subcode 0 is included
+			data, _ := json.Marshal(e)
+			if !strings.Contains(string(data), `"notification_subcode":0`) {
+				t.Error("Expected the notification subcode, got:", string(data))
+			}
+		}
+		if e.Type == TypeRouteDelta && (e.Routes == nil || *e.Routes != 0 ||
+			e.PreviousRoutes == nil || *e.PreviousRoutes != 1000) {
+			t.Error("Unexpected route count:", e.PreviousRoutes, e.Routes)
+		}
+		if e.Type == TypeRouteDelta {
+			// A drop to 0 routes is included
+			data, _ := json.Marshal(e)
+			if !strings.Contains(string(data), `"routes":0`) {
+				t.Error("Expected the route count, got:", string(data))
+			}
+		}
+		if e.Type == TypeStateChange && e.Routes != nil {
+			t.Error("Expected no route count for a state change")
+		}
+	}
+}
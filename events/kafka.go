@@ -0,0 +1,59 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// KafkaPublisher produces messages to a Kafka topic
+// through a Kafka REST proxy (v2 API).
+type KafkaPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewKafkaPublisher creates a publisher for a topic.
+func NewKafkaPublisher(proxy, topic string) *KafkaPublisher {
+	return &KafkaPublisher{
+		url: strings.TrimRight(proxy, "/") + "/topics/" + topic,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value *Event `json:"value"`
+}
+
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+// Publish sends the event keyed by the protocol name
+func (k *KafkaPublisher) Publish(event *Event) error {
+	payload, err := json.Marshal(kafkaRecords{
+		Records: []kafkaRecord{{Key: event.Protocol, Value: event}},
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := k.client.Post(
+		k.url, "application/vnd.kafka.json.v2+json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("kafka rest proxy responded with: %s", res.Status)
+	}
+
+	return nil
+}
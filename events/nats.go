@@ -0,0 +1,114 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NatsPublisher implements the minimal subset of the
+// NATS client protocol required to publish messages.
+type NatsPublisher struct {
+	sync.Mutex
+	server  string
+	subject string
+
+	conn net.Conn
+}
+
+// NewNatsPublisher creates a publisher for a NATS subject.
+// The connection is established on the first publish.
+func NewNatsPublisher(server, subject string) *NatsPublisher {
+	return &NatsPublisher{
+		server:  server,
+		subject: subject,
+	}
+}
+
+func (n *NatsPublisher) connect() error {
+	conn, err := net.DialTimeout("tcp", n.server, 5*time.Second)
+	if err != nil {
+		return err
+	}
+
+	// The server greets us with an INFO line
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	info, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(info, "INFO") {
+		conn.Close()
+		return fmt.Errorf("unexpected NATS greeting: %s", info)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	_, err = conn.Write([]byte(`CONNECT {"verbose":false,"pedantic":false,"name":"birdwatcher"}` + "\r\n"))
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	n.conn = conn
+	go n.keepalive(conn, reader)
+
+	return nil
+}
+
+// Answer PINGs from the server, otherwise we will
+// be disconnected as a stale client.
+func (n *NatsPublisher) keepalive(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		if strings.HasPrefix(line, "PING") {
+			n.Lock()
+			_, err = conn.Write([]byte("PONG\r\n"))
+			n.Unlock()
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	n.Lock()
+	if n.conn == conn {
+		n.conn = nil
+	}
+	n.Unlock()
+	conn.Close()
+}
+
+// Publish sends the event as JSON to the subject
+func (n *NatsPublisher) Publish(event *Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	if n.conn == nil {
+		if err := n.connect(); err != nil {
+			return err
+		}
+	}
+
+	msg := fmt.Sprintf("PUB %s %d\r\n%s\r\n", n.subject, len(payload), payload)
+	if _, err := n.conn.Write([]byte(msg)); err != nil {
+		n.conn.Close()
+		n.conn = nil
+		return err
+	}
+
+	return nil
+}
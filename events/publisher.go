@@ -0,0 +1,133 @@
+package events
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// A Publisher sends events to a message stream.
+type Publisher interface {
+	Publish(event *Event) error
+}
+
+// NewPublisher creates the publisher for the configured backend
+func NewPublisher(config Config) (Publisher, error) {
+	switch config.Backend {
+	case "nats":
+		return NewNatsPublisher(config.NatsServer, config.NatsSubject), nil
+	case "kafka":
+		return NewKafkaPublisher(config.KafkaRestProxy, config.KafkaTopic), nil
+	}
+	return nil, fmt.Errorf("unknown event backend: %s", config.Backend)
+}
+
+var tracker struct {
+	sync.Mutex
+	snapshots map[string]protocolSnapshot
+}
+
+// The most recent events, oldest first
+var recent struct {
+	sync.Mutex
+	events []*Event
+	max    int
+}
+
+// record adds an event to the recent events
+func record(event *Event) {
+	recent.Lock()
+	defer recent.Unlock()
+
+	recent.events = append(recent.events, event)
+	if len(recent.events) > recent.max {
+		recent.events = recent.events[len(recent.events)-recent.max:]
+	}
+}
+
+// Recent returns the most recent events, newest first
+func Recent() []*Event {
+	recent.Lock()
+	defer recent.Unlock()
+
+	events := make([]*Event, 0, len(recent.events))
+	for i := len(recent.events) - 1; i >= 0; i-- {
+		events = append(events, recent.events[i])
+	}
+	return events
+}
+
+// Start registers the change detection with the protocols
+// refresh and publishes events in the background.
+// Without a backend, events are only kept for /events.
+func Start(config Config) error {
+	recent.Lock()
+	recent.max = config.Recent
+	if recent.max <= 0 {
+		recent.max = 1000
+	}
+	recent.Unlock()
+
+	var queue chan *Event
+	if config.Backend != "" {
+		publisher, err := NewPublisher(config)
+		if err != nil {
+			return err
+		}
+
+		queue = make(chan *Event, 1024)
+		go func() {
+			for event := range queue {
+				if err := publisher.Publish(event); err != nil {
+					log.Println("Publishing event failed:", err)
+				}
+			}
+		}()
+	}
+
+	emit := func(event *Event) {
+		record(event)
+		if queue == nil {
+			return
+		}
+		select {
+		case queue <- event:
+		default:
+			log.Println("Event queue full, dropping event for:", event.Protocol)
+		}
+	}
+
+	bird.RegisterProtocolsHook(func(p bird.Parsed) {
+		protocols, ok := p["protocols"].(bird.Parsed)
+		if !ok {
+			return
+		}
+		current := takeSnapshots(protocols)
+
+		tracker.Lock()
+		previous := tracker.snapshots
+		tracker.snapshots = current
+		tracker.Unlock()
+
+		if previous == nil {
+			return // Nothing to compare with
+		}
+
+		for _, event := range diffSnapshots(config, previous, current, time.Now().UTC()) {
+			emit(event)
+		}
+	})
+
+	if config.LogFile != "" {
+		go tailLog(config.LogFile, emit)
+	}
+
+	if config.PollInterval > 0 {
+		bird.PollProtocols(time.Duration(config.PollInterval) * time.Second)
+	}
+
+	return nil
+}
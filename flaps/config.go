@@ -0,0 +1,13 @@
+package flaps
+
+// Session flap history configuration
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// Number of transitions kept per protocol
+	Size int `toml:"size"`
+
+	// Persist the history to this file, if set
+	File string `toml:"file"`
+}
@@ -0,0 +1,246 @@
+package flaps
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// A Transition is a change of the session state
+type Transition struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ring is a fixed size buffer of transitions
+type ring struct {
+	Transitions []Transition `json:"transitions"`
+	Next        int          `json:"next"`
+	Full        bool         `json:"full"`
+}
+
+func (r *ring) add(t Transition, size int) {
+	if len(r.Transitions) < size && !r.Full {
+		r.Transitions = append(r.Transitions, t)
+		if len(r.Transitions) == size {
+			r.Full = true
+		}
+		return
+	}
+	r.Transitions[r.Next] = t
+	r.Next = (r.Next + 1) % len(r.Transitions)
+}
+
+// ordered returns the transitions, oldest first
+func (r *ring) ordered() []Transition {
+	if !r.Full {
+		return append([]Transition{}, r.Transitions...)
+	}
+	return append(
+		append([]Transition{}, r.Transitions[r.Next:]...),
+		r.Transitions[:r.Next]...)
+}
+
+var history struct {
+	sync.RWMutex
+	config   Config
+	states   map[string]string
+	buffers  map[string]*ring
+	accounts map[string]*account
+}
+
+const birdTimeLayout = "2006-01-02 15:04:05"
+
+// The state change timestamp reported by BIRD is
+// more accurate than the time of the refresh.
+func transitionTime(protocol bird.Parsed, now time.Time) time.Time {
+	since, ok := protocol["state_changed"].(string)
+	if !ok || len(since) < len(birdTimeLayout) {
+		return now
+	}
+	t, err := time.ParseInLocation(birdTimeLayout, since[:len(birdTimeLayout)], time.Local)
+	if err != nil || t.After(now) {
+		return now
+	}
+	return t.UTC()
+}
+
+func sessionState(protocol bird.Parsed) string {
+	if state, ok := protocol["bgp_state"].(string); ok {
+		return state
+	}
+	state, _ := protocol["state"].(string)
+	return state
+}
+
+func update(protocols bird.Parsed, now time.Time) {
+	history.Lock()
+	defer history.Unlock()
+
+	size := history.config.Size
+	if size <= 0 {
+		size = 100
+	}
+
+	for name, p := range protocols {
+		protocol, ok := p.(bird.Parsed)
+		if !ok || protocol["bird_protocol"] != "BGP" {
+			continue
+		}
+
+		state := sessionState(protocol)
+		previous, known := history.states[name]
+		history.states[name] = state
+		if !known || previous == state {
+			continue
+		}
+
+		buf, ok := history.buffers[name]
+		if !ok {
+			buf = &ring{}
+			history.buffers[name] = buf
+		}
+		buf.add(Transition{
+			From:      previous,
+			To:        state,
+			Timestamp: transitionTime(protocol, now),
+		}, size)
+	}
+}
+
+// History returns the transitions of a protocol with the
+// duration the session stayed in the new state.
+func History(protocol string, now time.Time) bird.Parsed {
+	history.RLock()
+	transitions := []Transition{}
+	if buf, ok := history.buffers[protocol]; ok {
+		transitions = buf.ordered()
+	}
+	state := history.states[protocol]
+	history.RUnlock()
+
+	entries := make([]bird.Parsed, 0, len(transitions))
+	flaps24h := 0
+	flaps7d := 0
+	for i, t := range transitions {
+		end := now
+		if i+1 < len(transitions) {
+			end = transitions[i+1].Timestamp
+		}
+		entries = append(entries, bird.Parsed{
+			"from":      t.From,
+			"to":        t.To,
+			"timestamp": t.Timestamp,
+			"duration":  end.Sub(t.Timestamp).Seconds(),
+		})
+
+		if t.From == "Established" {
+			if now.Sub(t.Timestamp) <= 24*time.Hour {
+				flaps24h++
+			}
+			if now.Sub(t.Timestamp) <= 7*24*time.Hour {
+				flaps7d++
+			}
+		}
+	}
+
+	return bird.Parsed{
+		"protocol":  protocol,
+		"state":     state,
+		"history":   entries,
+		"flaps_24h": flaps24h,
+		"flaps_7d":  flaps7d,
+	}
+}
+
+type persisted struct {
+	States   map[string]string   `json:"states"`
+	Buffers  map[string]*ring    `json:"buffers"`
+	Accounts map[string]*account `json:"accounts"`
+}
+
+func load(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	p := persisted{}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	history.Lock()
+	if p.States != nil {
+		history.states = p.States
+	}
+	if p.Buffers != nil {
+		history.buffers = p.Buffers
+	}
+	if p.Accounts != nil {
+		history.accounts = p.Accounts
+	}
+	history.Unlock()
+
+	return nil
+}
+
+func save(filename string) error {
+	history.RLock()
+	data, err := json.Marshal(persisted{
+		States:   history.states,
+		Buffers:  history.buffers,
+		Accounts: history.accounts,
+	})
+	history.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// Start tracking state transitions and uptime
+// on every protocols refresh
+func Start(config Config) {
+	history.Lock()
+	history.config = config
+	history.states = make(map[string]string)
+	history.buffers = make(map[string]*ring)
+	history.accounts = make(map[string]*account)
+	history.Unlock()
+
+	if config.File != "" {
+		if err := load(config.File); err != nil {
+			log.Println("Loading flap history failed:", err)
+		}
+	}
+
+	bird.RegisterProtocolsHook(func(p bird.Parsed) {
+		protocols, ok := p["protocols"].(bird.Parsed)
+		if !ok {
+			return
+		}
+		now := time.Now().UTC()
+		update(protocols, now)
+		accountUptime(protocols, now)
+		if config.File != "" {
+			if err := save(config.File); err != nil {
+				log.Println("Saving flap history failed:", err)
+			}
+		}
+	})
+}
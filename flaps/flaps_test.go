@@ -0,0 +1,91 @@
+package flaps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+func TestRing(t *testing.T) {
+	r := &ring{}
+	for i := 0; i < 5; i++ {
+		r.add(Transition{To: string(rune('a' + i))}, 3)
+	}
+
+	transitions := r.ordered()
+	if len(transitions) != 3 {
+		t.Fatal("Expected 3 transitions, got:", len(transitions))
+	}
+	if transitions[0].To != "c" || transitions[2].To != "e" {
+		t.Error("Unexpected order:", transitions)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	Start(Config{Size: 10})
+
+	bgp := func(state string) bird.Parsed {
+		return bird.Parsed{
+			"R1": bird.Parsed{"bird_protocol": "BGP", "bgp_state": state},
+		}
+	}
+
+	now := time.Now().UTC()
+	update(bgp("Established"), now.Add(-3*time.Hour))
+	update(bgp("Active"), now.Add(-2*time.Hour))
+	update(bgp("Established"), now.Add(-1*time.Hour))
+
+	h := History("R1", now)
+	entries := h["history"].([]bird.Parsed)
+	if len(entries) != 2 {
+		t.Fatal("Expected 2 transitions, got:", len(entries))
+	}
+	if entries[0]["duration"].(float64) != 3600 {
+		t.Error("Expected the session to be down for an hour:", entries[0])
+	}
+	if h["flaps_24h"].(int) != 1 {
+		t.Error("Expected one flap, got:", h["flaps_24h"])
+	}
+}
+
+func TestUptime(t *testing.T) {
+	Start(Config{Size: 10})
+
+	bgp := func(state, changed string) bird.Parsed {
+		return bird.Parsed{
+			"R1": bird.Parsed{
+				"bird_protocol": "BGP",
+				"bgp_state":     state,
+				"state_changed": changed,
+			},
+		}
+	}
+
+	start := time.Date(2021, 3, 30, 12, 0, 0, 0, time.Local)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute).UTC()
+	}
+
+	accountUptime(bgp("Established", "2021-03-30 11:00:00"), at(0))
+	accountUptime(bgp("Established", "2021-03-30 11:00:00"), at(60))
+	// Went down after 30 minutes
+	accountUptime(bgp("Active", "2021-03-30 13:30:00"), at(120))
+	// Flapped in between, down again since 14:50
+	accountUptime(bgp("Active", "2021-03-30 14:50:00"), at(180))
+	accountUptime(bgp("Established", "2021-03-30 15:00:00"), at(240))
+
+	uptime := Uptime()
+	if len(uptime) != 1 {
+		t.Fatal("Expected one session, got:", uptime)
+	}
+	up := uptime[0]["up"].(float64)
+	down := uptime[0]["down"].(float64)
+	if up != (60+30+50+60)*60 || down != (30+10)*60 {
+		t.Error("Unexpected up/down time:", up/60, down/60)
+	}
+	availability := uptime[0]["availability"].(float64)
+	if availability < 83.33 || availability > 83.34 {
+		t.Error("Unexpected availability:", availability)
+	}
+}
@@ -0,0 +1,120 @@
+package flaps
+
+import (
+	"sort"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// An account is the cumulative time a session
+// was up or down since it was first seen.
+type account struct {
+	Up           float64   `json:"up"`
+	Down         float64   `json:"down"`
+	Established  bool      `json:"established"`
+	StateChanged string    `json:"state_changed"`
+	Since        time.Time `json:"since"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+func (a *account) add(established bool, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	if established {
+		a.Up += d.Seconds()
+	} else {
+		a.Down += d.Seconds()
+	}
+}
+
+// observe accounts the time since the last observation.
+// If the session changed its state since then, the
+// time is split at the reported state change.
+func (a *account) observe(protocol bird.Parsed, now time.Time) {
+	established := sessionState(protocol) == "Established"
+	changed, _ := protocol["state_changed"].(string)
+
+	if established != a.Established || changed != a.StateChanged {
+		t := transitionTime(protocol, now)
+		if t.Before(a.LastSeen) {
+			t = a.LastSeen
+		}
+		// With the same state the session flapped in between,
+		// the time before the last change is accounted to the
+		// opposite state.
+		a.add(!established, t.Sub(a.LastSeen))
+		a.add(established, now.Sub(t))
+	} else {
+		a.add(established, now.Sub(a.LastSeen))
+	}
+
+	a.Established = established
+	a.StateChanged = changed
+	a.LastSeen = now
+}
+
+func accountUptime(protocols bird.Parsed, now time.Time) {
+	history.Lock()
+	defer history.Unlock()
+
+	for name, p := range protocols {
+		protocol, ok := p.(bird.Parsed)
+		if !ok || protocol["bird_protocol"] != "BGP" {
+			continue
+		}
+
+		a, ok := history.accounts[name]
+		if !ok {
+			changed, _ := protocol["state_changed"].(string)
+			history.accounts[name] = &account{
+				Established:  sessionState(protocol) == "Established",
+				StateChanged: changed,
+				Since:        now,
+				LastSeen:     now,
+			}
+			continue
+		}
+		a.observe(protocol, now)
+	}
+}
+
+func availability(a *account) float64 {
+	total := a.Up + a.Down
+	if total == 0 {
+		if a.Established {
+			return 100
+		}
+		return 0
+	}
+	return a.Up / total * 100
+}
+
+// Uptime returns the cumulative up and down time in seconds
+// and the availability in percent of all BGP sessions.
+func Uptime() []bird.Parsed {
+	history.RLock()
+	defer history.RUnlock()
+
+	names := make([]string, 0, len(history.accounts))
+	for name := range history.accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	uptime := make([]bird.Parsed, 0, len(names))
+	for _, name := range names {
+		a := history.accounts[name]
+		uptime = append(uptime, bird.Parsed{
+			"protocol":     name,
+			"established":  a.Established,
+			"up":           a.Up,
+			"down":         a.Down,
+			"availability": availability(a),
+			"since":        a.Since,
+			"last_seen":    a.LastSeen,
+		})
+	}
+	return uptime
+}
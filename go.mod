@@ -10,4 +10,5 @@ require (
 	github.com/imdario/mergo v0.3.8
 	github.com/julienschmidt/httprouter v1.3.0
 	github.com/kr/pretty v0.1.0
+	github.com/oschwald/maxminddb-golang v1.8.0
 )
@@ -0,0 +1,17 @@
+package history
+
+// Snapshot storage configuration
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// Snapshots are stored as one file per day
+	// in this directory
+	Directory string `toml:"directory"`
+
+	// Snapshot interval in seconds
+	Interval int `toml:"interval"`
+
+	// Remove snapshots older than this number of days
+	Retention int `toml:"retention"`
+}
@@ -0,0 +1,194 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// A StateChange is a protocol with a different
+// state in the second snapshot.
+type StateChange struct {
+	Protocol      string `json:"protocol"`
+	PreviousState string `json:"previous_state"`
+	State         string `json:"state"`
+}
+
+// A RouteDelta is the change of route counts of a protocol
+type RouteDelta struct {
+	Protocol         string `json:"protocol"`
+	PreviousImported int64  `json:"previous_imported"`
+	Imported         int64  `json:"imported"`
+	ImportedDelta    int64  `json:"imported_delta"`
+	PreviousExported int64  `json:"previous_exported"`
+	Exported         int64  `json:"exported"`
+	ExportedDelta    int64  `json:"exported_delta"`
+	PreviousFiltered int64  `json:"previous_filtered"`
+	Filtered         int64  `json:"filtered"`
+	FilteredDelta    int64  `json:"filtered_delta"`
+}
+
+// A Diff describes the differences between two snapshots
+type Diff struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	StateChanges []StateChange `json:"state_changes"`
+	Added        []string      `json:"added"`
+	Removed      []string      `json:"removed"`
+	RouteDeltas  []RouteDelta  `json:"route_deltas"`
+}
+
+func (p ProtocolSnapshot) fullState() string {
+	if p.BGPState != "" {
+		return p.State + " " + p.BGPState
+	}
+	return p.State
+}
+
+// DiffSnapshots compares two snapshots. All lists
+// are sorted by protocol name.
+func DiffSnapshots(from, to *Snapshot) *Diff {
+	diff := &Diff{
+		From:         from.Timestamp,
+		To:           to.Timestamp,
+		StateChanges: []StateChange{},
+		Added:        []string{},
+		Removed:      []string{},
+		RouteDeltas:  []RouteDelta{},
+	}
+
+	names := make([]string, 0, len(to.Protocols))
+	for name := range to.Protocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		cur := to.Protocols[name]
+		prev, ok := from.Protocols[name]
+		if !ok {
+			diff.Added = append(diff.Added, name)
+			continue
+		}
+
+		if prev.fullState() != cur.fullState() {
+			diff.StateChanges = append(diff.StateChanges, StateChange{
+				Protocol:      name,
+				PreviousState: prev.fullState(),
+				State:         cur.fullState(),
+			})
+		}
+
+		if prev.Imported != cur.Imported ||
+			prev.Exported != cur.Exported ||
+			prev.Filtered != cur.Filtered {
+			diff.RouteDeltas = append(diff.RouteDeltas, RouteDelta{
+				Protocol:         name,
+				PreviousImported: prev.Imported,
+				Imported:         cur.Imported,
+				ImportedDelta:    cur.Imported - prev.Imported,
+				PreviousExported: prev.Exported,
+				Exported:         cur.Exported,
+				ExportedDelta:    cur.Exported - prev.Exported,
+				PreviousFiltered: prev.Filtered,
+				Filtered:         cur.Filtered,
+				FilteredDelta:    cur.Filtered - prev.Filtered,
+			})
+		}
+	}
+
+	for name := range from.Protocols {
+		if _, ok := to.Protocols[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Removed)
+
+	return diff
+}
+
+// Days searched back for the latest snapshot before a time
+const snapshotLookback = 7
+
+// SnapshotAt returns the latest stored snapshot
+// taken at or before the given time.
+func SnapshotAt(t time.Time) (*Snapshot, error) {
+	snapshots.RLock()
+	defer snapshots.RUnlock()
+
+	if snapshots.store == nil {
+		return nil, fmt.Errorf("History is not enabled")
+	}
+
+	t = t.UTC()
+	day := t.Truncate(24 * time.Hour)
+	for i := 0; i <= snapshotLookback; i++ {
+		res, err := snapshots.store.readDay(day, time.Time{}, t)
+		if err != nil {
+			return nil, err
+		}
+		if len(res) > 0 {
+			return res[len(res)-1], nil
+		}
+		day = day.Add(-24 * time.Hour)
+	}
+
+	return nil, fmt.Errorf("No snapshot found before %s", t.Format(time.RFC3339))
+}
+
+// CurrentSnapshot takes a snapshot of the protocols now
+func CurrentSnapshot(useCache bool) (*Snapshot, error) {
+	res, _, err := bird.Protocols(context.Background(), useCache)
+	if err != nil {
+		return nil, err
+	}
+	protocols, ok := res["protocols"].(bird.Parsed)
+	if !ok {
+		return nil, fmt.Errorf("Could not get protocols")
+	}
+	return takeSnapshot(protocols, time.Now().UTC()), nil
+}
+
+// Changes are the protocols which changed after a snapshot
+type Changes struct {
+	Snapshot time.Time `json:"snapshot"`
+	Changed  []string  `json:"changed"`
+	Removed  []string  `json:"removed"`
+}
+
+// changedProtocols lists the protocols of the second snapshot which
+// are new or differ in state, description or route counts.
+func changedProtocols(from, to *Snapshot) *Changes {
+	changes := &Changes{
+		Snapshot: from.Timestamp,
+		Changed:  []string{},
+		Removed:  []string{},
+	}
+	for name, cur := range to.Protocols {
+		if prev, ok := from.Protocols[name]; !ok || prev != cur {
+			changes.Changed = append(changes.Changed, name)
+		}
+	}
+	for name := range from.Protocols {
+		if _, ok := to.Protocols[name]; !ok {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+	sort.Strings(changes.Changed)
+	sort.Strings(changes.Removed)
+	return changes
+}
+
+// ChangesSince compares the protocols to the latest
+// snapshot taken at or before since.
+func ChangesSince(since time.Time, protocols bird.Parsed) (*Changes, error) {
+	from, err := SnapshotAt(since)
+	if err != nil {
+		return nil, err
+	}
+	return changedProtocols(from, takeSnapshot(protocols, time.Now().UTC())), nil
+}
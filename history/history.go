@@ -0,0 +1,91 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+var snapshots struct {
+	sync.RWMutex
+	store *store
+}
+
+func record(now time.Time) {
+	res, _, err := bird.Protocols(context.Background(), true)
+	if err != nil {
+		log.Println("History snapshot failed:", err)
+		return
+	}
+	protocols, _ := res["protocols"].(bird.Parsed)
+
+	snapshots.Lock()
+	defer snapshots.Unlock()
+
+	if err := snapshots.store.append(takeSnapshot(protocols, now)); err != nil {
+		log.Println("Storing history snapshot failed:", err)
+	}
+}
+
+// Snapshots returns the stored snapshots between from and to.
+// If protocol is not empty, only this protocol is included.
+func Snapshots(from, to time.Time, protocol string) ([]*Snapshot, error) {
+	snapshots.RLock()
+	defer snapshots.RUnlock()
+
+	if snapshots.store == nil {
+		return nil, fmt.Errorf("History is not enabled")
+	}
+
+	res, err := snapshots.store.read(from, to)
+	if err != nil || protocol == "" {
+		return res, err
+	}
+
+	for i, s := range res {
+		res[i] = s.filterProtocol(protocol)
+	}
+	return res, nil
+}
+
+// Start taking snapshots in the configured interval
+func Start(config Config) error {
+	if config.Directory == "" {
+		return fmt.Errorf("history directory is not set")
+	}
+	if err := os.MkdirAll(config.Directory, 0755); err != nil {
+		return err
+	}
+
+	interval := config.Interval
+	if interval <= 0 {
+		interval = 300
+	}
+
+	snapshots.Lock()
+	snapshots.store = &store{directory: config.Directory}
+	snapshots.Unlock()
+
+	go func() {
+		for {
+			now := time.Now().UTC()
+			record(now)
+
+			snapshots.Lock()
+			err := snapshots.store.prune(now, config.Retention)
+			snapshots.Unlock()
+			if err != nil {
+				log.Println("Pruning history snapshots failed:", err)
+			}
+
+			time.Sleep(time.Duration(interval) * time.Second)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	now := time.Date(2021, 3, 30, 12, 0, 0, 0, time.UTC)
+	protocols := bird.Parsed{
+		"R1": bird.Parsed{
+			"bird_protocol": "BGP",
+			"state":         "up",
+			"bgp_state":     "Established",
+			"state_changed": "2021-03-29 08:00:00",
+			"routes": bird.Parsed{
+				"imported":  int64(10),
+				"exported":  int64(2),
+				"filtered":  int64(1),
+				"preferred": int64(9),
+			},
+		},
+		"device1": bird.Parsed{
+			"bird_protocol": "Device",
+			"state":         "up",
+		},
+	}
+
+	s := takeSnapshot(protocols, now)
+	if len(s.Protocols) != 2 {
+		t.Fatal("Expected 2 protocols, got:", len(s.Protocols))
+	}
+	r1 := s.Protocols["R1"]
+	if r1.BGPState != "Established" || r1.Imported != 10 || r1.Preferred != 9 {
+		t.Error("Unexpected snapshot:", r1)
+	}
+	if s.Protocols["device1"].Imported != 0 {
+		t.Error("Expected no routes for device1")
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &store{directory: dir}
+	start := time.Date(2021, 3, 28, 23, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		snapshot := &Snapshot{
+			Timestamp: start.Add(time.Duration(i) * 12 * time.Hour),
+			Protocols: map[string]ProtocolSnapshot{
+				"R1": {State: "up", Imported: int64(i)},
+				"R2": {State: "down"},
+			},
+		}
+		if err := s.append(snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Snapshots are at 28. 23:00, 29. 11:00, 29. 23:00, 30. 11:00
+	res, err := s.read(start.Add(time.Hour), start.Add(36*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 3 {
+		t.Fatal("Expected 3 snapshots, got:", len(res))
+	}
+	if res[0].Protocols["R1"].Imported != 1 || res[2].Protocols["R1"].Imported != 3 {
+		t.Error("Unexpected snapshots:", res)
+	}
+
+	if len(res[0].filterProtocol("R2").Protocols) != 1 {
+		t.Error("Expected only R2 in the filtered snapshot")
+	}
+
+	if _, err := s.read(start, start.Add(-time.Hour)); err == nil {
+		t.Error("Expected an error for an invalid time range")
+	}
+
+	// Keep the current and one previous day
+	if err := s.prune(time.Date(2021, 3, 30, 1, 0, 0, 0, time.UTC), 1); err != nil {
+		t.Fatal(err)
+	}
+	res, _ = s.read(start, start.Add(48*time.Hour))
+	if len(res) != 3 {
+		t.Error("Expected 3 snapshots after pruning, got:", len(res))
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	from := &Snapshot{
+		Timestamp: time.Date(2021, 3, 30, 12, 0, 0, 0, time.UTC),
+		Protocols: map[string]ProtocolSnapshot{
+			"R1": {State: "up", BGPState: "Established", Imported: 100},
+			"R2": {State: "up", BGPState: "Established", Imported: 10},
+			"R3": {State: "up", BGPState: "Established"},
+		},
+	}
+	to := &Snapshot{
+		Timestamp: time.Date(2021, 3, 30, 14, 0, 0, 0, time.UTC),
+		Protocols: map[string]ProtocolSnapshot{
+			"R1": {State: "start", BGPState: "Active"},
+			"R2": {State: "up", BGPState: "Established", Imported: 12, Filtered: 1},
+			"R4": {State: "up", BGPState: "Established", Imported: 5},
+		},
+	}
+
+	diff := DiffSnapshots(from, to)
+	if len(diff.StateChanges) != 1 || diff.StateChanges[0].Protocol != "R1" ||
+		diff.StateChanges[0].State != "start Active" {
+		t.Error("Unexpected state changes:", diff.StateChanges)
+	}
+	if len(diff.Added) != 1 || diff.Added[0] != "R4" {
+		t.Error("Unexpected added protocols:", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != "R3" {
+		t.Error("Unexpected removed protocols:", diff.Removed)
+	}
+	if len(diff.RouteDeltas) != 2 {
+		t.Fatal("Expected 2 route deltas, got:", diff.RouteDeltas)
+	}
+	if diff.RouteDeltas[0].ImportedDelta != -100 ||
+		diff.RouteDeltas[1].ImportedDelta != 2 || diff.RouteDeltas[1].FilteredDelta != 1 {
+		t.Error("Unexpected route deltas:", diff.RouteDeltas)
+	}
+}
+
+func TestChangedProtocols(t *testing.T) {
+	from := &Snapshot{
+		Timestamp: time.Date(2021, 3, 30, 12, 0, 0, 0, time.UTC),
+		Protocols: map[string]ProtocolSnapshot{
+			"R1": {State: "up", BGPState: "Established", Imported: 100},
+			"R2": {State: "up", BGPState: "Established", Description: "Peer 2"},
+			"R3": {State: "up", BGPState: "Established"},
+			"R5": {State: "up", BGPState: "Established", Imported: 10},
+		},
+	}
+	to := &Snapshot{
+		Timestamp: time.Date(2021, 3, 30, 14, 0, 0, 0, time.UTC),
+		Protocols: map[string]ProtocolSnapshot{
+			"R1": {State: "up", BGPState: "Established", Imported: 101},
+			"R2": {State: "up", BGPState: "Established", Description: "Peer two"},
+			"R4": {State: "up", BGPState: "Established"},
+			"R5": {State: "up", BGPState: "Established", Imported: 10},
+		},
+	}
+
+	changes := changedProtocols(from, to)
+	if strings.Join(changes.Changed, ",") != "R1,R2,R4" {
+		t.Error("Unexpected changed protocols:", changes.Changed)
+	}
+	if len(changes.Removed) != 1 || changes.Removed[0] != "R3" {
+		t.Error("Unexpected removed protocols:", changes.Removed)
+	}
+	if !changes.Snapshot.Equal(from.Timestamp) {
+		t.Error("Unexpected snapshot time:", changes.Snapshot)
+	}
+}
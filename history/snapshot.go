@@ -0,0 +1,78 @@
+package history
+
+import (
+	"time"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// A ProtocolSnapshot is the state and route
+// counts of a protocol at a point in time.
+type ProtocolSnapshot struct {
+	BirdProtocol string `json:"bird_protocol"`
+	State        string `json:"state"`
+	BGPState     string `json:"bgp_state,omitempty"`
+	StateChanged string `json:"state_changed,omitempty"`
+	Description  string `json:"description,omitempty"`
+
+	Imported  int64 `json:"imported"`
+	Exported  int64 `json:"exported"`
+	Filtered  int64 `json:"filtered"`
+	Preferred int64 `json:"preferred"`
+}
+
+// A Snapshot holds all protocols at a point in time
+type Snapshot struct {
+	Timestamp time.Time                   `json:"timestamp"`
+	Protocols map[string]ProtocolSnapshot `json:"protocols"`
+}
+
+func routeCount(routes bird.Parsed, key string) int64 {
+	count, _ := routes[key].(int64)
+	return count
+}
+
+func takeSnapshot(protocols bird.Parsed, now time.Time) *Snapshot {
+	snapshot := &Snapshot{
+		Timestamp: now,
+		Protocols: make(map[string]ProtocolSnapshot),
+	}
+
+	for name, p := range protocols {
+		protocol, ok := p.(bird.Parsed)
+		if !ok {
+			continue
+		}
+
+		s := ProtocolSnapshot{}
+		s.BirdProtocol, _ = protocol["bird_protocol"].(string)
+		s.State, _ = protocol["state"].(string)
+		s.BGPState, _ = protocol["bgp_state"].(string)
+		s.StateChanged, _ = protocol["state_changed"].(string)
+		s.Description, _ = protocol["description"].(string)
+
+		if routes, ok := protocol["routes"].(bird.Parsed); ok {
+			s.Imported = routeCount(routes, "imported")
+			s.Exported = routeCount(routes, "exported")
+			s.Filtered = routeCount(routes, "filtered")
+			s.Preferred = routeCount(routes, "preferred")
+		}
+
+		snapshot.Protocols[name] = s
+	}
+
+	return snapshot
+}
+
+// filterProtocol returns a copy of the snapshot
+// containing only the given protocol.
+func (s *Snapshot) filterProtocol(protocol string) *Snapshot {
+	filtered := &Snapshot{
+		Timestamp: s.Timestamp,
+		Protocols: make(map[string]ProtocolSnapshot),
+	}
+	if p, ok := s.Protocols[protocol]; ok {
+		filtered.Protocols[protocol] = p
+	}
+	return filtered
+}
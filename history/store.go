@@ -0,0 +1,126 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	filePrefix = "snapshots-"
+	fileSuffix = ".jsonl"
+	dayLayout  = "2006-01-02"
+)
+
+// A store keeps snapshots as json lines in one file per day
+type store struct {
+	directory string
+}
+
+func (s *store) filename(day time.Time) string {
+	return filepath.Join(
+		s.directory,
+		filePrefix+day.UTC().Format(dayLayout)+fileSuffix)
+}
+
+func (s *store) append(snapshot *Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(
+		s.filename(snapshot.Timestamp),
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func (s *store) readDay(day time.Time, from, to time.Time) ([]*Snapshot, error) {
+	f, err := os.Open(s.filename(day))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	snapshots := []*Snapshot{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		snapshot := &Snapshot{}
+		if err := json.Unmarshal(scanner.Bytes(), snapshot); err != nil {
+			// Skip partially written lines
+			continue
+		}
+		if snapshot.Timestamp.Before(from) || snapshot.Timestamp.After(to) {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, scanner.Err()
+}
+
+// read returns all snapshots between from and to, oldest first
+func (s *store) read(from, to time.Time) ([]*Snapshot, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("Invalid time range")
+	}
+
+	snapshots := []*Snapshot{}
+	from = from.UTC()
+	to = to.UTC()
+	last := to.Truncate(24 * time.Hour)
+	for day := from.Truncate(24 * time.Hour); !day.After(last); day = day.Add(24 * time.Hour) {
+		res, err := s.readDay(day, from, to)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, res...)
+	}
+
+	return snapshots, nil
+}
+
+// prune removes the files of all days before the retention period
+func (s *store) prune(now time.Time, retention int) error {
+	if retention <= 0 {
+		return nil
+	}
+
+	files, err := ioutil.ReadDir(s.directory)
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -retention)
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		day, err := time.Parse(dayLayout,
+			strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil || !day.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.directory, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
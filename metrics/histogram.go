@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds of the
+// histogram buckets for durations in seconds.
+var DefaultBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60,
+}
+
+type histogramValues struct {
+	labelValues []string
+	counts      []uint64 // per bucket, not cumulative
+	count       uint64
+	sum         float64
+}
+
+// A Histogram counts observations in buckets
+type Histogram struct {
+	sync.Mutex
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	values  map[string]*histogramValues
+}
+
+// NewHistogram creates and registers a new histogram.
+// Without buckets, the DefaultBuckets are used.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	h := &Histogram{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		values:  make(map[string]*histogramValues),
+	}
+	register(h)
+	return h
+}
+
+// Observe adds a value to the histogram
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	h.Lock()
+	defer h.Unlock()
+
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValues{
+			labelValues: labelValues,
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = v
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			v.counts[i]++
+			break
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.Lock()
+	defer h.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", h.name, h.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.name)
+
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	bucketLabels := append(append([]string{}, h.labels...), "le")
+	for _, key := range keys {
+		v := h.values[key]
+
+		cumulative := uint64(0)
+		for i, bound := range h.buckets {
+			cumulative += v.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name,
+				formatLabels(bucketLabels, append(append([]string{}, v.labelValues...),
+					fmt.Sprintf("%g", bound))),
+				cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name,
+			formatLabels(bucketLabels, append(append([]string{}, v.labelValues...), "+Inf")),
+			v.count)
+
+		labels := formatLabels(h.labels, v.labelValues)
+		fmt.Fprintf(w, "%s_sum%s %g\n", h.name, labels, v.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels, v.count)
+	}
+}
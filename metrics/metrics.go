@@ -0,0 +1,184 @@
+package metrics
+
+// A minimal implementation of counters and gauges,
+// exposed in the prometheus text exposition format.
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type metric interface {
+	write(w io.Writer)
+}
+
+var registry struct {
+	sync.Mutex
+	metrics []metric
+}
+
+func register(m metric) {
+	registry.Lock()
+	registry.metrics = append(registry.metrics, m)
+	registry.Unlock()
+}
+
+// vector holds the values of a metric for
+// each combination of label values.
+type vector struct {
+	sync.Mutex
+	name     string
+	help     string
+	kind     string
+	labels   []string
+	values   map[string]float64
+	captions map[string][]string
+}
+
+func newVector(kind, name, help string, labels []string) *vector {
+	return &vector{
+		name:     name,
+		help:     help,
+		kind:     kind,
+		labels:   labels,
+		values:   make(map[string]float64),
+		captions: make(map[string][]string),
+	}
+}
+
+func (v *vector) add(delta float64, labelValues []string) {
+	key := strings.Join(labelValues, "\xff")
+	v.Lock()
+	v.values[key] += delta
+	v.captions[key] = labelValues
+	v.Unlock()
+}
+
+func (v *vector) set(value float64, labelValues []string) {
+	key := strings.Join(labelValues, "\xff")
+	v.Lock()
+	v.values[key] = value
+	v.captions[key] = labelValues
+	v.Unlock()
+}
+
+func (v *vector) remove(labelValues []string) {
+	key := strings.Join(labelValues, "\xff")
+	v.Lock()
+	delete(v.values, key)
+	delete(v.captions, key)
+	v.Unlock()
+}
+
+func (v *vector) reset() {
+	v.Lock()
+	v.values = make(map[string]float64)
+	v.captions = make(map[string][]string)
+	v.Unlock()
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		value = strings.Replace(value, `\`, `\\`, -1)
+		value = strings.Replace(value, `"`, `\"`, -1)
+		value = strings.Replace(value, "\n", `\n`, -1)
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, value))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (v *vector) write(w io.Writer) {
+	v.Lock()
+	defer v.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.kind)
+
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %g\n",
+			v.name, formatLabels(v.labels, v.captions[key]), v.values[key])
+	}
+}
+
+// A Counter is a monotonically increasing value
+type Counter struct {
+	*vector
+}
+
+// NewCounter creates and registers a new counter
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newVector("counter", name, help, labels)}
+	register(c)
+	return c
+}
+
+// Inc increments the counter by one
+func (c *Counter) Inc(labelValues ...string) {
+	c.add(1, labelValues)
+}
+
+// Add increments the counter by a value
+func (c *Counter) Add(value float64, labelValues ...string) {
+	c.add(value, labelValues)
+}
+
+// A Gauge is a value which can go up and down
+type Gauge struct {
+	*vector
+}
+
+// NewGauge creates and registers a new gauge
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{newVector("gauge", name, help, labels)}
+	register(g)
+	return g
+}
+
+// Set the gauge to a value
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.set(value, labelValues)
+}
+
+// Add a (negative) value to the gauge
+func (g *Gauge) Add(value float64, labelValues ...string) {
+	g.add(value, labelValues)
+}
+
+// Delete removes a label combination
+func (g *Gauge) Delete(labelValues ...string) {
+	g.remove(labelValues)
+}
+
+// Reset removes all label combinations
+func (g *Gauge) Reset() {
+	g.reset()
+}
+
+// Write all registered metrics
+func Write(w io.Writer) {
+	registry.Lock()
+	metrics := make([]metric, len(registry.metrics))
+	copy(metrics, registry.metrics)
+	registry.Unlock()
+
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	c := NewCounter("test_requests_total", "Test requests", "endpoint")
+	c.Inc("status")
+	c.Inc("status")
+	c.Add(3, "routes")
+
+	g := NewGauge("test_value", "Test value")
+	g.Set(42)
+
+	buf := &bytes.Buffer{}
+	Write(buf)
+	out := buf.String()
+	t.Log(out)
+
+	expected := []string{
+		"# TYPE test_requests_total counter",
+		`test_requests_total{endpoint="routes"} 3`,
+		`test_requests_total{endpoint="status"} 2`,
+		"# TYPE test_value gauge",
+		"test_value 42",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Error("Expected output to contain:", e)
+		}
+	}
+}
+
+func TestWriteHistogram(t *testing.T) {
+	h := NewHistogram("test_duration_seconds", "Test durations", []float64{0.1, 1}, "command")
+	h.Observe(0.05, "status")
+	h.Observe(0.5, "status")
+	h.Observe(3, "status")
+
+	buf := &bytes.Buffer{}
+	Write(buf)
+	out := buf.String()
+
+	expected := []string{
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{command="status",le="0.1"} 1`,
+		`test_duration_seconds_bucket{command="status",le="1"} 2`,
+		`test_duration_seconds_bucket{command="status",le="+Inf"} 3`,
+		`test_duration_seconds_sum{command="status"} 3.55`,
+		`test_duration_seconds_count{command="status"} 3`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Error("Expected output to contain:", e)
+		}
+	}
+}
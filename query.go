@@ -0,0 +1,277 @@
+package main
+
+// The query subcommand runs queries against BIRD
+// directly, bypassing the HTTP server.
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+type queryOptions struct {
+	protocol string
+	table    string
+	peer     string
+	prefix   string
+	match    string
+	where    string
+	filtered bool
+	export   bool
+	noexport bool
+}
+
+func queryRoutes(opts queryOptions) (bird.Parsed, error) {
+	mode, err := bird.ParseMatchMode(opts.match)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.where != "" {
+		where, err := bird.ValidateWhere(opts.where)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case opts.protocol != "":
+			res, _, err := bird.RoutesProtoWhere(context.Background(), false, opts.protocol, where)
+			return res, err
+		case opts.table != "":
+			res, _, err := bird.RoutesTableWhere(context.Background(), false, opts.table, where)
+			return res, err
+		}
+		return nil, fmt.Errorf("--where needs a --protocol or --table")
+	}
+
+	switch {
+	case opts.prefix != "" && opts.table != "":
+		res, _, err := bird.RoutesLookupTableMatch(context.Background(), false, opts.prefix, opts.table, mode)
+		return res, err
+	case opts.prefix != "" && opts.protocol != "":
+		res, _, err := bird.RoutesLookupProtocol(context.Background(), false, opts.prefix, opts.protocol)
+		return res, err
+	case opts.prefix != "":
+		res, _, err := bird.RoutesPrefixed(context.Background(), false, opts.prefix)
+		return res, err
+	case opts.protocol != "" && opts.filtered:
+		res, _, err := bird.RoutesFiltered(context.Background(), false, opts.protocol)
+		return res, err
+	case opts.protocol != "" && opts.export:
+		res, _, err := bird.RoutesExport(context.Background(), false, opts.protocol)
+		return res, err
+	case opts.protocol != "" && opts.noexport:
+		res, _, err := bird.RoutesNoExport(context.Background(), false, opts.protocol)
+		return res, err
+	case opts.protocol != "":
+		res, _, err := bird.RoutesProto(context.Background(), false, opts.protocol)
+		return res, err
+	case opts.table != "" && opts.peer != "":
+		res, _, err := bird.RoutesTableAndPeer(context.Background(), false, opts.table, opts.peer)
+		return res, err
+	case opts.table != "" && opts.filtered:
+		res, _, err := bird.RoutesTableFiltered(context.Background(), false, opts.table)
+		return res, err
+	case opts.table != "":
+		res, _, err := bird.RoutesTable(context.Background(), false, opts.table)
+		return res, err
+	case opts.peer != "":
+		res, _, err := bird.RoutesPeer(context.Background(), false, opts.peer)
+		return res, err
+	}
+	return nil, fmt.Errorf("routes need a --protocol, --table, --peer or --prefix")
+}
+
+func queryCount(opts queryOptions) (bird.Parsed, error) {
+	switch {
+	case opts.protocol != "" && opts.export:
+		res, _, err := bird.RoutesExportCount(context.Background(), false, opts.protocol)
+		return res, err
+	case opts.protocol != "":
+		res, _, err := bird.RoutesProtoCount(context.Background(), false, opts.protocol)
+		return res, err
+	case opts.table != "":
+		res, _, err := bird.RoutesTableCount(context.Background(), false, opts.table)
+		return res, err
+	}
+	return nil, fmt.Errorf("count needs a --protocol or --table")
+}
+
+func runQuery(kind string, opts queryOptions) (bird.Parsed, error) {
+	switch kind {
+	case "status":
+		res, _, err := bird.Status(context.Background(), false)
+		return res, err
+	case "protocols":
+		res, _, err := bird.Protocols(context.Background(), false)
+		return res, err
+	case "protocols_bgp":
+		res, _, err := bird.ProtocolsBgp(context.Background(), false)
+		return res, err
+	case "protocols_short":
+		res, _, err := bird.ProtocolsShort(context.Background(), false)
+		return res, err
+	case "symbols":
+		res, _, err := bird.Symbols(context.Background(), false)
+		return res, err
+	case "routes":
+		return queryRoutes(opts)
+	case "count":
+		return queryCount(opts)
+	}
+	return nil, fmt.Errorf("unknown query: %s", kind)
+}
+
+func stringValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []string:
+		return strings.Join(value, " ")
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+var routeColumns = []string{
+	"network", "gateway", "interface", "from_protocol",
+	"age", "learnt_from", "primary", "metric",
+}
+
+var routeBgpColumns = []string{
+	"as_path", "next_hop", "local_pref", "med", "origin",
+}
+
+func writeRoutesCSV(w *csv.Writer, routes []bird.Parsed) {
+	w.Write(append(append([]string{}, routeColumns...), routeBgpColumns...))
+	for _, route := range routes {
+		row := []string{}
+		for _, col := range routeColumns {
+			row = append(row, stringValue(route[col]))
+		}
+		bgp, _ := route["bgp"].(bird.Parsed)
+		for _, col := range routeBgpColumns {
+			row = append(row, stringValue(bgp[col]))
+		}
+		w.Write(row)
+	}
+}
+
+var protocolColumns = []string{
+	"protocol", "bird_protocol", "table", "state", "state_changed",
+	"neighbor_address", "neighbor_as", "description",
+}
+
+var protocolRoutesColumns = []string{
+	"imported", "filtered", "exported", "preferred",
+}
+
+func writeProtocolsCSV(w *csv.Writer, protocols bird.Parsed) {
+	w.Write(append(append([]string{}, protocolColumns...), protocolRoutesColumns...))
+
+	names := make([]string, 0, len(protocols))
+	for name := range protocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		protocol, ok := protocols[name].(bird.Parsed)
+		if !ok {
+			continue
+		}
+		if _, ok := protocol["protocol"]; !ok {
+			protocol["protocol"] = name // protocols short
+		}
+		row := []string{}
+		for _, col := range protocolColumns {
+			row = append(row, stringValue(protocol[col]))
+		}
+		routes, _ := protocol["routes"].(bird.Parsed)
+		for _, col := range protocolRoutesColumns {
+			row = append(row, stringValue(routes[col]))
+		}
+		w.Write(row)
+	}
+}
+
+// writeCSV writes routes and protocols as tables,
+// everything else as key value pairs.
+func writeCSV(out io.Writer, res bird.Parsed) error {
+	w := csv.NewWriter(out)
+	if routes, ok := res["routes"].([]bird.Parsed); ok {
+		writeRoutesCSV(w, routes)
+	} else if protocols, ok := res["protocols"].(bird.Parsed); ok {
+		writeProtocolsCSV(w, protocols)
+	} else {
+		values := res
+		if status, ok := res["status"].(bird.Parsed); ok {
+			values = status
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			if key == "ttl" || key == "cached_at" {
+				continue
+			}
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		w.Write([]string{"key", "value"})
+		for _, key := range keys {
+			w.Write([]string{key, stringValue(values[key])})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func queryCommand(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("usage: birdwatcher query " +
+			"status|protocols|protocols_bgp|protocols_short|symbols|routes|count [options]")
+	}
+	kind := args[0]
+
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	configfile := flags.String("config", "/etc/birdwatcher/birdwatcher.conf", "Configuration file location")
+	ipv6 := flags.Bool("6", false, "Use bird6 instead of bird")
+	format := flags.String("format", "json", "Output format: json or csv")
+	opts := queryOptions{}
+	flags.StringVar(&opts.protocol, "protocol", "", "Protocol name")
+	flags.StringVar(&opts.table, "table", "", "Table name")
+	flags.StringVar(&opts.peer, "peer", "", "Peer address")
+	flags.StringVar(&opts.prefix, "prefix", "", "Look up a prefix")
+	flags.StringVar(&opts.match, "match", "", "Prefix match mode in a --table: exact, longest or covering")
+	flags.StringVar(&opts.where, "where", "", "Filter expression for --protocol or --table routes")
+	flags.BoolVar(&opts.filtered, "filtered", false, "Filtered routes")
+	flags.BoolVar(&opts.export, "export", false, "Exported routes")
+	flags.BoolVar(&opts.noexport, "noexport", false, "Not exported routes")
+	flags.Parse(args[1:])
+
+	if _, err := commandConfig(*configfile, *ipv6); err != nil {
+		return err
+	}
+
+	res, err := runQuery(kind, opts)
+	if err != nil {
+		return fmt.Errorf("query failed: %v", err)
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(res)
+	case "csv":
+		return writeCSV(os.Stdout, res)
+	}
+	return fmt.Errorf("unknown format: %s", *format)
+}
@@ -0,0 +1,115 @@
+package main
+
+// The replay subcommand feeds captured birdc
+// outputs back through the parsers.
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// Summarize a parse result for the replay report
+func summarize(res bird.Parsed) string {
+	if routes, ok := res["routes"].([]bird.Parsed); ok {
+		return fmt.Sprintf("%d routes", len(routes))
+	}
+	if count, ok := res["routes"].(int64); ok {
+		return fmt.Sprintf("count %d", count)
+	}
+	if protocols, ok := res["protocols"].(bird.Parsed); ok {
+		return fmt.Sprintf("%d protocols", len(protocols))
+	}
+	if symbols, ok := res["symbols"].(bird.Parsed); ok {
+		return fmt.Sprintf("%d symbol types", len(symbols))
+	}
+	if status, ok := res["status"].(bird.Parsed); ok {
+		return fmt.Sprintf("version %v", status["version"])
+	}
+	return "empty result"
+}
+
+func captureFiles(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.capture"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func replayCommand(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	ipv6 := flags.Bool("6", false, "Parse the output of bird6")
+	dualstack := flags.Bool("dualstack", false, "Do not filter protocol channels by IP version")
+	charset := flags.String("charset", bird.CharsetLatin1, "Transcode output which is not valid UTF-8 from latin1, or replace")
+	printJSON := flags.Bool("json", false, "Print the parsed results")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: birdwatcher replay [options] <spool dir or capture files>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *ipv6 {
+		bird.IPVersion = "6"
+	}
+	bird.ClientConf.Dualstack = *dualstack
+	bird.ClientConf.OutputCharset = *charset
+
+	files, err := captureFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	for _, file := range files {
+		header, out, err := bird.ReadCapture(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Skipping", file+":", err)
+			continue
+		}
+
+		// Captures hold the raw output, normalize it like birdc output
+		outputType := bird.ParserTypeForCommand(header.Command)
+		res, err := bird.Parse(outputType, bytes.NewReader(bird.NormalizeOutput(out)))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Skipping", file+":", err)
+			continue
+		}
+
+		if *printJSON {
+			if err := encoder.Encode(bird.Parsed{
+				"capture": header,
+				"result":  res,
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Printf("%s  %s (%d bytes): %s\n",
+			header.Timestamp.Format("2006-01-02 15:04:05"),
+			header.Command, header.Size, summarize(res))
+	}
+
+	return nil
+}
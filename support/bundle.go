@@ -0,0 +1,144 @@
+package support
+
+// Support bundles collect everything needed for a bug
+// report into a single gzipped tar archive.
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// Route counts are only collected for setups with
+// a reasonable number of tables.
+const maxCountedTables = 32
+
+type bundle struct {
+	tar *tar.Writer
+	now time.Time
+}
+
+func (b *bundle) add(name string, data []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: b.now,
+	}
+	if err := b.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := b.tar.Write(data)
+	return err
+}
+
+func (b *bundle) addJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return b.add(name, data)
+}
+
+func (b *bundle) addParsed(name string, res bird.Parsed, err error) error {
+	if err != nil {
+		res = bird.Parsed{"error": err}
+	}
+	return b.addJSON(name, res)
+}
+
+func tables(symbols bird.Parsed) []string {
+	s, ok := symbols["symbols"].(bird.Parsed)
+	if !ok {
+		return []string{}
+	}
+	tables, ok := s["routing table"].([]string)
+	if !ok {
+		return []string{}
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// WriteBundle writes the archive to w. The config should
+// be sanitized by the caller.
+func WriteBundle(w io.Writer, config interface{}, version string) error {
+	gz := gzip.NewWriter(w)
+	b := &bundle{
+		tar: tar.NewWriter(gz),
+		now: time.Now().UTC(),
+	}
+
+	info := bird.Parsed{
+		"version":    version,
+		"go_version": runtime.Version(),
+		"ip_version": bird.IPVersion,
+		"created_at": b.now,
+	}
+	if err := b.addJSON("version.json", info); err != nil {
+		return err
+	}
+
+	status, _, err := bird.Status(context.Background(), true)
+	if err := b.addParsed("status.json", status, err); err != nil {
+		return err
+	}
+
+	protocols, _, err := bird.Protocols(context.Background(), true)
+	if err := b.addParsed("protocols.json", protocols, err); err != nil {
+		return err
+	}
+
+	symbols, _, err := bird.Symbols(context.Background(), true)
+	if err := b.addParsed("symbols.json", symbols, err); err != nil {
+		return err
+	}
+
+	tableNames := tables(symbols)
+	if err := b.addJSON("tables.json", tableNames); err != nil {
+		return err
+	}
+
+	counts := bird.Parsed{}
+	if len(tableNames) <= maxCountedTables {
+		for _, table := range tableNames {
+			count, _, err := bird.RoutesTableCount(context.Background(), true, table)
+			if err != nil {
+				count = bird.Parsed{"error": err}
+			}
+			counts[table] = count
+		}
+	} else {
+		counts["error"] = "too many tables, counts skipped"
+	}
+	if err := b.addJSON("counts.json", counts); err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := toml.NewEncoder(buf).Encode(config); err != nil {
+		return err
+	}
+	if err := b.add("birdwatcher.conf", buf.Bytes()); err != nil {
+		return err
+	}
+
+	if err := b.addJSON("errors.json", bird.RecentErrors()); err != nil {
+		return err
+	}
+
+	if err := b.tar.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
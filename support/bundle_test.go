@@ -0,0 +1,67 @@
+package support
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestWriteBundle(t *testing.T) {
+	defer withFixtures(t)()
+
+	config := struct {
+		Cache struct {
+			RedisPassword string `toml:"redis_password"`
+		}
+	}{}
+	config.Cache.RedisPassword = "<redacted>"
+
+	buf := &bytes.Buffer{}
+	if err := WriteBundle(buf, config, "2.0.0-test"); err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(archive)
+		files[header.Name] = data
+	}
+
+	for _, name := range []string{
+		"version.json", "status.json", "protocols.json", "symbols.json",
+		"tables.json", "counts.json", "birdwatcher.conf", "errors.json",
+	} {
+		if _, ok := files[name]; !ok {
+			t.Error("Expected", name, "in the bundle")
+		}
+	}
+
+	info := map[string]interface{}{}
+	if err := json.Unmarshal(files["version.json"], &info); err != nil || info["version"] != "2.0.0-test" {
+		t.Error("Unexpected version info:", string(files["version.json"]))
+	}
+	if !strings.Contains(string(files["birdwatcher.conf"]), `redis_password = "<redacted>"`) {
+		t.Error("Expected the config to be included:", string(files["birdwatcher.conf"]))
+	}
+	counts := map[string]map[string]interface{}{}
+	if err := json.Unmarshal(files["counts.json"], &counts); err != nil || counts["master"]["routes"] == nil {
+		t.Error("Expected the route count of the master table:", string(files["counts.json"]))
+	}
+}
@@ -0,0 +1,220 @@
+package support
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// A Result of a selftest check
+type Result struct {
+	Name     string   `json:"name"`
+	Ok       bool     `json:"ok"`
+	Warning  bool     `json:"warning"`
+	Problems []string `json:"problems"`
+}
+
+func missingFields(res bird.Parsed, fields ...string) []string {
+	problems := []string{}
+	for _, field := range fields {
+		value, ok := res[field]
+		if !ok || value == nil || value == "" {
+			problems = append(problems, "missing field: "+field)
+		}
+	}
+	return problems
+}
+
+func checkError(err error) []string {
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+func checkStatus() []string {
+	res, _, err := bird.Status(context.Background(), false)
+	if problems := checkError(err); problems != nil {
+		return problems
+	}
+	status, ok := res["status"].(bird.Parsed)
+	if !ok {
+		return []string{"status not parsed"}
+	}
+	return missingFields(status, "version", "router_id", "current_server", "last_reboot")
+}
+
+func checkProtocolList(name string, res bird.Parsed, err error, fields ...string) []string {
+	if problems := checkError(err); problems != nil {
+		return problems
+	}
+	protocols, ok := res["protocols"].(bird.Parsed)
+	if !ok || len(protocols) == 0 {
+		return []string{"no protocols parsed"}
+	}
+
+	problems := []string{}
+	for id, p := range protocols {
+		protocol, ok := p.(bird.Parsed)
+		if !ok {
+			problems = append(problems, id+": invalid protocol")
+			continue
+		}
+		for _, problem := range missingFields(protocol, fields...) {
+			problems = append(problems, id+": "+problem)
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func checkSymbols() []string {
+	res, _, err := bird.Symbols(context.Background(), false)
+	if problems := checkError(err); problems != nil {
+		return problems
+	}
+	symbols, ok := res["symbols"].(bird.Parsed)
+	if !ok || len(symbols) == 0 {
+		return []string{"no symbols parsed"}
+	}
+	problems := []string{}
+	for _, kind := range []string{"routing table", "protocol"} {
+		if _, ok := symbols[kind]; !ok {
+			problems = append(problems, "no symbols of type: "+kind)
+		}
+	}
+	return problems
+}
+
+func checkRoutes(res bird.Parsed, err error) []string {
+	if problems := checkError(err); problems != nil {
+		return problems
+	}
+	routes, ok := res["routes"].([]bird.Parsed)
+	if !ok || len(routes) == 0 {
+		return []string{"no routes parsed"}
+	}
+
+	// Report every missing field only once
+	missing := map[string]int{}
+	for _, route := range routes {
+		for _, problem := range missingFields(route,
+			"network", "gateway", "from_protocol", "age", "metric", "primary") {
+			missing[problem]++
+		}
+	}
+	problems := []string{}
+	for problem, count := range missing {
+		problems = append(problems, fmt.Sprintf("%s (%d of %d routes)", problem, count, len(routes)))
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func checkCount(res bird.Parsed, err error) []string {
+	if problems := checkError(err); problems != nil {
+		return problems
+	}
+	if _, ok := res["routes"].(int64); !ok {
+		return []string{"no route count parsed"}
+	}
+	return nil
+}
+
+// firstEstablished returns the first BGP session
+// with imported routes, for testing the route queries.
+func firstEstablished() string {
+	res, _, _ := bird.ProtocolsBgp(context.Background(), true)
+	protocols, ok := res["protocols"].(bird.Parsed)
+	if !ok {
+		return ""
+	}
+	names := []string{}
+	for name, p := range protocols {
+		protocol, ok := p.(bird.Parsed)
+		if !ok {
+			continue
+		}
+		routes, _ := protocol["routes"].(bird.Parsed)
+		if imported, _ := routes["imported"].(int64); imported > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// Selftest runs all supported queries against BIRD
+// and checks the parsed results.
+func Selftest() []*Result {
+	results := []*Result{}
+	check := func(name string, problems []string) {
+		results = append(results, &Result{
+			Name:     name,
+			Ok:       len(problems) == 0,
+			Problems: problems,
+		})
+	}
+
+	check("status", checkStatus())
+
+	protocols, _, err := bird.Protocols(context.Background(), false)
+	check("protocols all", checkProtocolList("protocols", protocols, err,
+		"protocol", "bird_protocol", "table", "state", "state_changed", "routes"))
+
+	short, _, err := bird.ProtocolsShort(context.Background(), false)
+	check("protocols", checkProtocolList("protocols", short, err,
+		"proto", "table", "state", "since"))
+
+	check("symbols", checkSymbols())
+
+	master, _, err := bird.RoutesTableCount(context.Background(), false, "master")
+	check("route table count", checkCount(master, err))
+
+	protocol := firstEstablished()
+	if protocol == "" {
+		results = append(results, &Result{
+			Name:     "routes",
+			Ok:       true,
+			Warning:  true,
+			Problems: []string{"no BGP session with routes, route queries skipped"},
+		})
+		return results
+	}
+
+	proto, _, err := bird.RoutesProto(context.Background(), false, protocol)
+	check("route all protocol "+protocol, checkRoutes(proto, err))
+
+	count, _, err := bird.RoutesProtoCount(context.Background(), false, protocol)
+	check("route protocol "+protocol+" count", checkCount(count, err))
+
+	primary, _, err := bird.RoutesProtoPrimaryCount(context.Background(), false, protocol)
+	check("route primary protocol "+protocol+" count", checkCount(primary, err))
+
+	// Routes might be legitimately empty, report
+	// these only as warnings.
+	for _, query := range []struct {
+		name string
+		run  func(context.Context, bool, string) (bird.Parsed, bool, error)
+	}{
+		{"filtered", bird.RoutesFiltered},
+		{"export", bird.RoutesExport},
+		{"noexport", bird.RoutesNoExport},
+	} {
+		res, _, err := query.run(context.Background(), false, protocol)
+		problems := checkRoutes(res, err)
+		results = append(results, &Result{
+			Name:     "route all " + query.name + " " + protocol,
+			Ok:       true,
+			Warning:  len(problems) > 0,
+			Problems: problems,
+		})
+	}
+
+	return results
+}
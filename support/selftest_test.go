@@ -0,0 +1,87 @@
+package support
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alice-lg/birdwatcher/bird"
+)
+
+// withFixtures answers the queries with the samples
+// of the test directory.
+func withFixtures(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := "BIRD 1.6.6 ready.\n42 of 42 routes for 42 networks\n"
+	for name, output := range map[string]string{
+		"symbols":        "BIRD 1.6.6 ready.\nmaster\trouting table\nR194_42\tprotocol\n",
+		"route_table":    count,
+		"route_protocol": count,
+		"route_primary":  count,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".sample"), []byte(output), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, sample := range map[string]string{
+		"status":        "status1.sample",
+		"protocols_all": "protocols_bgp_pipe.sample",
+		"protocols":     "protocols_short.sample",
+		"route":         "routes_bird1_ipv4.sample",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join("../test", sample))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".sample"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf := bird.ClientConf
+	bird.ClientConf.Fixtures = dir
+	bird.ClientConf.CacheTtl = 5
+	bird.InitializeCache()
+	return func() {
+		bird.ClientConf = conf
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSelftest(t *testing.T) {
+	defer withFixtures(t)()
+
+	expected := []string{
+		"status",
+		"protocols all",
+		"protocols",
+		"symbols",
+		"route table count",
+		"route all protocol R194_42",
+		"route protocol R194_42 count",
+		"route primary protocol R194_42 count",
+		"route all filtered R194_42",
+		"route all export R194_42",
+		"route all noexport R194_42",
+	}
+
+	// The checks run in the same order every time
+	for run := 0; run < 3; run++ {
+		results := Selftest()
+		if len(results) != len(expected) {
+			t.Fatal("Expected", len(expected), "results, got:", len(results))
+		}
+		for i, result := range results {
+			if result.Name != expected[i] {
+				t.Error("Expected", expected[i], "at", i, "got:", result.Name)
+			}
+			if !result.Ok || result.Warning || len(result.Problems) > 0 {
+				t.Error("Unexpected result of", result.Name+":", result.Problems)
+			}
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package tracing
+
+// Tracing configuration
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// OTLP/HTTP traces endpoint of the collector,
+	// e.g. http://localhost:4318/v1/traces
+	Endpoint string `toml:"endpoint"`
+
+	ServiceName string `toml:"service_name"`
+
+	// Export interval in seconds
+	Interval int `toml:"interval"`
+}
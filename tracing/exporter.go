@@ -0,0 +1,223 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Spans are dropped if the exporter can not keep up
+const maxQueuedSpans = 4096
+
+var exporter struct {
+	sync.Mutex
+	enabled bool
+	spans   []*Span
+}
+
+func enabled() bool {
+	exporter.Lock()
+	defer exporter.Unlock()
+	return exporter.enabled
+}
+
+func queue(s *Span) {
+	exporter.Lock()
+	defer exporter.Unlock()
+	if len(exporter.spans) < maxQueuedSpans {
+		exporter.spans = append(exporter.spans, s)
+	}
+}
+
+// OTLP JSON encoding, see opentelemetry-proto
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            otlpStatus      `json:"status"`
+}
+
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+
+	statusOk    = 1
+	statusError = 2
+)
+
+func otlpAttributes(attributes map[string]interface{}) []otlpAttribute {
+	res := make([]otlpAttribute, 0, len(attributes))
+	for key, v := range attributes {
+		value := otlpValue{}
+		switch v := v.(type) {
+		case string:
+			value.StringValue = &v
+		case bool:
+			value.BoolValue = &v
+		case int:
+			s := strconv.Itoa(v)
+			value.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		default:
+			s := fmt.Sprintf("%v", v)
+			value.StringValue = &s
+		}
+		res = append(res, otlpAttribute{Key: key, Value: value})
+	}
+	return res
+}
+
+func encodeSpan(s *Span) otlpSpan {
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Attributes:        otlpAttributes(s.attributes),
+		Status:            otlpStatus{Code: statusOk},
+	}
+	if s.parentID != (spanID{}) {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	if _, ok := s.attributes["http.method"]; ok {
+		span.Kind = spanKindServer
+	}
+	if s.err != "" {
+		span.Status = otlpStatus{Code: statusError, Message: s.err}
+	}
+	return span
+}
+
+func encodeSpans(serviceName string, spans []*Span) ([]byte, error) {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		encoded = append(encoded, encodeSpan(s))
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]interface{}{
+						"service.name": serviceName,
+					}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]string{"name": "birdwatcher"},
+						"spans": encoded,
+					},
+				},
+			},
+		},
+	})
+}
+
+func export(client *http.Client, config Config) error {
+	exporter.Lock()
+	spans := exporter.spans
+	exporter.spans = nil
+	exporter.Unlock()
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	payload, err := encodeSpans(config.ServiceName, spans)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Post(config.Endpoint, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("collector responded with: %s", res.Status)
+	}
+	return nil
+}
+
+// Record enables tracing and keeps the ended spans instead of
+// exporting them, e.g. in tests. The returned function disables
+// tracing again and returns the recorded spans.
+func Record() func() []*Span {
+	exporter.Lock()
+	exporter.enabled = true
+	exporter.spans = nil
+	exporter.Unlock()
+
+	return func() []*Span {
+		exporter.Lock()
+		defer exporter.Unlock()
+		spans := exporter.spans
+		exporter.enabled = false
+		exporter.spans = nil
+		return spans
+	}
+}
+
+// Start exporting spans to the collector
+func Start(config Config) error {
+	if config.Endpoint == "" {
+		return fmt.Errorf("tracing endpoint is not set")
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "birdwatcher"
+	}
+	interval := config.Interval
+	if interval <= 0 {
+		interval = 5
+	}
+
+	exporter.Lock()
+	exporter.enabled = true
+	exporter.Unlock()
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	go func() {
+		for range time.Tick(time.Duration(interval) * time.Second) {
+			if err := export(client, config); err != nil {
+				log.Println("Exporting traces failed:", err)
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+type traceID [16]byte
+type spanID [8]byte
+
+// A Span is a timed operation of a trace. All methods
+// are no-ops on a nil span, which is returned when
+// tracing is disabled.
+type Span struct {
+	name       string
+	traceID    traceID
+	spanID     spanID
+	parentID   spanID
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	err        string
+}
+
+func newSpanID() spanID {
+	id := spanID{}
+	rand.Read(id[:])
+	return id
+}
+
+func newTraceID() traceID {
+	id := traceID{}
+	rand.Read(id[:])
+	return id
+}
+
+// StartSpan starts the root span of a new trace
+func StartSpan(name string) *Span {
+	if !enabled() {
+		return nil
+	}
+	return &Span{
+		name:       name,
+		traceID:    newTraceID(),
+		spanID:     newSpanID(),
+		start:      time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+}
+
+// StartRemoteSpan starts a span continuing the trace of a
+// W3C traceparent header. Invalid headers start a new trace.
+func StartRemoteSpan(name, traceparent string) *Span {
+	span := StartSpan(name)
+	if span == nil {
+		return nil
+	}
+	if trace, parent, ok := parseTraceparent(traceparent); ok {
+		span.traceID = trace
+		span.parentID = parent
+	}
+	return span
+}
+
+// parseTraceparent parses a version 00 traceparent header:
+// 00-<32 hex trace id>-<16 hex parent id>-<2 hex flags>
+func parseTraceparent(header string) (traceID, spanID, bool) {
+	trace := traceID{}
+	parent := spanID{}
+
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || parts[0] != "00" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return trace, parent, false
+	}
+	if _, err := hex.Decode(trace[:], []byte(parts[1])); err != nil {
+		return trace, parent, false
+	}
+	if _, err := hex.Decode(parent[:], []byte(parts[2])); err != nil {
+		return trace, parent, false
+	}
+	if trace == (traceID{}) || parent == (spanID{}) {
+		return trace, parent, false
+	}
+	return trace, parent, true
+}
+
+type contextKey struct{}
+
+// ContextWithSpan returns a copy of the context carrying the span
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// SpanFromContext returns the span of the context or nil
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(contextKey{}).(*Span)
+	return s
+}
+
+// StartSpanFromContext starts a child of the span of the
+// context, or the root span of a new trace without one.
+func StartSpanFromContext(ctx context.Context, name string) *Span {
+	if parent := SpanFromContext(ctx); parent != nil {
+		return parent.Child(name)
+	}
+	return StartSpan(name)
+}
+
+// Child starts a span within the span
+func (s *Span) Child(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return &Span{
+		name:       name,
+		traceID:    s.traceID,
+		spanID:     newSpanID(),
+		parentID:   s.spanID,
+		start:      time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+}
+
+// SetAttribute sets a string, bool, int, int64 or float64 attribute
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.attributes[key] = value
+}
+
+// SetError marks the span as failed
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.err = err.Error()
+}
+
+// Name returns the name of the span
+func (s *Span) Name() string {
+	if s == nil {
+		return ""
+	}
+	return s.name
+}
+
+// TraceID returns the hex encoded trace id of the span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// SpanID returns the hex encoded id of the span
+func (s *Span) SpanID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.spanID[:])
+}
+
+// ParentID returns the hex encoded id of the parent span,
+// which is empty for the root span of a trace.
+func (s *Span) ParentID() string {
+	if s == nil || s.parentID == (spanID{}) {
+		return ""
+	}
+	return hex.EncodeToString(s.parentID[:])
+}
+
+// End finishes the span and queues it for export
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	queue(s)
+}
@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	trace, parent, ok := parseTraceparent(
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok {
+		t.Fatal("Expected a valid traceparent")
+	}
+	if fmt.Sprintf("%x", trace[:]) != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		fmt.Sprintf("%x", parent[:]) != "00f067aa0ba902b7" {
+		t.Error("Unexpected ids:", trace, parent)
+	}
+
+	for _, header := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+	} {
+		if _, _, ok := parseTraceparent(header); ok {
+			t.Error("Expected invalid traceparent:", header)
+		}
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	span := StartSpan("test")
+	if span != nil {
+		t.Fatal("Expected no span while tracing is disabled")
+	}
+	// No-ops on nil spans
+	child := span.Child("child")
+	child.SetAttribute("key", "value")
+	child.SetError(fmt.Errorf("failed"))
+	child.End()
+}
+
+func TestExport(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	exporter.Lock()
+	exporter.enabled = true
+	exporter.Unlock()
+	defer func() {
+		exporter.Lock()
+		exporter.enabled = false
+		exporter.spans = nil
+		exporter.Unlock()
+	}()
+
+	root := StartRemoteSpan("HTTP GET", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	root.SetAttribute("http.method", "GET")
+	child := root.Child("handler")
+	child.SetAttribute("birdc.output_bytes", 42)
+	child.SetError(fmt.Errorf("bird unreachable"))
+	child.End()
+	root.End()
+
+	config := Config{Endpoint: server.URL, ServiceName: "birdwatcher"}
+	if err := export(server.Client(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode the spans of the first resource and scope
+	spans := received["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatal("Expected 2 spans, got:", len(spans))
+	}
+
+	c := spans[0].(map[string]interface{})
+	r := spans[1].(map[string]interface{})
+	if r["traceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" || r["parentSpanId"] != "00f067aa0ba902b7" {
+		t.Error("Expected the remote trace context, got:", r)
+	}
+	if c["traceId"] != r["traceId"] || c["parentSpanId"] != r["spanId"] {
+		t.Error("Expected the child in the same trace, got:", c)
+	}
+	if r["kind"].(float64) != spanKindServer {
+		t.Error("Expected a server span, got:", r["kind"])
+	}
+	if status := c["status"].(map[string]interface{}); status["code"].(float64) != statusError {
+		t.Error("Expected an error status, got:", status)
+	}
+	attr := c["attributes"].([]interface{})[0].(map[string]interface{})
+	if attr["value"].(map[string]interface{})["intValue"] != "42" {
+		t.Error("Unexpected attribute:", attr)
+	}
+}